@@ -0,0 +1,70 @@
+package config
+
+import (
+	"strings"
+	"telegramBot/pkg/i18n"
+)
+
+// Rechte, die nach bestandenem Captcha vergeben werden können (CaptchaConfig.GrantPermissions)
+const (
+	PermissionMessages    = "messages"
+	PermissionMedia       = "media"
+	PermissionPolls       = "polls"
+	PermissionOther       = "other" // Sticker, GIFs, Inline-Bots
+	PermissionWebPreviews = "web_previews"
+	PermissionChangeInfo  = "change_info"
+	PermissionInviteUsers = "invite_users"
+	PermissionPinMessages = "pin_messages"
+)
+
+// CaptchaPermissions sind alle Werte, die in grant_permissions erlaubt sind
+var CaptchaPermissions = []string{
+	PermissionMessages, PermissionMedia, PermissionPolls, PermissionOther,
+	PermissionWebPreviews, PermissionChangeInfo, PermissionInviteUsers, PermissionPinMessages,
+}
+
+// GrantPermissionsRestore gibt nach dem Captcha die vorherigen Rechte bzw. die Standardrechte der Gruppe zurück
+const GrantPermissionsRestore = "restore"
+
+// Aktionen für User, die das Captcha nicht bestehen (CaptchaConfig.FailAction)
+const (
+	CaptchaFailKick = "kick" // entfernen, erneuter Beitritt möglich
+	CaptchaFailBan  = "ban"  // dauerhaft sperren
+)
+
+// ParseGrantPermissions liest eine kommagetrennte Liste von Rechten, z.B. "messages,media".
+// Ein leerer Wert oder "restore" ergibt nil: dann gibt es keine Begrenzung über die Gruppe hinaus.
+func ParseGrantPermissions(value string) ([]string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == GrantPermissionsRestore {
+		return nil, nil
+	}
+
+	var permissions []string
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if !isCaptchaPermission(entry) {
+			return nil, i18n.NewError("config.invalid_permission", i18n.Vars{
+				"permission": entry,
+				"options":    strings.Join(CaptchaPermissions, ", "),
+			})
+		}
+		permissions = append(permissions, entry)
+	}
+
+	return permissions, nil
+}
+
+func isCaptchaPermission(name string) bool {
+	for _, permission := range CaptchaPermissions {
+		if permission == name {
+			return true
+		}
+	}
+	return false
+}
+
+func validateGrantPermissions(value string) error {
+	_, err := ParseGrantPermissions(value)
+	return err
+}
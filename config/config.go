@@ -3,35 +3,144 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 )
 
 type Config struct {
-	BotToken string         `json:"bot_token"`
-	Debug    bool           `json:"debug"`
-	Captcha  CaptchaConfig  `json:"captcha"`
-	Admin    AdminConfig    `json:"admin"`
-	Database DatabaseConfig `json:"database"`
+	BotToken    string           `json:"bot_token"`
+	APIEndpoint string           `json:"api_endpoint,omitempty"`
+	Debug       bool             `json:"debug"`
+	Mode        string           `json:"mode,omitempty"`
+	Webhook     WebhookConfig    `json:"webhook"`
+	Captcha     CaptchaConfig    `json:"captcha"`
+	Admin       AdminConfig      `json:"admin"`
+	Database    DatabaseConfig   `json:"database"`
+	Logging     LoggingConfig    `json:"logging"`
+	I18n        I18nConfig       `json:"i18n"`
+	RateLimit   RateLimitConfig  `json:"rate_limit"`
+	Dispatcher  DispatcherConfig `json:"dispatcher"`
+}
+
+// Update-Modi für Config.Mode
+const (
+	ModePolling = "polling"
+	ModeWebhook = "webhook"
+)
+
+// WebhookConfig wird nur im Modus "webhook" verwendet
+type WebhookConfig struct {
+	URL         string `json:"url"`
+	ListenAddr  string `json:"listen_addr"`
+	Path        string `json:"path"`
+	SecretToken string `json:"secret_token"`
+	CertFile    string `json:"cert_file"`
+	KeyFile     string `json:"key_file"`
+}
+
+// RateLimitConfig begrenzt die ausgehenden Anfragen an die Bot API, 0 = Standardwert
+type RateLimitConfig struct {
+	GlobalPerSecond  int `json:"global_per_second"`  // Anfragen pro Sekunde über alle Chats, Standard 30
+	GroupPerMinute   int `json:"group_per_minute"`   // Nachrichten pro Minute in einer Gruppe, Standard 20
+	PrivatePerSecond int `json:"private_per_second"` // Nachrichten pro Sekunde in einem Privatchat, Standard 1
+	MaxRetries       int `json:"max_retries"`        // Wiederholungen bei 429 und vorübergehenden Fehlern, Standard 3
+}
+
+// DispatcherConfig steuert die Verarbeitung eingehender Updates, 0 = Standardwert
+type DispatcherConfig struct {
+	Workers   int `json:"workers"`    // parallel arbeitende Worker, Standard 8
+	QueueSize int `json:"queue_size"` // wartende Updates pro Worker, danach greift Gegendruck, Standard 100
 }
 
 type CaptchaConfig struct {
-	TimeoutMinutes                   int    `json:"timeout_minutes"`
-	MaxAttempts                      int    `json:"max_attempts"`
-	WelcomeMessage                   string `json:"welcome_message"`
-	MessageDeleteDelayMinutes        int    `json:"message_delete_delay_minutes"`
-	SuccessMessageDeleteDelayMinutes int    `json:"success_message_delete_delay_minutes"`
+	TimeoutMinutes                   Duration `json:"timeout_minutes"` // Zahl = Minuten
+	MaxAttempts                      int      `json:"max_attempts"`
+	WelcomeMessage                   string   `json:"welcome_message"` // Vorlage mit Platzhaltern wie {mention}
+	WelcomeFormat                    string   `json:"welcome_format"`  // plain (Standard), markdown oder html
+	MessageDeleteDelayMinutes        int      `json:"message_delete_delay_minutes"`
+	SuccessMessageDeleteDelayMinutes int      `json:"success_message_delete_delay_minutes"`
+	ChallengeType                    string   `json:"challenge_type"`
+	RulesAcceptance                  string   `json:"rules_acceptance"`  // on: nach dem Captcha die Regeln akzeptieren lassen
+	GrantPermissions                 string   `json:"grant_permissions"` // restore (Standard) oder Obergrenze der Rechte, z.B. "messages,media"
+	FailAction                       string   `json:"fail_action"`       // kick (Standard) oder ban
 }
 
+// Werte für CaptchaConfig.RulesAcceptance
+const (
+	RulesAcceptanceOff = "off"
+	RulesAcceptanceOn  = "on"
+)
+
+// Formate für CaptchaConfig.WelcomeFormat
+const (
+	WelcomeFormatPlain    = "plain"
+	WelcomeFormatMarkdown = "markdown" // MarkdownV2
+	WelcomeFormatHTML     = "html"
+)
+
 type AdminConfig struct {
-	DefaultMuteHours  int     `json:"default_mute_hours"`
-	MaxDeleteMessages int     `json:"max_delete_messages"`
-	AdminUserIDs      []int64 `json:"admin_user_ids"`
+	DefaultMuteHours  Duration `json:"default_mute_hours"` // Zahl = Stunden
+	MaxDeleteMessages int      `json:"max_delete_messages"`
+	AdminUserIDs      []int64  `json:"admin_user_ids"` // wird beim ersten Start in die Datenbank übernommen (erster Eintrag = Owner)
+	WarnLadder        string   `json:"warn_ladder"`
+	WarnExpiryDays    Duration `json:"warn_expiry_days"` // Zahl = Tage, 0 = nie
+
+	// Grenzen für Dauern bei /mute und /tban, z.B. "1m", "1w" oder "perm" (leer = Standardwert)
+	MinDuration     string `json:"min_duration"`
+	MaxMuteDuration string `json:"max_mute_duration"`
+	MaxBanDuration  string `json:"max_ban_duration"`
+
+	// Flood-Schutz: mehr als FloodMaxMessages Nachrichten in FloodWindowSeconds lösen FloodAction aus (0 = aus)
+	FloodMaxMessages   int      `json:"flood_max_messages"`
+	FloodWindowSeconds int      `json:"flood_window_seconds"`
+	FloodAction        string   `json:"flood_action"`
+	FloodMuteHours     Duration `json:"flood_mute_hours"` // Zahl = Stunden, leer = default_mute_hours
 }
 
+// Aktionen für AdminConfig.FloodAction
+const (
+	FloodActionDelete = "delete"
+	FloodActionMute   = "mute"
+	FloodActionKick   = "kick"
+	FloodActionBan    = "ban"
+)
+
 type DatabaseConfig struct {
 	FilePath string `json:"file_path"`
 }
 
+// I18nConfig legt die Standardsprache fest; Gruppen können sie per /config überschreiben
+type I18nConfig struct {
+	Locale string `json:"locale"` // de (Standard) oder en
+}
+
+// Log-Formate für LoggingConfig.Format
+const (
+	LogFormatJSON = "json"
+	LogFormatText = "text"
+)
+
+// Modi für LoggingConfig.MessageLogging, also was von Gruppennachrichten in events.log landet
+const (
+	MessageLogOff      = "off"      // keine MESSAGE-Einträge
+	MessageLogMetadata = "metadata" // nur Chat, User, Nachrichten-ID und Länge
+	MessageLogHashed   = "hashed"   // wie metadata, zusätzlich ein HMAC des Textes
+	MessageLogFull     = "full"     // vollständiger Text
+)
+
+// LoggingConfig steuert commands.log und events.log. Leere Werte verwenden die Standards.
+type LoggingConfig struct {
+	Format               string `json:"format"`                 // json (JSON Lines, Standard) oder text
+	CommandLog           string `json:"command_log"`            // Standard: commands.log
+	EventLog             string `json:"event_log"`              // Standard: events.log
+	MaxSizeMB            int    `json:"max_size_mb"`            // Rotation ab dieser Größe, 0 = keine Größengrenze
+	RotateAfter          string `json:"rotate_after"`           // Rotation nach dieser Dauer, z.B. "1d", leer = keine Altersgrenze
+	MaxBackups           int    `json:"max_backups"`            // Anzahl aufbewahrter .gz-Dateien, 0 = alle
+	MessageLogging       string `json:"message_logging"`        // off, metadata (Standard), hashed oder full; pro Gruppe änderbar
+	MessageRetentionDays int    `json:"message_retention_days"` // MESSAGE-Einträge werden danach gelöscht, 0 = nie; pro Gruppe änderbar
+	HashSalt             string `json:"hash_salt"`              // Schlüssel für den Modus hashed, leer = zufällig pro Start
+}
+
 func LoadConfig(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -47,5 +156,72 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("bot token not configured")
 	}
 
+	switch config.Mode {
+	case "", ModePolling:
+	case ModeWebhook:
+		if config.Webhook.URL == "" || config.Webhook.ListenAddr == "" {
+			return nil, fmt.Errorf("webhook mode requires webhook.url and webhook.listen_addr")
+		}
+		if (config.Webhook.CertFile == "") != (config.Webhook.KeyFile == "") {
+			return nil, fmt.Errorf("webhook.cert_file and webhook.key_file must be set together")
+		}
+		if config.Webhook.SecretToken == "" {
+			log.Printf("WARNING: webhook.secret_token is empty, the webhook endpoint accepts updates from anyone")
+		}
+	default:
+		return nil, fmt.Errorf("unknown mode: %s", config.Mode)
+	}
+
+	if _, err := ParseWarnLadder(config.Admin.WarnLadder); err != nil {
+		return nil, fmt.Errorf("invalid admin.warn_ladder: %w", err)
+	}
+
+	switch config.Logging.Format {
+	case "", LogFormatJSON, LogFormatText:
+	default:
+		return nil, fmt.Errorf("unknown logging.format: %s", config.Logging.Format)
+	}
+	if config.I18n.Locale != "" {
+		setting, _ := LookupSetting("locale")
+		if _, err := setting.Parse(config.I18n.Locale); err != nil {
+			return nil, fmt.Errorf("invalid i18n.locale: %w", err)
+		}
+	}
+	if config.Logging.MessageLogging != "" {
+		setting, _ := LookupSetting("message_logging")
+		if _, err := setting.Parse(config.Logging.MessageLogging); err != nil {
+			return nil, fmt.Errorf("invalid logging.message_logging: %w", err)
+		}
+	}
+	if config.Logging.RotateAfter != "" {
+		if err := validateMinDuration(config.Logging.RotateAfter); err != nil {
+			return nil, fmt.Errorf("invalid logging.rotate_after: %w", err)
+		}
+	}
+
+	for key, value := range map[string]string{
+		"min_duration":       config.Admin.MinDuration,
+		"max_mute_duration":  config.Admin.MaxMuteDuration,
+		"max_ban_duration":   config.Admin.MaxBanDuration,
+		"default_mute_hours": string(config.Admin.DefaultMuteHours),
+		"warn_expiry_days":   string(config.Admin.WarnExpiryDays),
+		"flood_mute_hours":   string(config.Admin.FloodMuteHours),
+	} {
+		// 0 stand in älteren Dateien für "nicht gesetzt"
+		if value == "" || value == "0" {
+			continue
+		}
+		setting, _ := LookupSetting(key)
+		if _, err := setting.Parse(value); err != nil {
+			return nil, fmt.Errorf("invalid admin.%s: %w", key, err)
+		}
+	}
+	if value := string(config.Captcha.TimeoutMinutes); value != "" && value != "0" {
+		setting, _ := LookupSetting("timeout_minutes")
+		if _, err := setting.Parse(value); err != nil {
+			return nil, fmt.Errorf("invalid captcha.timeout_minutes: %w", err)
+		}
+	}
+
 	return &config, nil
 }
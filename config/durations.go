@@ -0,0 +1,150 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"telegramBot/pkg/duration"
+	"telegramBot/pkg/i18n"
+	"time"
+)
+
+// Standardgrenzen, wenn in der Config nichts gesetzt ist
+const (
+	defaultMinDuration     = "1m"
+	defaultMaxMuteDuration = "1w"
+	defaultMaxBanDuration  = "perm"
+)
+
+// Telegram behandelt Banns unter 30 Sekunden als permanent
+const minAllowedDuration = 30 * time.Second
+
+// Duration ist eine Dauer in der Config: Text wie "90m" oder "1d" oder, wie früher, eine Zahl
+// in der Einheit des Schlüssels (z.B. Stunden bei default_mute_hours)
+type Duration string
+
+// UnmarshalJSON nimmt Zahlen und Text an, damit bestehende config.json-Dateien gültig bleiben
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*d = Duration(text)
+		return nil
+	}
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err != nil {
+		return fmt.Errorf("expected a number or a duration like \"90m\", got %s", data)
+	}
+	*d = Duration(number.String())
+	return nil
+}
+
+// In liefert die Dauer, eine Zahl ohne Einheit zählt in unit. Leere oder ungültige Werte ergeben 0.
+func (d Duration) In(unit time.Duration) time.Duration {
+	value, err := parseDurationValue(string(d), unit)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+// parseDurationValue liest eine Dauer wie duration.Parse oder eine Zahl in unit
+func parseDurationValue(value string, unit time.Duration) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if n, err := strconv.Atoi(value); err == nil {
+		if n < 0 {
+			return 0, i18n.NewError("duration.not_positive", i18n.Vars{"input": value})
+		}
+		return time.Duration(n) * unit, nil
+	}
+	return duration.Parse(value)
+}
+
+// defaultCaptchaTimeout gilt, wenn timeout_minutes nicht gesetzt ist
+const defaultCaptchaTimeout = 5 * time.Minute
+
+// Timeout liefert timeout_minutes als Dauer, ohne Wert defaultCaptchaTimeout
+func (c CaptchaConfig) Timeout() time.Duration {
+	if d := c.TimeoutMinutes.In(time.Minute); d > 0 {
+		return d
+	}
+	return defaultCaptchaTimeout
+}
+
+// DefaultMute liefert default_mute_hours als Dauer
+func (a AdminConfig) DefaultMute() time.Duration {
+	return a.DefaultMuteHours.In(time.Hour)
+}
+
+// FloodMute liefert die Mute-Dauer des Flood-Schutzes, ohne eigenen Wert die Standarddauer
+func (a AdminConfig) FloodMute() time.Duration {
+	if d := a.FloodMuteHours.In(time.Hour); d > 0 {
+		return d
+	}
+	return a.DefaultMute()
+}
+
+// WarnExpiry liefert, nach welcher Zeit Verwarnungen verfallen (0 = nie)
+func (a AdminConfig) WarnExpiry() time.Duration {
+	return a.WarnExpiryDays.In(duration.Day)
+}
+
+// ClampMute hält Mutes ohne eingegebene Dauer (Standarddauer, Flood-Schutz, Verwarnungen)
+// in den Grenzen von MuteLimits. Permanent wird zur Höchstdauer, wenn diese begrenzt ist.
+func (a AdminConfig) ClampMute(d time.Duration) time.Duration {
+	min, max := a.MuteLimits()
+	switch {
+	case d == duration.Permanent:
+		if max != duration.Permanent {
+			return max
+		}
+	case d < min:
+		return min
+	case max != duration.Permanent && d > max:
+		return max
+	}
+	return d
+}
+
+// MuteLimits liefert die erlaubte Mindest- und Höchstdauer für /mute
+func (a AdminConfig) MuteLimits() (time.Duration, time.Duration) {
+	return parseLimit(a.MinDuration, defaultMinDuration), parseLimit(a.MaxMuteDuration, defaultMaxMuteDuration)
+}
+
+// BanLimits liefert die erlaubte Mindest- und Höchstdauer für /tban
+func (a AdminConfig) BanLimits() (time.Duration, time.Duration) {
+	return parseLimit(a.MinDuration, defaultMinDuration), parseLimit(a.MaxBanDuration, defaultMaxBanDuration)
+}
+
+// parseLimit liest einen bereits validierten Grenzwert; ungültige Werte fallen auf den Standard zurück
+func parseLimit(value, fallback string) time.Duration {
+	if value != "" {
+		if d, err := duration.Parse(value); err == nil {
+			return d
+		}
+	}
+	d, _ := duration.Parse(fallback)
+	return d
+}
+
+func validateMinDuration(value string) error {
+	d, err := duration.Parse(value)
+	if err != nil {
+		return err
+	}
+	if d == duration.Permanent || d < minAllowedDuration {
+		return i18n.NewError("config.min_duration_range", i18n.Vars{"min": duration.Format(minAllowedDuration)})
+	}
+	return nil
+}
+
+func validateMaxDuration(value string) error {
+	d, err := duration.Parse(value)
+	if err != nil {
+		return err
+	}
+	if d != duration.Permanent && d < minAllowedDuration {
+		return i18n.NewError("config.max_duration_range", i18n.Vars{"min": duration.Format(minAllowedDuration)})
+	}
+	return nil
+}
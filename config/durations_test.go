@@ -0,0 +1,29 @@
+package config
+
+import (
+	"encoding/json"
+	"telegramBot/pkg/duration"
+	"testing"
+	"time"
+)
+
+func TestDurationsInConfigJSON(t *testing.T) {
+	var admin AdminConfig
+	if err := json.Unmarshal([]byte(`{"default_mute_hours": 2, "warn_expiry_days": "2w"}`), &admin); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got := admin.DefaultMute(); got != 2*time.Hour {
+		t.Errorf("default mute = %s, want 2h", got)
+	}
+	if got := admin.WarnExpiry(); got != 2*duration.Week {
+		t.Errorf("warn expiry = %s, want 2w", got)
+	}
+	if got := admin.FloodMute(); got != 2*time.Hour {
+		t.Errorf("flood mute = %s, want the default mute 2h", got)
+	}
+
+	var captcha CaptchaConfig
+	if got := captcha.Timeout(); got != 5*time.Minute {
+		t.Errorf("captcha timeout without a value = %s, want 5m", got)
+	}
+}
@@ -0,0 +1,263 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"telegramBot/pkg/duration"
+	"telegramBot/pkg/i18n"
+	"time"
+)
+
+// Setting beschreibt einen per /config änderbaren Konfigurationsschlüssel. Die Beschreibung
+// steht im i18n-Katalog unter "setting.<Key>".
+type Setting struct {
+	Key      string
+	Section  string
+	Numeric  bool
+	Min      int
+	Max      int
+	Options  []string
+	Validate func(string) error // zusätzliche Prüfung für Text-Werte
+	Unit     time.Duration      // Dauer-Schlüssel: Zahl in dieser Einheit oder z.B. "90m", Min/Max in Einheiten
+}
+
+// Settings enthält alle Schlüssel, die global und pro Gruppe überschrieben werden können
+var Settings = []Setting{
+	{Key: "timeout_minutes", Section: "captcha", Unit: time.Minute, Min: 1, Max: 60},
+	{Key: "max_attempts", Section: "captcha", Numeric: true, Min: 1, Max: 10},
+	{Key: "welcome_message", Section: "captcha"},
+	{Key: "welcome_format", Section: "captcha", Options: []string{WelcomeFormatPlain, WelcomeFormatMarkdown, WelcomeFormatHTML}},
+	{Key: "message_delete_delay_minutes", Section: "captcha", Numeric: true, Min: 1, Max: 60},
+	{Key: "success_message_delete_delay_minutes", Section: "captcha", Numeric: true, Min: 1, Max: 60},
+	{Key: "challenge_type", Section: "captcha", Options: []string{"math", "emoji", "word", "image"}},
+	{Key: "rules_acceptance", Section: "captcha", Options: []string{RulesAcceptanceOff, RulesAcceptanceOn}},
+	{Key: "grant_permissions", Section: "captcha", Validate: validateGrantPermissions},
+	{Key: "fail_action", Section: "captcha", Options: []string{CaptchaFailKick, CaptchaFailBan}},
+	{Key: "default_mute_hours", Section: "admin", Unit: time.Hour, Min: 1, Max: 168},
+	{Key: "max_delete_messages", Section: "admin", Numeric: true, Min: 1, Max: 1000},
+	{Key: "warn_ladder", Section: "admin", Validate: validateWarnLadder},
+	{Key: "warn_expiry_days", Section: "admin", Unit: duration.Day, Min: 0, Max: 365},
+	{Key: "min_duration", Section: "admin", Validate: validateMinDuration},
+	{Key: "max_mute_duration", Section: "admin", Validate: validateMaxDuration},
+	{Key: "max_ban_duration", Section: "admin", Validate: validateMaxDuration},
+	{Key: "flood_max_messages", Section: "admin", Numeric: true, Min: 0, Max: 100},
+	{Key: "flood_window_seconds", Section: "admin", Numeric: true, Min: 1, Max: 300},
+	{Key: "flood_action", Section: "admin", Options: []string{FloodActionDelete, FloodActionMute, FloodActionKick, FloodActionBan}},
+	{Key: "flood_mute_hours", Section: "admin", Unit: time.Hour, Min: 1, Max: 168},
+	{Key: "locale", Section: "i18n", Options: i18n.Locales()},
+	{Key: "message_logging", Section: "logging", Options: []string{MessageLogOff, MessageLogMetadata, MessageLogHashed, MessageLogFull}},
+	{Key: "message_retention_days", Section: "logging", Numeric: true, Min: 0, Max: 3650},
+}
+
+func valueOrDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// durationValue zeigt eine nicht gesetzte Dauer wie früher als 0 an
+func durationValue(d Duration) string {
+	return valueOrDefault(string(d), "0")
+}
+
+func validateWarnLadder(value string) error {
+	_, err := ParseWarnLadder(value)
+	return err
+}
+
+// LookupSetting sucht die Beschreibung eines Konfigurationsschlüssels
+func LookupSetting(key string) (*Setting, bool) {
+	for i := range Settings {
+		if Settings[i].Key == key {
+			return &Settings[i], true
+		}
+	}
+	return nil, false
+}
+
+// Parse validiert einen Wert und gibt ihn im passenden Typ zurück (int oder string)
+func (s *Setting) Parse(value string) (interface{}, error) {
+	if s.Unit != 0 {
+		d, err := parseDurationValue(value, s.Unit)
+		if err != nil {
+			return nil, err
+		}
+		min, max := time.Duration(s.Min)*s.Unit, time.Duration(s.Max)*s.Unit
+		if d < min || d > max {
+			return nil, i18n.NewError("config.out_of_range", i18n.Vars{"key": s.Key, "min": duration.Format(min), "max": duration.Format(max)})
+		}
+		return strings.TrimSpace(value), nil
+	}
+
+	if !s.Numeric {
+		if s.Validate != nil {
+			if err := s.Validate(value); err != nil {
+				return nil, fmt.Errorf("%s: %w", s.Key, err)
+			}
+		}
+		if len(s.Options) == 0 {
+			return value, nil
+		}
+		for _, option := range s.Options {
+			if value == option {
+				return value, nil
+			}
+		}
+		return nil, i18n.NewError("config.invalid_option", i18n.Vars{"key": s.Key, "options": strings.Join(s.Options, ", ")})
+	}
+
+	val, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, i18n.NewError("config.expected_number", i18n.Vars{"key": s.Key})
+	}
+	if val < s.Min || val > s.Max {
+		return nil, i18n.NewError("config.out_of_range", i18n.Vars{"key": s.Key, "min": s.Min, "max": s.Max})
+	}
+	return val, nil
+}
+
+// GetValue liefert den aktuellen Wert eines Schlüssels als String
+func (c *Config) GetValue(key string) (string, error) {
+	switch key {
+	case "timeout_minutes":
+		return durationValue(c.Captcha.TimeoutMinutes), nil
+	case "max_attempts":
+		return strconv.Itoa(c.Captcha.MaxAttempts), nil
+	case "welcome_message":
+		return c.Captcha.WelcomeMessage, nil
+	case "welcome_format":
+		return valueOrDefault(c.Captcha.WelcomeFormat, WelcomeFormatPlain), nil
+	case "message_delete_delay_minutes":
+		return strconv.Itoa(c.Captcha.MessageDeleteDelayMinutes), nil
+	case "success_message_delete_delay_minutes":
+		return strconv.Itoa(c.Captcha.SuccessMessageDeleteDelayMinutes), nil
+	case "challenge_type":
+		if c.Captcha.ChallengeType == "" {
+			return "math", nil
+		}
+		return c.Captcha.ChallengeType, nil
+	case "rules_acceptance":
+		return valueOrDefault(c.Captcha.RulesAcceptance, RulesAcceptanceOff), nil
+	case "grant_permissions":
+		return valueOrDefault(c.Captcha.GrantPermissions, GrantPermissionsRestore), nil
+	case "fail_action":
+		return valueOrDefault(c.Captcha.FailAction, CaptchaFailKick), nil
+	case "default_mute_hours":
+		return durationValue(c.Admin.DefaultMuteHours), nil
+	case "max_delete_messages":
+		return strconv.Itoa(c.Admin.MaxDeleteMessages), nil
+	case "warn_ladder":
+		if c.Admin.WarnLadder == "" {
+			return "off", nil
+		}
+		return c.Admin.WarnLadder, nil
+	case "warn_expiry_days":
+		return durationValue(c.Admin.WarnExpiryDays), nil
+	case "min_duration":
+		return valueOrDefault(c.Admin.MinDuration, defaultMinDuration), nil
+	case "max_mute_duration":
+		return valueOrDefault(c.Admin.MaxMuteDuration, defaultMaxMuteDuration), nil
+	case "max_ban_duration":
+		return valueOrDefault(c.Admin.MaxBanDuration, defaultMaxBanDuration), nil
+	case "flood_max_messages":
+		return strconv.Itoa(c.Admin.FloodMaxMessages), nil
+	case "flood_window_seconds":
+		return strconv.Itoa(c.Admin.FloodWindowSeconds), nil
+	case "flood_action":
+		if c.Admin.FloodAction == "" {
+			return FloodActionDelete, nil
+		}
+		return c.Admin.FloodAction, nil
+	case "flood_mute_hours":
+		return durationValue(c.Admin.FloodMuteHours), nil
+	case "locale":
+		return valueOrDefault(c.I18n.Locale, i18n.DefaultLocale), nil
+	case "message_logging":
+		return valueOrDefault(c.Logging.MessageLogging, MessageLogMetadata), nil
+	case "message_retention_days":
+		return strconv.Itoa(c.Logging.MessageRetentionDays), nil
+	}
+	return "", i18n.NewError("config.unknown_key", i18n.Vars{"key": key})
+}
+
+// SetValue validiert einen Wert und schreibt ihn in die Config
+func (c *Config) SetValue(key, value string) error {
+	setting, ok := LookupSetting(key)
+	if !ok {
+		return i18n.NewError("config.unknown_key", i18n.Vars{"key": key})
+	}
+
+	parsed, err := setting.Parse(value)
+	if err != nil {
+		return err
+	}
+
+	switch key {
+	case "timeout_minutes":
+		c.Captcha.TimeoutMinutes = Duration(parsed.(string))
+	case "max_attempts":
+		c.Captcha.MaxAttempts = parsed.(int)
+	case "welcome_message":
+		c.Captcha.WelcomeMessage = parsed.(string)
+	case "welcome_format":
+		c.Captcha.WelcomeFormat = parsed.(string)
+	case "message_delete_delay_minutes":
+		c.Captcha.MessageDeleteDelayMinutes = parsed.(int)
+	case "success_message_delete_delay_minutes":
+		c.Captcha.SuccessMessageDeleteDelayMinutes = parsed.(int)
+	case "challenge_type":
+		c.Captcha.ChallengeType = parsed.(string)
+	case "rules_acceptance":
+		c.Captcha.RulesAcceptance = parsed.(string)
+	case "grant_permissions":
+		c.Captcha.GrantPermissions = strings.ReplaceAll(parsed.(string), " ", "")
+	case "fail_action":
+		c.Captcha.FailAction = parsed.(string)
+	case "default_mute_hours":
+		c.Admin.DefaultMuteHours = Duration(parsed.(string))
+	case "max_delete_messages":
+		c.Admin.MaxDeleteMessages = parsed.(int)
+	case "warn_ladder":
+		c.Admin.WarnLadder = parsed.(string)
+	case "warn_expiry_days":
+		c.Admin.WarnExpiryDays = Duration(parsed.(string))
+	case "min_duration":
+		c.Admin.MinDuration = parsed.(string)
+	case "max_mute_duration":
+		c.Admin.MaxMuteDuration = parsed.(string)
+	case "max_ban_duration":
+		c.Admin.MaxBanDuration = parsed.(string)
+	case "flood_max_messages":
+		c.Admin.FloodMaxMessages = parsed.(int)
+	case "flood_window_seconds":
+		c.Admin.FloodWindowSeconds = parsed.(int)
+	case "flood_action":
+		c.Admin.FloodAction = parsed.(string)
+	case "flood_mute_hours":
+		c.Admin.FloodMuteHours = Duration(parsed.(string))
+	case "locale":
+		c.I18n.Locale = parsed.(string)
+	case "message_logging":
+		c.Logging.MessageLogging = parsed.(string)
+	case "message_retention_days":
+		c.Logging.MessageRetentionDays = parsed.(int)
+	}
+	return nil
+}
+
+// WithOverrides erstellt eine Kopie der Config mit gruppenspezifischen Werten.
+// Ungültige Overrides werden übersprungen und als Fehler zurückgegeben.
+func (c *Config) WithOverrides(overrides map[string]string) (*Config, []error) {
+	merged := *c
+	var errs []error
+
+	for key, value := range overrides {
+		if err := merged.SetValue(key, value); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return &merged, errs
+}
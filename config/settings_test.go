@@ -0,0 +1,15 @@
+package config
+
+import (
+	"telegramBot/pkg/i18n"
+	"testing"
+)
+
+// Die /config-Menüs lesen die Beschreibungen nur aus dem Katalog
+func TestSettingDescriptions(t *testing.T) {
+	for _, setting := range Settings {
+		if _, ok := i18n.Lookup(i18n.DefaultLocale, "setting."+setting.Key); !ok {
+			t.Errorf("setting %s has no catalog description", setting.Key)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"telegramBot/pkg/duration"
+	"telegramBot/pkg/i18n"
+	"time"
+)
+
+// Aktionen, die eine Stufe der Verwarnungs-Eskalation auslösen kann
+const (
+	WarnActionMute = "mute"
+	WarnActionKick = "kick"
+	WarnActionBan  = "ban"
+)
+
+// WarnStep ist eine Stufe der Eskalation: ab Count aktiven Verwarnungen wird Action ausgeführt
+type WarnStep struct {
+	Count    int
+	Action   string
+	Duration time.Duration // nur für mute, duration.Permanent für dauerhaftes Mute
+}
+
+// ParseWarnLadder liest eine Eskalationsleiter im Format "3:mute:1d,5:ban".
+// Ein leerer Wert oder "off" deaktiviert die automatische Eskalation.
+func ParseWarnLadder(value string) ([]WarnStep, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "off" {
+		return nil, nil
+	}
+
+	var steps []WarnStep
+	seen := make(map[int]bool)
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, i18n.NewError("warn_ladder.invalid_step", i18n.Vars{"step": entry})
+		}
+
+		count, err := strconv.Atoi(parts[0])
+		if err != nil || count < 1 {
+			return nil, i18n.NewError("warn_ladder.invalid_count", i18n.Vars{"step": entry})
+		}
+		if seen[count] {
+			return nil, i18n.NewError("warn_ladder.duplicate_count", i18n.Vars{"count": count})
+		}
+		seen[count] = true
+
+		step := WarnStep{Count: count, Action: parts[1]}
+		switch step.Action {
+		case WarnActionMute:
+			if len(parts) != 3 {
+				return nil, i18n.NewError("warn_ladder.duration_required", i18n.Vars{"step": entry, "count": count})
+			}
+			step.Duration, err = duration.Parse(parts[2])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", entry, err)
+			}
+		case WarnActionKick, WarnActionBan:
+			if len(parts) != 2 {
+				return nil, i18n.NewError("warn_ladder.no_duration", i18n.Vars{"step": entry})
+			}
+		default:
+			return nil, i18n.NewError("warn_ladder.unknown_action", i18n.Vars{"step": entry})
+		}
+
+		steps = append(steps, step)
+	}
+
+	sort.Slice(steps, func(i, j int) bool { return steps[i].Count < steps[j].Count })
+	return steps, nil
+}
+
+// WarnStepFor liefert die Stufe, die bei count aktiven Verwarnungen greift.
+// Oberhalb der letzten Stufe wird diese erneut angewendet.
+func WarnStepFor(steps []WarnStep, count int) (WarnStep, bool) {
+	for _, step := range steps {
+		if step.Count == count {
+			return step, true
+		}
+	}
+	if len(steps) > 0 && count > steps[len(steps)-1].Count {
+		return steps[len(steps)-1], true
+	}
+	return WarnStep{}, false
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"telegramBot/pkg/i18n"
+	"testing"
+	"time"
+)
+
+func TestParseWarnLadder(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []WarnStep
+		wantKey string // erwarteter i18n-Schlüssel bei Fehlern
+	}{
+		{value: ""},
+		{value: "off"},
+		{value: "5:ban, 3:mute:1d", want: []WarnStep{
+			{Count: 3, Action: WarnActionMute, Duration: 24 * time.Hour},
+			{Count: 5, Action: WarnActionBan},
+		}},
+		{value: "2:kick", want: []WarnStep{{Count: 2, Action: WarnActionKick}}},
+		{value: "3", wantKey: "warn_ladder.invalid_step"},
+		{value: "x:ban", wantKey: "warn_ladder.invalid_count"},
+		{value: "0:ban", wantKey: "warn_ladder.invalid_count"},
+		{value: "3:ban,3:kick", wantKey: "warn_ladder.duplicate_count"},
+		{value: "3:mute", wantKey: "warn_ladder.duration_required"},
+		{value: "3:ban:1d", wantKey: "warn_ladder.no_duration"},
+		{value: "3:shout", wantKey: "warn_ladder.unknown_action"},
+		{value: "3:mute:1x", wantKey: "duration.unknown_unit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseWarnLadder(tt.value)
+			if tt.wantKey == "" {
+				if err != nil || !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("ParseWarnLadder(%q) = %+v, %v, want %+v", tt.value, got, err, tt.want)
+				}
+				return
+			}
+
+			var i18nErr *i18n.Error
+			if !errors.As(err, &i18nErr) || i18nErr.Key != tt.wantKey {
+				t.Errorf("ParseWarnLadder(%q) error = %v, want %s", tt.value, err, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestWarnStepFor(t *testing.T) {
+	steps, err := ParseWarnLadder("2:mute:1h,4:ban")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		count  int
+		action string // "" wenn keine Stufe greift
+	}{
+		{1, ""},
+		{2, WarnActionMute},
+		{3, ""},
+		{4, WarnActionBan},
+		{6, WarnActionBan},
+	}
+
+	for _, tt := range tests {
+		step, ok := WarnStepFor(steps, tt.count)
+		if ok != (tt.action != "") || step.Action != tt.action {
+			t.Errorf("WarnStepFor(%d) = %+v, %v, want %q", tt.count, step, ok, tt.action)
+		}
+	}
+
+	if _, ok := WarnStepFor(nil, 3); ok {
+		t.Error("WarnStepFor without a ladder returned a step")
+	}
+}
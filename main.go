@@ -53,18 +53,47 @@ func main() {
 func registerHandlers(b *bot.Bot) {
 	b.RegisterHandler("new_member", captcha.NewHandler())
 	b.RegisterHandler("callback", captcha.NewCallbackHandler())
+	b.RegisterHandler("callback_modlist", admin.NewModListCallbackHandler())
+	b.RegisterHandler("callback_groups", admin.NewGroupsCallbackHandler())
+	b.RegisterHandler("callback_rules", captcha.NewRulesCallbackHandler())
 	b.RegisterHandler("captcha_message", captcha.NewMessageHandler())
+	b.RegisterHandler("flood", admin.NewFloodHandler())
 	b.RegisterHandler("message", handlers.NewMessageHandler())
 
 	b.RegisterHandler("ban", admin.NewBanHandler())
+	b.RegisterHandler("tban", admin.NewTempBanHandler())
+	b.RegisterHandler("unban", admin.NewUnbanHandler())
+	b.RegisterHandler("tbans", admin.NewTempBansHandler())
+	b.RegisterHandler("banlist", admin.NewBanListHandler())
+	b.RegisterHandler("mutelist", admin.NewMuteListHandler())
+	b.RegisterHandler("history", admin.NewHistoryHandler())
 	b.RegisterHandler("kick", admin.NewKickHandler())
 	b.RegisterHandler("mute", admin.NewMuteHandler())
 	b.RegisterHandler("unmute", admin.NewUnmuteHandler())
 	b.RegisterHandler("del", admin.NewDeleteHandler())
+	b.RegisterHandler("purge", admin.NewPurgeHandler())
+	b.RegisterHandler("warn", admin.NewWarnHandler())
+	b.RegisterHandler("unwarn", admin.NewUnwarnHandler())
+	b.RegisterHandler("warns", admin.NewWarnsHandler())
+	b.RegisterHandler("resetwarns", admin.NewResetWarnsHandler())
 	b.RegisterHandler("help", admin.NewHelpHandler())
+	b.RegisterHandler("forgetme", handlers.NewForgetMeHandler())
 	b.RegisterHandler("permissions", admin.NewPermissionsHandler())
+	b.RegisterHandler("groups", admin.NewGroupsHandler())
 	b.RegisterHandler("config", admin.NewConfigHandler())
+	b.RegisterHandler("template", admin.NewTemplateHandler())
+	b.RegisterHandler("setwelcome", admin.NewSetWelcomeHandler())
+	b.RegisterHandler("setrules", admin.NewSetRulesHandler())
+	b.RegisterHandler("rules", admin.NewRulesHandler())
+	b.RegisterHandler("start", admin.NewStartHandler())
 	b.RegisterHandler("add_admin", admin.NewAddAdminHandler())
 	b.RegisterHandler("del_admin", admin.NewDelAdminHandler())
+	b.RegisterHandler("admins", admin.NewAdminsHandler())
+	b.RegisterHandler("roleperms", admin.NewRolePermsHandler())
+	b.RegisterHandler("claim", admin.NewClaimHandler())
 	b.RegisterHandler("bootstrap", admin.NewBootstrapHandler())
+
+	b.RegisterJobHandler(bot.JobKickPending, captcha.KickPendingJob)
+	b.RegisterJobHandler(bot.JobUnmute, admin.UnmuteJob)
+	b.RegisterJobHandler(bot.JobUnban, admin.UnbanJob)
 }
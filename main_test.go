@@ -0,0 +1,1909 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"telegramBot/config"
+	"telegramBot/pkg/bot"
+	"telegramBot/pkg/database"
+	"telegramBot/pkg/duration"
+	"telegramBot/pkg/telegramtest"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	testGroupID int64 = -100123
+	testAdminID int64 = 11
+	testUserID  int64 = 42
+	waitTimeout       = 2 * time.Second
+)
+
+var (
+	testAdmin = tgbotapi.User{ID: testAdminID, FirstName: "Admin", UserName: "admin"}
+	testUser  = tgbotapi.User{ID: testUserID, FirstName: "User", UserName: "user"}
+)
+
+type testEnv struct {
+	server *telegramtest.Server
+	bot    *bot.Bot
+}
+
+func newTestEnv(t *testing.T, options ...func(*config.Config)) *testEnv {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Chdir(dir) // commands.log und events.log landen im Temp-Verzeichnis
+
+	server := telegramtest.NewServer()
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{
+		BotToken:    "test-token",
+		APIEndpoint: server.Endpoint(),
+		Captcha: config.CaptchaConfig{
+			TimeoutMinutes:                   "5",
+			MaxAttempts:                      3,
+			WelcomeMessage:                   "Willkommen!",
+			MessageDeleteDelayMinutes:        5,
+			SuccessMessageDeleteDelayMinutes: 1,
+		},
+		Admin: config.AdminConfig{
+			DefaultMuteHours:  "1",
+			MaxDeleteMessages: 100,
+		},
+		Database: config.DatabaseConfig{FilePath: filepath.Join(dir, "bot.db")},
+	}
+	for _, option := range options {
+		option(cfg)
+	}
+
+	b, err := bot.NewBot(cfg)
+	if err != nil {
+		t.Fatalf("NewBot: %v", err)
+	}
+	registerHandlers(b)
+
+	go b.Start()
+	t.Cleanup(func() { b.Stop() })
+
+	server.SetMemberStatus(testGroupID, testAdminID, "administrator")
+
+	return &testEnv{server: server, bot: b}
+}
+
+func (e *testEnv) waitFor(t *testing.T, method string, count int) []telegramtest.Call {
+	t.Helper()
+	calls, err := e.server.WaitForCall(method, count, waitTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return calls
+}
+
+func message(chatID int64, chatType string, from tgbotapi.User, text string) *tgbotapi.Message {
+	msg := &tgbotapi.Message{
+		MessageID: int(time.Now().UnixNano() % 100000),
+		From:      &from,
+		Chat:      &tgbotapi.Chat{ID: chatID, Type: chatType},
+		Date:      int(time.Now().Unix()),
+		Text:      text,
+	}
+
+	if strings.HasPrefix(text, "/") {
+		command := strings.SplitN(text, " ", 2)[0]
+		msg.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(command)}}
+	}
+	return msg
+}
+
+func groupMessage(from tgbotapi.User, text string) tgbotapi.Update {
+	return tgbotapi.Update{Message: message(testGroupID, "supergroup", from, text)}
+}
+
+func privateMessage(from tgbotapi.User, text string) tgbotapi.Update {
+	return tgbotapi.Update{Message: message(from.ID, "private", from, text)}
+}
+
+func replyTo(update tgbotapi.Update, target tgbotapi.User) tgbotapi.Update {
+	update.Message.ReplyToMessage = message(testGroupID, "supergroup", target, "hallo")
+	return update
+}
+
+// captchaAnswer leitet aus dem gespeicherten Challenge-Zustand eine richtige oder falsche Antwort ab
+func captchaAnswer(t *testing.T, pending *database.PendingUser, correct bool) string {
+	t.Helper()
+
+	switch pending.ChallengeType {
+	case "math":
+		m := regexp.MustCompile(`^(\d+)([+\-*])(\d+)$`).FindStringSubmatch(pending.ChallengeState)
+		if m == nil {
+			t.Fatalf("unexpected math state %q", pending.ChallengeState)
+		}
+		a, _ := strconv.Atoi(m[1])
+		c, _ := strconv.Atoi(m[3])
+		solution := map[string]int{"+": a + c, "-": a - c, "*": a * c}[m[2]]
+		if !correct {
+			solution++
+		}
+		return strconv.Itoa(solution)
+
+	case "image":
+		if correct {
+			return pending.ChallengeState
+		}
+		return strings.Repeat("1", len(pending.ChallengeState)+1)
+
+	default:
+		var state struct {
+			Target  string   `json:"target"`
+			Options []string `json:"options"`
+		}
+		if err := json.Unmarshal([]byte(pending.ChallengeState), &state); err != nil {
+			t.Fatalf("unexpected pick state %q: %v", pending.ChallengeState, err)
+		}
+		if correct {
+			return state.Target
+		}
+		for _, option := range state.Options {
+			if option != state.Target {
+				return option
+			}
+		}
+		t.Fatal("no wrong option available")
+		return ""
+	}
+}
+
+func TestCaptchaFlow(t *testing.T) {
+	tests := []struct {
+		challenge    string
+		correct      bool
+		promptMethod string
+		resultMethod string
+		resultParam  string
+		wantText     string
+	}{
+		{"math", true, "sendMessage", "sendMessage", "text", "erfolgreich gelöst"},
+		{"math", false, "sendMessage", "sendMessage", "text", "Falsche Antwort"},
+		{"image", true, "sendPhoto", "sendMessage", "text", "erfolgreich gelöst"},
+		{"image", false, "sendPhoto", "sendMessage", "text", "Falsche Antwort"},
+		{"emoji", true, "sendMessage", "sendMessage", "text", "erfolgreich gelöst"},
+		{"emoji", false, "sendMessage", "answerCallbackQuery", "text", "Falsch!"},
+		{"word", true, "sendMessage", "sendMessage", "text", "erfolgreich gelöst"},
+		{"word", false, "sendMessage", "answerCallbackQuery", "text", "Falsch!"},
+	}
+
+	for _, tt := range tests {
+		name := tt.challenge + "/wrong"
+		if tt.correct {
+			name = tt.challenge + "/correct"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			env := newTestEnv(t, func(cfg *config.Config) {
+				cfg.Captcha.ChallengeType = tt.challenge
+			})
+
+			join := message(testGroupID, "supergroup", testUser, "")
+			join.NewChatMembers = []tgbotapi.User{testUser}
+			env.server.PushUpdate(tgbotapi.Update{Message: join})
+
+			env.waitFor(t, "restrictChatMember", 1)
+			prompt := env.waitFor(t, tt.promptMethod, 1)
+
+			pending, err := env.bot.GetDB().GetPendingUser(testUserID, testGroupID)
+			if err != nil {
+				t.Fatalf("pending user not stored: %v", err)
+			}
+			if pending.ChallengeType != tt.challenge {
+				t.Fatalf("challenge type = %q, want %q", pending.ChallengeType, tt.challenge)
+			}
+
+			answer := captchaAnswer(t, pending, tt.correct)
+			if tt.challenge == "emoji" || tt.challenge == "word" {
+				if !strings.Contains(prompt[0].Params.Get("reply_markup"), "captcha_pick:") {
+					t.Fatalf("prompt has no answer buttons: %s", prompt[0].Params.Get("reply_markup"))
+				}
+				welcome := message(testGroupID, "supergroup", tgbotapi.User{ID: telegramtest.BotUserID, IsBot: true}, "")
+				env.server.PushUpdate(tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+					ID:      "cb-1",
+					From:    &testUser,
+					Message: welcome,
+					Data:    "captcha_pick:" + answer,
+				}})
+			} else {
+				env.server.PushUpdate(groupMessage(testUser, answer))
+			}
+
+			count := 1
+			if tt.resultMethod == tt.promptMethod {
+				count = 2
+			}
+			results := env.waitFor(t, tt.resultMethod, count)
+			if text := results[count-1].Params.Get(tt.resultParam); !strings.Contains(text, tt.wantText) {
+				t.Errorf("%s = %q, want it to contain %q", tt.resultMethod, text, tt.wantText)
+			}
+
+			if tt.correct {
+				// Beide Eingabewege vergeben dieselben Standardrechte
+				restricts := env.waitFor(t, "restrictChatMember", 2)
+				permissions := restricts[1].Params.Get("permissions")
+				if !strings.Contains(permissions, `"can_send_media_messages":true`) || strings.Contains(permissions, `"can_pin_messages":true`) {
+					t.Errorf("granted permissions = %s, want the default set", permissions)
+				}
+				if _, err := env.bot.GetDB().GetWelcomeMessage(testUserID, testGroupID); err == nil {
+					t.Error("captcha message record was not cleaned up")
+				}
+			} else if restricts := env.server.Calls("restrictChatMember"); len(restricts) != 1 {
+				t.Errorf("restrictChatMember called %d times after wrong answer, want 1", len(restricts))
+			}
+		})
+	}
+}
+
+func TestCaptchaPolicy(t *testing.T) {
+	policy := func(challenge string) func(cfg *config.Config) {
+		return func(cfg *config.Config) {
+			cfg.Captcha.ChallengeType = challenge
+			cfg.Captcha.MaxAttempts = 1
+			cfg.Captcha.FailAction = config.CaptchaFailBan
+			cfg.Captcha.GrantPermissions = "messages,invite_users"
+		}
+	}
+
+	join := func(env *testEnv) *database.PendingUser {
+		msg := message(testGroupID, "supergroup", testUser, "")
+		msg.NewChatMembers = []tgbotapi.User{testUser}
+		env.server.PushUpdate(tgbotapi.Update{Message: msg})
+		env.waitFor(t, "sendMessage", 1)
+		pending, err := env.bot.GetDB().GetPendingUser(testUserID, testGroupID)
+		if err != nil {
+			t.Fatalf("pending user not stored: %v", err)
+		}
+		return pending
+	}
+
+	t.Run("configured permissions are granted", func(t *testing.T) {
+		env := newTestEnv(t, policy("math"))
+		pending := join(env)
+		env.server.PushUpdate(groupMessage(testUser, captchaAnswer(t, pending, true)))
+
+		restricts := env.waitFor(t, "restrictChatMember", 2)
+		permissions := restricts[1].Params.Get("permissions")
+		if !strings.Contains(permissions, `"can_invite_users":true`) || strings.Contains(permissions, `"can_send_media_messages":true`) {
+			t.Errorf("granted permissions = %s, want messages and invite_users only", permissions)
+		}
+	})
+
+	t.Run("fail action ban applies to button answers", func(t *testing.T) {
+		env := newTestEnv(t, policy("emoji"))
+		pending := join(env)
+		env.server.PushUpdate(tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+			ID:      "cb-1",
+			From:    &testUser,
+			Message: message(testGroupID, "supergroup", tgbotapi.User{ID: telegramtest.BotUserID, IsBot: true}, ""),
+			Data:    "captcha_pick:" + captchaAnswer(t, pending, false),
+		}})
+
+		bans := env.waitFor(t, "banChatMember", 1)
+		if got := bans[0].Int64("user_id"); got != testUserID {
+			t.Errorf("banned user = %d, want %d", got, testUserID)
+		}
+		answers := env.waitFor(t, "answerCallbackQuery", 1)
+		if text := answers[0].Params.Get("text"); !strings.Contains(text, "Zu viele") {
+			t.Errorf("callback answer = %q", text)
+		}
+		if unbans := env.server.Calls("unbanChatMember"); len(unbans) != 0 {
+			t.Errorf("unbanChatMember called %d times, want 0 for fail_action ban", len(unbans))
+		}
+		if _, err := env.bot.GetDB().GetPendingUser(testUserID, testGroupID); err == nil {
+			t.Error("user still pending after failing the captcha")
+		}
+	})
+}
+
+func TestCaptchaTimeout(t *testing.T) {
+	env := newTestEnv(t)
+	db := env.bot.GetDB()
+	// Der Gruppenwert gilt für Ablauf, Hinweis und Kick; message_delete_delay_minutes bleibt bei 5
+	if err := db.SetGroupSetting(testGroupID, "timeout_minutes", "2m"); err != nil {
+		t.Fatal(err)
+	}
+
+	join := message(testGroupID, "supergroup", testUser, "")
+	join.NewChatMembers = []tgbotapi.User{testUser}
+	env.server.PushUpdate(tgbotapi.Update{Message: join})
+	prompt := env.waitFor(t, "sendMessage", 1)
+
+	if text := prompt[0].Params.Get("text"); !strings.Contains(text, "Du hast 2m Zeit") {
+		t.Errorf("prompt = %q, want the group timeout", text)
+	}
+	pending, err := db.GetPendingUser(testUserID, testGroupID)
+	if err != nil || pending == nil {
+		t.Fatalf("pending user not stored (err=%v)", err)
+	}
+	if d := time.Until(pending.ExpiresAt); d < 119*time.Second || d > 2*time.Minute {
+		t.Errorf("captcha expires in %s, want 2m", d)
+	}
+
+	due := func(after time.Duration) bool {
+		jobs, err := db.GetDueJobs(time.Now().Add(after))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return slices.ContainsFunc(jobs, func(job database.Job) bool {
+			return job.Kind == bot.JobKickPending && job.UserID == testUserID
+		})
+	}
+	if !eventually(func() bool { return due(3 * time.Minute) }) || due(time.Minute) {
+		t.Error("captcha kick is not scheduled after the group timeout")
+	}
+}
+
+func TestRestorePermissions(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(env *testEnv)
+		want     string
+		unwanted string
+	}{
+		{
+			name: "group defaults without media",
+			setup: func(env *testEnv) {
+				env.server.SetChatPermissions(testGroupID, tgbotapi.ChatPermissions{CanSendMessages: true, CanSendPolls: true})
+			},
+			want:     `"can_send_polls":true`,
+			unwanted: `"can_send_media_messages":true`,
+		},
+		{
+			name: "previously restricted member",
+			setup: func(env *testEnv) {
+				env.server.SetMemberRestrictions(testGroupID, testUserID, tgbotapi.ChatPermissions{CanSendMessages: true})
+			},
+			want:     `"can_send_messages":true`,
+			unwanted: `"can_send_polls":true`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			tt.setup(env)
+
+			env.server.PushUpdate(groupMessage(testAdmin, "/mute 42 1h"))
+			env.waitFor(t, "restrictChatMember", 1)
+			if snapshot, err := env.bot.GetDB().GetPermissionSnapshot(testGroupID, testUserID); err != nil || snapshot == nil {
+				t.Fatalf("no permission snapshot after mute (err=%v)", err)
+			}
+
+			env.server.PushUpdate(groupMessage(testAdmin, "/unmute 42"))
+			restricts := env.waitFor(t, "restrictChatMember", 2)
+			permissions := restricts[1].Params.Get("permissions")
+			if !strings.Contains(permissions, tt.want) || strings.Contains(permissions, tt.unwanted) {
+				t.Errorf("restored permissions = %s, want %s without %s", permissions, tt.want, tt.unwanted)
+			}
+
+			env.waitFor(t, "sendMessage", 2)
+			if snapshot, _ := env.bot.GetDB().GetPermissionSnapshot(testGroupID, testUserID); snapshot != nil {
+				t.Error("permission snapshot kept after unmute")
+			}
+		})
+	}
+}
+
+func TestDeleteMessages(t *testing.T) {
+	other := tgbotapi.User{ID: 77, UserName: "other", FirstName: "Other"}
+
+	// Verlauf: 10-14 im Abstand von einer Minute, ungerade IDs von other, 10 ist 30 Minuten alt
+	history := func(env *testEnv) {
+		for i, id := range []int{10, 11, 12, 13, 14} {
+			from := testUser
+			if id%2 == 1 {
+				from = other
+			}
+			msg := message(testGroupID, "supergroup", from, "nachricht")
+			msg.MessageID = id
+			msg.Date = int(time.Now().Add(time.Duration(i-4) * time.Minute).Unix())
+			if id == 10 {
+				msg.Date = int(time.Now().Add(-30 * time.Minute).Unix())
+			}
+			env.server.PushUpdate(tgbotapi.Update{Message: msg})
+		}
+	}
+
+	command := func(text string, reply int) tgbotapi.Update {
+		update := groupMessage(testAdmin, text)
+		update.Message.MessageID = 20
+		if reply > 0 {
+			update.Message.ReplyToMessage = message(testGroupID, "supergroup", other, "")
+			update.Message.ReplyToMessage.MessageID = reply
+		}
+		return update
+	}
+
+	tests := []struct {
+		name   string
+		update tgbotapi.Update
+		want   string
+	}{
+		{"last n", command("/del 2", 0), "[13,14,20]"},
+		{"from reply", command("/del from-reply", 12), "[12,13,14,20]"},
+		{"since", command("/del since 10m", 0), "[11,12,13,14,20]"},
+		{"purge user", command("/purge @other 5", 0), "[11,13,20]"},
+		{"purge by reply", command("/purge 1", 13), "[13,20]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			history(env)
+			for deadline := time.Now().Add(waitTimeout); len(env.bot.MessageIndex().Messages(testGroupID)) < 5; {
+				if time.Now().After(deadline) {
+					t.Fatal("history was not indexed")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			env.server.PushUpdate(tt.update)
+
+			calls := env.waitFor(t, "deleteMessages", 1)
+			if got := calls[0].Params.Get("message_ids"); got != tt.want {
+				t.Errorf("message_ids = %s, want %s", got, tt.want)
+			}
+			if probes := env.server.Calls("deleteMessage"); len(probes) != 0 {
+				t.Errorf("deleteMessage called %d times, want only the batched call", len(probes))
+			}
+		})
+	}
+}
+
+func TestModerationCommands(t *testing.T) {
+	tests := []struct {
+		name   string
+		update tgbotapi.Update
+		method string
+		count  int
+		check  func(t *testing.T, env *testEnv, calls []telegramtest.Call)
+	}{
+		{
+			name:   "ban by user id",
+			update: groupMessage(testAdmin, "/ban 42 Spam"),
+			method: "banChatMember",
+			count:  1,
+			check: func(t *testing.T, env *testEnv, calls []telegramtest.Call) {
+				if got := calls[0].Int64("user_id"); got != testUserID {
+					t.Errorf("banned user = %d, want %d", got, testUserID)
+				}
+			},
+		},
+		{
+			name:   "temp ban with duration",
+			update: groupMessage(testAdmin, "/tban 42 3d Spam"),
+			method: "banChatMember",
+			count:  1,
+			check: func(t *testing.T, env *testEnv, calls []telegramtest.Call) {
+				until := time.Unix(calls[0].Int64("until_date"), 0)
+				if d := time.Until(until); d < 71*time.Hour || d > 72*time.Hour {
+					t.Errorf("until_date = %s, want about 3 days from now", until)
+				}
+				var banned *database.BannedUser
+				var err error
+				eventually(func() bool {
+					banned, err = env.bot.GetDB().GetBannedUser(testUserID, testGroupID)
+					return err == nil
+				})
+				if err != nil || banned.Reason != "Spam" || banned.Until.IsZero() {
+					t.Errorf("temp ban not stored (banned=%+v, err=%v)", banned, err)
+				}
+			},
+		},
+		{
+			name:   "unban by user id",
+			update: groupMessage(testAdmin, "/unban 42"),
+			method: "unbanChatMember",
+			count:  1,
+			check: func(t *testing.T, env *testEnv, calls []telegramtest.Call) {
+				if got := calls[0].Int64("user_id"); got != testUserID {
+					t.Errorf("unbanned user = %d, want %d", got, testUserID)
+				}
+			},
+		},
+		{
+			name:   "mute by reply",
+			update: replyTo(groupMessage(testAdmin, "/mute 2 Störend"), testUser),
+			method: "restrictChatMember",
+			count:  1,
+			check: func(t *testing.T, env *testEnv, calls []telegramtest.Call) {
+				if strings.Contains(calls[0].Params.Get("permissions"), "can_send_messages") {
+					t.Errorf("mute should revoke can_send_messages, got %s", calls[0].Params.Get("permissions"))
+				}
+				muted, err := env.bot.GetDB().IsUserMuted(testUserID, testGroupID)
+				if err != nil || !muted {
+					t.Errorf("user not stored as muted (err=%v)", err)
+				}
+			},
+		},
+		{
+			name:   "mute with combined duration",
+			update: groupMessage(testAdmin, "/mute 42 1h30m Spam"),
+			method: "restrictChatMember",
+			count:  1,
+			check: func(t *testing.T, env *testEnv, calls []telegramtest.Call) {
+				muted, err := env.bot.GetDB().GetMutedUser(testUserID, testGroupID)
+				if err != nil {
+					t.Fatalf("GetMutedUser: %v", err)
+				}
+				if d := time.Until(muted.Until); d < 89*time.Minute || d > 90*time.Minute {
+					t.Errorf("muted until %s, want about 90 minutes from now", muted.Until)
+				}
+			},
+		},
+		{
+			name:   "invalid duration names failing token",
+			update: groupMessage(testAdmin, "/mute 42 1w2x"),
+			method: "sendMessage",
+			count:  1,
+			check: func(t *testing.T, env *testEnv, calls []telegramtest.Call) {
+				if len(env.server.Calls("restrictChatMember")) != 0 {
+					t.Error("user was muted despite invalid duration")
+				}
+				if text := calls[0].Params.Get("text"); !strings.Contains(text, `"2x"`) {
+					t.Errorf("reply = %q, want error naming \"2x\"", text)
+				}
+			},
+		},
+		{
+			name:   "non-admin is rejected",
+			update: groupMessage(testUser, "/ban 11"),
+			method: "sendMessage",
+			count:  1,
+			check: func(t *testing.T, env *testEnv, calls []telegramtest.Call) {
+				if len(env.server.Calls("banChatMember")) != 0 {
+					t.Error("non-admin was able to ban")
+				}
+				if text := calls[0].Params.Get("text"); !strings.Contains(text, "keine Berechtigung") {
+					t.Errorf("reply = %q, want permission error", text)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.server.PushUpdate(tt.update)
+
+			calls := env.waitFor(t, tt.method, tt.count)
+			if tt.check != nil {
+				tt.check(t, env, calls)
+			}
+		})
+	}
+}
+
+func TestWarnEscalation(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) {
+		cfg.Admin.WarnLadder = "2:mute:1h,3:ban"
+		cfg.Admin.MaxMuteDuration = "30m"
+	})
+
+	warn := func(n int) {
+		env.server.PushUpdate(groupMessage(testAdmin, "/warn 42 Spam"))
+		env.waitFor(t, "sendMessage", n)
+	}
+
+	warn(1)
+	if calls := env.server.Calls("restrictChatMember"); len(calls) != 0 {
+		t.Fatalf("first warning should not escalate, got %d restrictions", len(calls))
+	}
+
+	warn(2)
+	calls := env.waitFor(t, "restrictChatMember", 1)
+	if got := calls[0].Int64("user_id"); got != testUserID {
+		t.Errorf("muted user = %d, want %d", got, testUserID)
+	}
+	if muted, err := env.bot.GetDB().GetMutedUser(testUserID, testGroupID); err != nil {
+		t.Errorf("GetMutedUser: %v", err)
+	} else if d := time.Until(muted.Until); d < 29*time.Minute || d > 30*time.Minute {
+		t.Errorf("ladder mute lasts %s, want it clamped to max_mute_duration 30m", d)
+	}
+
+	warn(3)
+	env.waitFor(t, "banChatMember", 1)
+
+	warnings, err := env.bot.GetDB().GetWarnings(testGroupID, testUserID, time.Time{})
+	if err != nil || len(warnings) != 3 {
+		t.Fatalf("stored warnings = %d (err=%v), want 3", len(warnings), err)
+	}
+	if warnings[0].AdminID != testAdminID || warnings[0].Reason != "Spam" {
+		t.Errorf("warning = %+v, want admin %d with reason Spam", warnings[0], testAdminID)
+	}
+
+	env.server.PushUpdate(groupMessage(testAdmin, "/warns 42"))
+	list := env.waitFor(t, "sendMessage", 4)[3].Params.Get("text")
+	if !strings.Contains(list, "3. ") || !strings.Contains(list, " - Spam (Admin-ID: 11)") {
+		t.Errorf("/warns = %q, want three numbered entries with reason and admin", list)
+	}
+
+	env.server.PushUpdate(groupMessage(testAdmin, "/resetwarns 42"))
+	env.waitFor(t, "sendMessage", 5)
+	if warnings, _ := env.bot.GetDB().GetWarnings(testGroupID, testUserID, time.Time{}); len(warnings) != 0 {
+		t.Errorf("warnings after reset = %d, want 0", len(warnings))
+	}
+}
+
+func TestFloodProtection(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     tgbotapi.User
+		action   string
+		muteFor  config.Duration
+		wantMute time.Duration // erwartete Mute-Dauer, 0 = nicht prüfen
+		method   string
+		count    int
+	}{
+		{name: "mute", from: testUser, action: config.FloodActionMute, muteFor: "1", wantMute: time.Hour, method: "restrictChatMember", count: 1},
+		{name: "mute clamped to max_mute_duration", from: testUser, action: config.FloodActionMute, muteFor: "2w", wantMute: duration.Week, method: "restrictChatMember", count: 1},
+		{name: "delete", from: testUser, action: config.FloodActionDelete, method: "deleteMessage", count: 2},
+		{name: "admin is exempt", from: testAdmin, action: config.FloodActionBan},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, func(cfg *config.Config) {
+				cfg.Admin.FloodMaxMessages = 3
+				cfg.Admin.FloodWindowSeconds = 10
+				cfg.Admin.FloodAction = tt.action
+				cfg.Admin.FloodMuteHours = tt.muteFor
+			})
+
+			for i := 0; i < 5; i++ {
+				env.server.PushUpdate(groupMessage(tt.from, fmt.Sprintf("spam %d", i)))
+			}
+
+			if tt.method == "" {
+				time.Sleep(500 * time.Millisecond)
+				for _, method := range []string{"banChatMember", "deleteMessage"} {
+					if calls := env.server.Calls(method); len(calls) != 0 {
+						t.Errorf("admin triggered %s %d times", method, len(calls))
+					}
+				}
+				return
+			}
+
+			calls := env.waitFor(t, tt.method, tt.count)
+			if got := calls[0].Int64("chat_id"); got != testGroupID {
+				t.Errorf("%s chat = %d, want %d", tt.method, got, testGroupID)
+			}
+			if tt.wantMute > 0 {
+				muted, err := env.bot.GetDB().GetMutedUser(testUserID, testGroupID)
+				if err != nil {
+					t.Fatalf("GetMutedUser: %v", err)
+				}
+				if d := time.Until(muted.Until); d < tt.wantMute-time.Minute || d > tt.wantMute {
+					t.Errorf("flood mute lasts %s, want %s", d, tt.wantMute)
+				}
+			}
+		})
+	}
+}
+
+func TestRequestQueue(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures []error // Antworten vor dem ersten Erfolg
+		messages int
+		want     int           // erwartete sendMessage-Aufrufe
+		minDelay time.Duration // Mindestabstand zwischen erstem und letztem sendMessage
+	}{
+		{name: "retry after 429", failures: []error{telegramtest.TooManyRequests(1)}, messages: 1, want: 2, minDelay: 900 * time.Millisecond},
+		{name: "no retry of send methods on 5xx", failures: []error{&telegramtest.APIError{Code: http.StatusBadGateway, Message: "Bad Gateway"}}, messages: 1, want: 1},
+		{name: "private chat limit", messages: 4, want: 4, minDelay: 900 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+
+			var mu sync.Mutex
+			var sent []time.Time
+			env.server.Handle("sendMessage", func(params url.Values) (interface{}, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				sent = append(sent, time.Now())
+				if len(sent) <= len(tt.failures) {
+					return nil, tt.failures[len(sent)-1]
+				}
+				chatID, _ := strconv.ParseInt(params.Get("chat_id"), 10, 64)
+				return tgbotapi.Message{MessageID: len(sent), Chat: &tgbotapi.Chat{ID: chatID}, Text: params.Get("text")}, nil
+			})
+
+			for i := 0; i < tt.messages; i++ {
+				env.server.PushUpdate(privateMessage(testUser, "/help"))
+			}
+
+			env.waitFor(t, "sendMessage", tt.want)
+			time.Sleep(600 * time.Millisecond)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(sent) != tt.want {
+				t.Fatalf("sendMessage called %d times, want %d", len(sent), tt.want)
+			}
+			if delay := sent[len(sent)-1].Sub(sent[0]); delay < tt.minDelay {
+				t.Errorf("requests sent within %s, want at least %s", delay, tt.minDelay)
+			}
+		})
+	}
+}
+
+func TestUpdateOrdering(t *testing.T) {
+	env := newTestEnv(t, func(cfg *config.Config) {
+		cfg.Captcha.ChallengeType = "math"
+		cfg.Dispatcher.Workers = 4
+	})
+
+	// Beitritt und Antwort direkt hintereinander: die Antwort darf den Beitritt nicht überholen
+	join := message(testGroupID, "supergroup", testUser, "")
+	join.NewChatMembers = []tgbotapi.User{testUser}
+	env.server.PushUpdate(tgbotapi.Update{Message: join})
+	env.server.PushUpdate(groupMessage(testUser, "-999999"))
+
+	messages := env.waitFor(t, "sendMessage", 2)
+	if text := messages[1].Params.Get("text"); !strings.Contains(text, "Falsche Antwort") {
+		t.Errorf("second sendMessage = %q, want the wrong answer notice", text)
+	}
+
+	// Processed zählt erst nach dem Ende des Handlers hoch
+	stats := env.bot.DispatcherStats()
+	for deadline := time.Now().Add(waitTimeout); stats.Processed < 2 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		stats = env.bot.DispatcherStats()
+	}
+	if stats.Workers != 4 {
+		t.Errorf("workers = %d, want 4", stats.Workers)
+	}
+	if stats.Processed < 2 {
+		t.Errorf("processed = %d, want at least 2", stats.Processed)
+	}
+}
+
+func TestModerationListPaging(t *testing.T) {
+	env := newTestEnv(t)
+
+	for i := 0; i < 12; i++ {
+		_, err := env.bot.GetDB().AddModerationAction(database.ModerationAction{
+			ChatID:    testGroupID,
+			UserID:    int64(100 + i),
+			AdminID:   testAdminID,
+			Action:    database.ActionBan,
+			Reason:    "Spam",
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("AddModerationAction: %v", err)
+		}
+	}
+
+	env.server.PushUpdate(groupMessage(testAdmin, "/banlist"))
+	calls := env.waitFor(t, "sendMessage", 2)
+
+	var list *telegramtest.Call
+	for i := range calls {
+		if calls[i].Int64("chat_id") == testAdminID {
+			list = &calls[i]
+		}
+	}
+	if list == nil {
+		t.Fatal("ban list was not sent by DM")
+	}
+	if text := list.Params.Get("text"); !strings.Contains(text, "Seite 1/2, 12 Einträge") {
+		t.Errorf("first page = %q", text)
+	}
+
+	next := "modlist:bans:" + strconv.FormatInt(testGroupID, 10) + ":0:1"
+	if markup := list.Params.Get("reply_markup"); !strings.Contains(markup, next) {
+		t.Fatalf("reply_markup = %s, want button %s", markup, next)
+	}
+
+	env.server.PushUpdate(tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:   "page",
+		From: &testAdmin,
+		Message: &tgbotapi.Message{
+			MessageID: 1,
+			Chat:      &tgbotapi.Chat{ID: testAdminID, Type: "private"},
+		},
+		Data: next,
+	}})
+
+	edits := env.waitFor(t, "editMessageText", 1)
+	if text := edits[0].Params.Get("text"); !strings.Contains(text, "Seite 2/2") {
+		t.Errorf("second page = %q", text)
+	}
+}
+
+// readLogLines wartet, bis path mindestens count Zeilen enthält
+// eventually wartet, bis cond erfüllt ist, etwa auf einen Datenbankeintrag nach einem API-Aufruf
+func eventually(cond func() bool) bool {
+	deadline := time.Now().Add(waitTimeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return true
+}
+
+func readLogLines(t *testing.T, path string, count int) []string {
+	t.Helper()
+	deadline := time.Now().Add(waitTimeout)
+	for {
+		data, _ := os.ReadFile(path)
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(data) > 0 && len(lines) >= count {
+			return lines
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s: got %d lines, want %d", path, len(lines), count)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStructuredLogging(t *testing.T) {
+	t.Run("json lines", func(t *testing.T) {
+		newTestEnv(t, func(cfg *config.Config) {
+			cfg.Logging.CommandLog = "cmd.jsonl"
+		}).server.PushUpdate(groupMessage(testAdmin, "/warn 42 Spam"))
+
+		lines := readLogLines(t, "cmd.jsonl", 1)
+		var entry bot.LogEntry
+		if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+			t.Fatalf("command log is not JSON: %v\n%s", err, lines[0])
+		}
+		if entry.Type != "COMMAND" || entry.Command != "warn" || entry.Args != "42 Spam" {
+			t.Errorf("entry = %+v, want COMMAND warn with args", entry)
+		}
+		if entry.ChatID != testGroupID || entry.UserID != testAdminID || entry.Result != bot.ResultSuccess {
+			t.Errorf("entry = %+v, want chat %d, user %d, SUCCESS", entry, testGroupID, testAdminID)
+		}
+		if entry.DurationMS == nil {
+			t.Error("entry has no duration_ms")
+		}
+	})
+
+	t.Run("plain text", func(t *testing.T) {
+		newTestEnv(t, func(cfg *config.Config) {
+			cfg.Logging.Format = config.LogFormatText
+		}).server.PushUpdate(groupMessage(testAdmin, "/warn 42 Spam"))
+
+		lines := readLogLines(t, "commands.log", 1)
+		if !strings.Contains(lines[0], "| Command: warn 42 Spam | Result: SUCCESS") {
+			t.Errorf("text log line = %q", lines[0])
+		}
+	})
+
+	t.Run("multi-line text is purged completely", func(t *testing.T) {
+		env := newTestEnv(t, func(cfg *config.Config) {
+			cfg.Logging.Format = config.LogFormatText
+		})
+		events := env.bot.GetEventLogger()
+		events.LogMessage(config.MessageLogFull, testGroupID, testUserID, "@user", 1, "erste Zeile\nzweite Zeile")
+		events.LogMessage(config.MessageLogFull, testGroupID, testAdminID, "@admin", 2, "bleibt")
+
+		lines := readLogLines(t, "events.log", 2)
+		if len(lines) != 2 || !strings.Contains(lines[0], `Text: erste Zeile\nzweite Zeile`) {
+			t.Fatalf("multi-line message not escaped:\n%s", strings.Join(lines, "\n"))
+		}
+
+		// Ältere Versionen haben den Text unmaskiert geschrieben, die Folgezeile hat keinen Kopf
+		legacy := fmt.Sprintf("[%s] MESSAGE | Chat: %d | User: %d (@user) | Text: alt\nalte Folgezeile\n",
+			time.Now().Format("2006-01-02 15:04:05"), testGroupID, testUserID)
+		f, err := os.OpenFile("events.log", os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.WriteString(legacy)
+		f.Close()
+
+		entries, _, err := env.bot.ForgetUser(testUserID)
+		if err != nil || entries != 2 {
+			t.Fatalf("ForgetUser removed %d log entries (err=%v), want 2", entries, err)
+		}
+		data, _ := os.ReadFile("events.log")
+		if text := string(data); strings.Contains(text, "Zeile") || strings.Contains(text, "Folgezeile") || !strings.Contains(text, "bleibt") {
+			t.Errorf("events.log after /forgetme:\n%s", text)
+		}
+	})
+
+	// readBackups liefert die Zeilen aller .gz-Backups von path, ältestes zuerst
+	readBackups := func(t *testing.T, path string) [][]string {
+		t.Helper()
+		backups, err := filepath.Glob(path + ".*")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(backups)
+
+		var result [][]string
+		for _, backup := range backups {
+			if !strings.HasSuffix(backup, ".gz") {
+				t.Fatalf("rotated file %s was not compressed", backup)
+			}
+			f, err := os.Open(backup)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gz, err := gzip.NewReader(f)
+			if err != nil {
+				f.Close()
+				t.Fatalf("%s: %v", backup, err)
+			}
+			data, err := io.ReadAll(gz)
+			f.Close()
+			if err != nil {
+				t.Fatalf("%s: %v", backup, err)
+			}
+			result = append(result, strings.Split(strings.TrimSpace(string(data)), "\n"))
+		}
+		return result
+	}
+
+	t.Run("size rotation compresses and prunes backups", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "commands.jsonl")
+		logger, err := bot.NewCommandLogger(path, bot.LogOptions{MaxSize: 200, MaxBackups: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Jeder Eintrag ist größer als die halbe Grenze, also rotiert jeder weitere Eintrag
+		for i := 1; i <= 5; i++ {
+			logger.LogCommand(testGroupID, testUserID, "@user", "warn", fmt.Sprintf("eintrag %d", i), time.Millisecond, nil)
+			time.Sleep(5 * time.Millisecond) // Backups tragen einen Zeitstempel in Millisekunden
+		}
+		if err := logger.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		backups := readBackups(t, path)
+		if len(backups) != 2 {
+			t.Fatalf("got %d backups, want max_backups = 2", len(backups))
+		}
+		for i, want := range []string{"eintrag 3", "eintrag 4"} {
+			if len(backups[i]) != 1 || !strings.Contains(backups[i][0], want) {
+				t.Errorf("backup %d = %q, want only %q", i, backups[i], want)
+			}
+		}
+		if current := readLogLines(t, path, 1); len(current) != 1 || !strings.Contains(current[0], "eintrag 5") {
+			t.Errorf("current log = %q, want only the latest entry", current)
+		}
+	})
+
+	t.Run("purge runs beside writers and covers uncompressed backups", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "events.jsonl")
+		// Eine Rotation, deren Komprimierung fehlgeschlagen ist, hinterlässt die Datei ohne .gz
+		leftover := fmt.Sprintf(`{"time":"2020-01-01T00:00:00Z","type":"MESSAGE","chat_id":%d,"user_id":%d}`+"\n", testGroupID, testUserID)
+		if err := os.WriteFile(path+".20200101-000000.000", []byte(leftover), 0644); err != nil {
+			t.Fatal(err)
+		}
+		events, err := bot.NewEventLogger(path, bot.LogOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer events.Close()
+
+		const writes = 200
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < writes; i++ {
+				events.LogEvent("USER_JOINED", testGroupID, testAdminID, "@admin", "bleibt")
+				if i%2 == 0 {
+					events.LogEvent("USER_JOINED", testGroupID, testUserID, "@user", "weg")
+				}
+			}
+		}()
+		removed := 0
+		for purging := true; purging; {
+			select {
+			case <-done:
+				purging = false
+			default:
+			}
+			n, err := events.Purge(func(entry bot.LogEntry) bool { return entry.UserID == testUserID })
+			if err != nil {
+				t.Fatalf("Purge: %v", err)
+			}
+			removed += n
+		}
+
+		if removed != writes/2+1 {
+			t.Errorf("removed %d entries, want %d", removed, writes/2+1)
+		}
+		lines := readLogLines(t, path, writes)
+		if len(lines) != writes || strings.Contains(strings.Join(lines, "\n"), `"user_id":42`) {
+			t.Errorf("log has %d lines after purging, want %d entries of the other user", len(lines), writes)
+		}
+		if data, _ := os.ReadFile(path + ".20200101-000000.000"); len(data) != 0 {
+			t.Errorf("uncompressed backup still contains %q", data)
+		}
+	})
+
+	t.Run("age rotation", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "commands.jsonl")
+		logger, err := bot.NewCommandLogger(path, bot.LogOptions{RotateAfter: 50 * time.Millisecond})
+		if err != nil {
+			t.Fatal(err)
+		}
+		logger.LogCommand(testGroupID, testUserID, "@user", "warn", "alt", time.Millisecond, nil)
+		logger.LogCommand(testGroupID, testUserID, "@user", "warn", "auch alt", time.Millisecond, nil)
+		time.Sleep(60 * time.Millisecond)
+		logger.LogCommand(testGroupID, testUserID, "@user", "warn", "neu", time.Millisecond, nil)
+		if err := logger.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		backups := readBackups(t, path)
+		if len(backups) != 1 || len(backups[0]) != 2 {
+			t.Fatalf("backups = %q, want one backup with both old entries", backups)
+		}
+		if current := readLogLines(t, path, 1); len(current) != 1 || !strings.Contains(current[0], `"args":"neu"`) {
+			t.Errorf("current log = %q, want only the new entry", current)
+		}
+	})
+}
+
+func TestMessageLoggingPrivacy(t *testing.T) {
+	env := newTestEnv(t)
+	db := env.bot.GetDB()
+
+	// Als gemuteter User wird jede Nachricht gelöscht; deleteMessage zeigt, dass sie geloggt wurde
+	if err := db.AddMutedUser(database.MutedUser{UserID: testUserID, ChatID: testGroupID, Until: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, mode := range []string{"", config.MessageLogFull, config.MessageLogHashed, config.MessageLogOff} {
+		if mode != "" {
+			if err := db.SetGroupSetting(testGroupID, "message_logging", mode); err != nil {
+				t.Fatal(err)
+			}
+		}
+		env.server.PushUpdate(groupMessage(testUser, "geheim"))
+		env.waitFor(t, "deleteMessage", i+1)
+	}
+
+	lines := readLogLines(t, "events.log", 3)
+	if len(lines) != 3 {
+		t.Fatalf("events.log has %d lines, want 3 (off must not log):\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+
+	var entries []bot.LogEntry
+	for _, line := range lines {
+		var entry bot.LogEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if e := entries[0]; e.Text != "" || e.TextHash != "" || e.Length != 6 || e.MessageID == 0 {
+		t.Errorf("metadata entry = %+v, want only length and message id", e)
+	}
+	if e := entries[1]; e.Text != "geheim" {
+		t.Errorf("full entry text = %q, want geheim", e.Text)
+	}
+	if e := entries[2]; e.Text != "" || len(e.TextHash) != 64 {
+		t.Errorf("hashed entry = %+v, want hex HMAC without text", e)
+	}
+
+	t.Run("retention", func(t *testing.T) {
+		if err := db.SetGroupSetting(testGroupID, "message_retention_days", "1"); err != nil {
+			t.Fatal(err)
+		}
+		if removed, err := env.bot.PurgeExpiredMessages(time.Now()); err != nil || removed != 0 {
+			t.Fatalf("purge of fresh messages removed %d (err=%v), want 0", removed, err)
+		}
+		if removed, err := env.bot.PurgeExpiredMessages(time.Now().Add(48 * time.Hour)); err != nil || removed != 3 {
+			t.Fatalf("purge after two days removed %d (err=%v), want 3", removed, err)
+		}
+	})
+
+	t.Run("forgetme", func(t *testing.T) {
+		if _, err := db.AddWarning(database.Warning{ChatID: testGroupID, UserID: testUserID, AdminID: testAdminID}); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.RecordRulesAcceptance(testGroupID, testUserID, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		env.bot.GetEventLogger().LogJoin(testGroupID, testUserID, "@user")
+		env.bot.GetEventLogger().LogBan(testGroupID, testUserID, "@user", "Spam")
+
+		env.server.PushUpdate(privateMessage(testUser, "/forgetme confirm"))
+		calls := env.waitFor(t, "sendMessage", 1)
+		if text := calls[0].Params.Get("text"); !strings.Contains(text, "gelöscht") {
+			t.Errorf("reply = %q, want confirmation", text)
+		}
+
+		// Nur Sanktions-Events bleiben im Log, wie die Sanktionen in der Datenbank
+		for _, line := range readLogLines(t, "events.log", 1) {
+			var entry bot.LogEntry
+			if err := json.Unmarshal([]byte(line), &entry); err == nil && entry.UserID == testUserID && entry.Type != "USER_BANNED" {
+				t.Errorf("events.log still contains %s of the user", entry.Type)
+			}
+		}
+		if data, _ := os.ReadFile("events.log"); !strings.Contains(string(data), "USER_BANNED") {
+			t.Error("ban event was removed by /forgetme")
+		}
+		if accepted, _ := db.GetRulesAcceptance(testGroupID, testUserID); !accepted.IsZero() {
+			t.Errorf("rules acceptance after /forgetme = %v, want none", accepted)
+		}
+
+		// Sanktionen bleiben bestehen, sonst ließen sich Verwarnungen und Mutes per /forgetme zurücksetzen
+		if warnings, _ := db.GetWarnings(testGroupID, testUserID, time.Time{}); len(warnings) != 1 {
+			t.Errorf("warnings after /forgetme = %d, want 1", len(warnings))
+		}
+		if muted, _ := db.IsUserMuted(testUserID, testGroupID); !muted {
+			t.Error("mute was removed by /forgetme")
+		}
+	})
+}
+
+func TestGroupConfigCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    tgbotapi.User
+		command string
+		want    int
+	}{
+		{"group admin sets own group", testAdmin, "/config -100123 max_attempts 7", 7},
+		{"member cannot change group", testUser, "/config -100123 max_attempts 7", 3},
+		{"invalid value is rejected", testAdmin, "/config -100123 max_attempts 99", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.server.PushUpdate(privateMessage(tt.from, tt.command))
+
+			env.waitFor(t, "sendMessage", 1)
+
+			if got := env.bot.GetChatConfig(testGroupID).Captcha.MaxAttempts; got != tt.want {
+				t.Errorf("max_attempts = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDurationSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    time.Duration
+	}{
+		{"bare number counts in hours", "/config -100123 default_mute_hours 3", 3 * time.Hour},
+		{"duration grammar", "/config -100123 default_mute_hours 90m", 90 * time.Minute},
+		{"out of range is rejected", "/config -100123 default_mute_hours 2w", time.Hour},
+		{"perm is rejected", "/config -100123 default_mute_hours perm", time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.server.PushUpdate(privateMessage(testAdmin, tt.command))
+
+			env.waitFor(t, "sendMessage", 1)
+
+			if got := env.bot.GetChatConfig(testGroupID).Admin.DefaultMute(); got != tt.want {
+				t.Errorf("default mute = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBotAdmins(t *testing.T) {
+	const legacyAdminID, moderatorID int64 = 77, 55
+	moderator := tgbotapi.User{ID: moderatorID, FirstName: "Mod"}
+
+	var cfg *config.Config
+	env := newTestEnv(t, func(c *config.Config) {
+		c.Admin.AdminUserIDs = []int64{testAdminID, legacyAdminID}
+		cfg = c
+	})
+	db := env.bot.GetDB()
+
+	// Einmalige Übernahme aus admin_user_ids: der erste Eintrag wird Owner
+	if role := env.bot.BotRole(testAdminID); role != database.RoleOwner {
+		t.Fatalf("imported role of first admin = %q, want owner", role)
+	}
+	if role := env.bot.BotRole(legacyAdminID); role != database.RoleAdmin {
+		t.Fatalf("imported role of second admin = %q, want admin", role)
+	}
+
+	steps := []struct {
+		name     string
+		from     tgbotapi.User
+		command  string
+		wantText string
+	}{
+		{"owner grants moderator", testAdmin, "/add_admin 55 moderator", "Bot-moderator"},
+		{"moderator cannot grant", moderator, "/add_admin 99", "keine Berechtigung"},
+		{"unknown role", testAdmin, "/add_admin 99 superuser", "Unbekannte Rolle"},
+		{"owner revokes legacy admin", testAdmin, "/del_admin 77", "entfernt"},
+		{"last owner stays", testAdmin, "/del_admin 11", "letzte Owner"},
+		{"global config is stored in the database", testAdmin, "/config max_attempts 5", "max_attempts = 5"},
+	}
+
+	for i, step := range steps {
+		env.server.PushUpdate(privateMessage(step.from, step.command))
+		messages := env.waitFor(t, "sendMessage", i+1)
+		if text := messages[i].Params.Get("text"); !strings.Contains(text, step.wantText) {
+			t.Errorf("%s: reply = %q, want it to contain %q", step.name, text, step.wantText)
+		}
+	}
+
+	if role := env.bot.BotRole(moderatorID); role != database.RoleModerator {
+		t.Errorf("role of granted user = %q, want moderator", role)
+	}
+	if role := env.bot.BotRole(legacyAdminID); role != "" {
+		t.Errorf("role of revoked user = %q, want none", role)
+	}
+	if got := env.bot.GetConfig().Captcha.MaxAttempts; got != 5 {
+		t.Errorf("global max_attempts = %d, want 5", got)
+	}
+
+	audit, err := db.GetAdminAudit(10)
+	if err != nil {
+		t.Fatalf("GetAdminAudit: %v", err)
+	}
+	if len(audit) != 4 || audit[0].Action != database.AuditRevoke || audit[0].ActorID != testAdminID {
+		t.Errorf("audit trail = %+v, want 2 imports, 1 grant and the revoke last", audit)
+	}
+
+	// Ein Neustart übernimmt admin_user_ids nicht erneut, globale Werte bleiben erhalten
+	restarted, err := bot.NewBot(cfg)
+	if err != nil {
+		t.Fatalf("NewBot: %v", err)
+	}
+	defer restarted.Stop()
+	if role := restarted.BotRole(legacyAdminID); role != "" {
+		t.Errorf("revoked admin was imported again with role %q", role)
+	}
+	if got := restarted.GetConfig().Captcha.MaxAttempts; got != 5 {
+		t.Errorf("max_attempts after restart = %d, want 5", got)
+	}
+}
+
+func TestRolePermissions(t *testing.T) {
+	const helperID, moderatorID int64 = 61, 55
+	helper := tgbotapi.User{ID: helperID, FirstName: "Helper"}
+	moderator := tgbotapi.User{ID: moderatorID, FirstName: "Mod"}
+
+	env := newTestEnv(t, func(c *config.Config) {
+		c.Admin.AdminUserIDs = []int64{testAdminID}
+	})
+	if err := env.bot.GrantBotRole(moderatorID, database.RoleModerator, testAdminID, "test"); err != nil {
+		t.Fatalf("GrantBotRole: %v", err)
+	}
+	// Gruppen-Admin, der bei Telegram nur Nachrichten löschen darf
+	env.server.SetAdminRights(testGroupID, helperID, telegramtest.AdminRights{CanDeleteMessages: true})
+
+	steps := []struct {
+		name     string
+		update   tgbotapi.Update
+		wantText string
+	}{
+		{"telegram rights do not cover kick", groupMessage(helper, "/kick 42"), "keine Berechtigung"},
+		{"moderator cannot change role permissions", privateMessage(moderator, "/roleperms global"), "keine Berechtigung"},
+		{"owner permissions are fixed", privateMessage(testAdmin, "/roleperms global owner none"), "immer alle Rechte"},
+		{"unknown permission", privateMessage(testAdmin, "/roleperms global moderator fly"), "Unbekanntes Recht"},
+		{"owner grants kick to tg_delete in the group", privateMessage(testAdmin, "/roleperms -100123 tg_delete delete,kick"), "delete, kick"},
+	}
+
+	for i, step := range steps {
+		env.server.PushUpdate(step.update)
+		messages := env.waitFor(t, "sendMessage", i+1)
+		if text := messages[i].Params.Get("text"); !strings.Contains(text, step.wantText) {
+			t.Errorf("%s: reply = %q, want it to contain %q", step.name, text, step.wantText)
+		}
+	}
+	if calls := env.server.Calls("banChatMember"); len(calls) != 0 {
+		t.Fatalf("kick without permission was executed: %d banChatMember calls", len(calls))
+	}
+
+	// Mit dem Recht der Gruppe darf der Helfer jetzt kicken
+	env.server.PushUpdate(groupMessage(helper, "/kick 42"))
+	env.waitFor(t, "banChatMember", 1)
+
+	checks := []struct {
+		name       string
+		setup      func()
+		chatID     int64
+		userID     int64
+		permission string
+		want       bool
+	}{
+		{"group override applies", nil, testGroupID, helperID, bot.PermKick, true},
+		{"telegram roles only apply in groups", nil, 0, helperID, bot.PermDelete, false},
+		{"moderator default", nil, testGroupID, moderatorID, bot.PermBan, true},
+		{"moderator has no config by default", nil, 0, moderatorID, bot.PermConfig, false},
+		{"global setting replaces the default", func() {
+			env.bot.SetRolePermissions(0, database.RoleModerator, []string{bot.PermWarn})
+		}, testGroupID, moderatorID, bot.PermBan, false},
+		{"group setting wins over global", func() {
+			env.bot.SetRolePermissions(testGroupID, database.RoleModerator, []string{bot.PermBan})
+		}, testGroupID, moderatorID, bot.PermBan, true},
+		{"reset restores the default", func() {
+			env.bot.ResetRolePermissions(testGroupID, bot.TelegramDelete)
+		}, testGroupID, helperID, bot.PermKick, false},
+		{"owner keeps everything", nil, testGroupID, testAdminID, bot.PermManageAdmins, true},
+	}
+
+	for _, check := range checks {
+		if check.setup != nil {
+			check.setup()
+		}
+		if got := env.bot.HasPermission(check.chatID, check.userID, check.permission); got != check.want {
+			t.Errorf("%s: HasPermission(%d, %d, %s) = %v, want %v", check.name, check.chatID, check.userID, check.permission, got, check.want)
+		}
+	}
+}
+
+func TestGroupAdminScope(t *testing.T) {
+	const otherGroupID, syncedAdminID int64 = -100999, 88
+
+	var cfg *config.Config
+	env := newTestEnv(t, func(c *config.Config) { cfg = c })
+	db := env.bot.GetDB()
+
+	// Eine Admin-Aktion macht den Gruppen-Admin nicht mehr zum globalen Bot-Admin
+	env.server.PushUpdate(groupMessage(testAdmin, "/ban 42 Spam"))
+	env.waitFor(t, "banChatMember", 1)
+	if role := env.bot.BotRole(testAdminID); role != "" {
+		t.Fatalf("group admin got global role %q", role)
+	}
+
+	env.server.PushUpdate(privateMessage(testAdmin, "/config max_attempts 5"))
+	messages := env.waitFor(t, "sendMessage", 2)
+	if text := messages[1].Params.Get("text"); !strings.Contains(text, "keine Berechtigung") {
+		t.Errorf("global /config by group admin: reply = %q, want no permission", text)
+	}
+
+	if stored, err := db.GetGroupAdmin(testGroupID, testAdminID); err != nil || stored == nil {
+		t.Fatalf("group admin not stored for the group (admin=%+v, err=%v)", stored, err)
+	}
+	if !env.bot.HasPermission(testGroupID, testAdminID, bot.PermBan) {
+		t.Error("group admin lost ban permission in own group")
+	}
+	if env.bot.HasPermission(otherGroupID, testAdminID, bot.PermBan) {
+		t.Error("group admin has ban permission in another group")
+	}
+
+	// Aus admin_user_ids übernommene Admins sind gewollt, der Abgleich lässt ihre Rolle unverändert
+	if err := db.SetBotAdmin(database.BotAdmin{UserID: testAdminID, Role: database.RoleAdmin, GrantedAt: time.Now()}, "config admin_user_ids"); err != nil {
+		t.Fatalf("SetBotAdmin: %v", err)
+	}
+	env.bot.ReconcileGroupAdmins()
+	if role := env.bot.BotRole(testAdminID); role != database.RoleAdmin {
+		t.Errorf("group admin from admin_user_ids = %q after reconcile, want admin", role)
+	}
+	if _, err := env.bot.RevokeBotRole(testAdminID, 0, "test"); err != nil {
+		t.Fatalf("RevokeBotRole: %v", err)
+	}
+
+	// Der Abgleich entfernt die Rechte, sobald Telegram den User nicht mehr als Admin führt
+	env.server.SetMemberStatus(testGroupID, testAdminID, "member")
+	env.bot.ReconcileGroupAdmins()
+	if stored, err := db.GetGroupAdmin(testGroupID, testAdminID); err != nil || stored != nil {
+		t.Errorf("demoted admin still stored (admin=%+v, err=%v)", stored, err)
+	}
+	if env.bot.HasPermission(testGroupID, testAdminID, bot.PermBan) {
+		t.Error("demoted admin still has ban permission")
+	}
+
+	// Frühere Versionen haben Gruppen-Admins global befördert: der erste Start mit einer alten
+	// Datenbank nimmt das einmalig zurück und schreibt dem Owner, wen es betrifft
+	const ownerID int64 = 70
+	legacyCfg := *cfg
+	legacyCfg.Database.FilePath = filepath.Join(t.TempDir(), "legacy.db")
+	legacyDB, err := database.NewDB(legacyCfg.Database.FilePath)
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	for userID, note := range map[int64]string{ownerID: "claim token", syncedAdminID: "sync: group admin"} {
+		role := database.RoleAdmin
+		if userID == ownerID {
+			role = database.RoleOwner
+		}
+		if err := legacyDB.SetBotAdmin(database.BotAdmin{UserID: userID, Role: role, GrantedAt: time.Now()}, note); err != nil {
+			t.Fatalf("SetBotAdmin: %v", err)
+		}
+	}
+	legacyDB.Close()
+
+	restarted, err := bot.NewBot(&legacyCfg)
+	if err != nil {
+		t.Fatalf("NewBot: %v", err)
+	}
+	go restarted.Start()
+	if role := restarted.BotRole(syncedAdminID); role != "" {
+		t.Errorf("synced group admin kept global role %q after restart", role)
+	}
+	notified := false
+	for deadline := time.Now().Add(waitTimeout); !notified && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		for _, call := range env.server.Calls("sendMessage") {
+			if call.Int64("chat_id") == ownerID && strings.Contains(call.Params.Get("text"), strconv.FormatInt(syncedAdminID, 10)) {
+				notified = true
+			}
+		}
+	}
+	if !notified {
+		t.Error("owner was not told which synced admins lost their role")
+	}
+
+	// Die Bereinigung läuft nur einmal, eine neue Vergabe bleibt bei weiteren Starts bestehen
+	if err := restarted.GetDB().SetBotAdmin(database.BotAdmin{UserID: syncedAdminID, Role: database.RoleAdmin, GrantedAt: time.Now()}, "sync: group admin"); err != nil {
+		t.Fatalf("SetBotAdmin: %v", err)
+	}
+	restarted.Stop()
+	again, err := bot.NewBot(&legacyCfg)
+	if err != nil {
+		t.Fatalf("NewBot: %v", err)
+	}
+	defer again.Stop()
+	if role := again.BotRole(syncedAdminID); role != database.RoleAdmin {
+		t.Errorf("second start revoked the role again (role %q)", role)
+	}
+}
+
+func TestClaimToken(t *testing.T) {
+	env := newTestEnv(t)
+
+	// Ohne Admins wird niemand mehr durch die erste DM Owner
+	env.server.PushUpdate(privateMessage(testUser, "Hallo"))
+	messages := env.waitFor(t, "sendMessage", 1)
+	if text := messages[0].Params.Get("text"); !strings.Contains(text, "/claim") {
+		t.Fatalf("first DM: reply = %q, want the claim hint", text)
+	}
+	if role := env.bot.BotRole(testUserID); role != "" {
+		t.Fatalf("first DM made user %q", role)
+	}
+
+	token, err := env.bot.IssueClaimToken()
+	if err != nil {
+		t.Fatalf("IssueClaimToken: %v", err)
+	}
+	// Ein noch gültiges Token wird nicht ersetzt
+	if err := env.bot.RenewClaimToken(); err != nil {
+		t.Fatalf("RenewClaimToken: %v", err)
+	}
+
+	steps := []struct {
+		name     string
+		update   tgbotapi.Update
+		wantText string
+	}{
+		{"wrong token", privateMessage(testUser, "/claim 0123456789abcdef"), "Ungültiges Claim-Token"},
+		{"token in a group is not redeemed", groupMessage(testUser, "/claim "+token), "nur per DM"},
+		{"valid token", privateMessage(testUser, "/claim "+token), "jetzt Owner"},
+		{"token is single-use", privateMessage(testAdmin, "/claim "+token), "Ungültiges Claim-Token"},
+	}
+
+	for i, step := range steps {
+		env.server.PushUpdate(step.update)
+		messages := env.waitFor(t, "sendMessage", i+2)
+		if text := messages[i+1].Params.Get("text"); !strings.Contains(text, step.wantText) {
+			t.Errorf("%s: reply = %q, want it to contain %q", step.name, text, step.wantText)
+		}
+	}
+
+	if role := env.bot.BotRole(testUserID); role != database.RoleOwner {
+		t.Errorf("role after claim = %q, want owner", role)
+	}
+	if role := env.bot.BotRole(testAdminID); role != "" {
+		t.Errorf("second claim granted role %q", role)
+	}
+}
+
+func TestKnownChats(t *testing.T) {
+	const otherGroupID int64 = -100555
+	env := newTestEnv(t)
+	db := env.bot.GetDB()
+	botUser := &tgbotapi.User{ID: telegramtest.BotUserID, IsBot: true}
+
+	membership := func(chat tgbotapi.Chat, oldStatus string, newMember tgbotapi.ChatMember) tgbotapi.Update {
+		newMember.User = botUser
+		return tgbotapi.Update{MyChatMember: &tgbotapi.ChatMemberUpdated{
+			Chat:          chat,
+			From:          testAdmin,
+			Date:          int(time.Now().Unix()),
+			OldChatMember: tgbotapi.ChatMember{User: botUser, Status: oldStatus},
+			NewChatMember: newMember,
+		}}
+	}
+	reply := func(chatID int64, want string) telegramtest.Call {
+		t.Helper()
+		for deadline := time.Now().Add(waitTimeout); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			for _, call := range env.server.Calls("sendMessage") {
+				if call.Int64("chat_id") == chatID && strings.Contains(call.Params.Get("text"), want) {
+					return call
+				}
+			}
+		}
+		t.Fatalf("no message to %d containing %q", chatID, want)
+		return telegramtest.Call{}
+	}
+	answer := func(data string, from tgbotapi.User, count int) string {
+		t.Helper()
+		env.server.PushUpdate(tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+			ID:      data,
+			From:    &from,
+			Message: &tgbotapi.Message{MessageID: 1, Chat: &tgbotapi.Chat{ID: from.ID, Type: "private"}},
+			Data:    data,
+		}})
+		return env.waitFor(t, "answerCallbackQuery", count)[count-1].Params.Get("text")
+	}
+
+	// Beitritt und Verlassen kommen über my_chat_member, Titel und Typ auch aus normalen Updates
+	other := tgbotapi.Chat{ID: otherGroupID, Type: "supergroup", Title: "Andere Gruppe"}
+	env.server.PushUpdate(membership(other, "left", tgbotapi.ChatMember{Status: "member"}))
+	env.server.PushUpdate(membership(other, "member", tgbotapi.ChatMember{Status: "kicked"}))
+
+	group := tgbotapi.Chat{ID: testGroupID, Type: "supergroup", Title: "Testgruppe"}
+	env.server.PushUpdate(membership(group, "left", tgbotapi.ChatMember{Status: "administrator", CanDeleteMessages: true}))
+	ban := groupMessage(testAdmin, "/ban 42 Spam")
+	ban.Message.Chat.Title = "Testgruppe"
+	env.server.PushUpdate(ban)
+	env.waitFor(t, "banChatMember", 1)
+
+	chat, err := db.GetKnownChat(testGroupID)
+	if err != nil || chat == nil {
+		t.Fatalf("group not stored (chat=%+v, err=%v)", chat, err)
+	}
+	if chat.Title != "Testgruppe" || chat.Type != "supergroup" || chat.BotStatus != "administrator" || chat.JoinedAt.IsZero() || !chat.LeftAt.IsZero() {
+		t.Errorf("stored group = %+v", chat)
+	}
+	if !strings.Contains(chat.BotPermissions, `"CanDeleteMessages":true`) {
+		t.Errorf("bot permissions = %s, want CanDeleteMessages", chat.BotPermissions)
+	}
+
+	for deadline := time.Now().Add(waitTimeout); ; time.Sleep(10 * time.Millisecond) {
+		left, err := db.GetKnownChat(otherGroupID)
+		if err == nil && left != nil && !left.LeftAt.IsZero() {
+			if left.JoinedAt.IsZero() || left.BotStatus != "kicked" {
+				t.Errorf("left group = %+v", left)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("leaving group %d not stored (chat=%+v, err=%v)", otherGroupID, left, err)
+		}
+	}
+
+	// /groups zeigt nur Gruppen, in denen der Bot ist und der User etwas verwalten darf
+	env.server.PushUpdate(privateMessage(testAdmin, "/groups"))
+	list := reply(testAdminID, "Testgruppe")
+	if text := list.Params.Get("text"); strings.Contains(text, "Andere Gruppe") {
+		t.Errorf("/groups lists a left group: %q", text)
+	}
+	if markup := list.Params.Get("reply_markup"); !strings.Contains(markup, "groups:select:-100123") {
+		t.Errorf("reply_markup = %s, want select button", markup)
+	}
+
+	env.server.PushUpdate(privateMessage(testUser, "/groups"))
+	reply(testUserID, "Keine Gruppen")
+
+	// Ein zweites /groups fragt Telegram nicht erneut nach jedem Chat
+	lookups := len(env.server.Calls("getChatMember"))
+	if lookups == 0 {
+		t.Fatal("first /groups did not ask Telegram for the membership")
+	}
+	env.server.PushUpdate(privateMessage(testUser, "/groups"))
+	env.waitFor(t, "sendMessage", len(env.server.Calls("sendMessage"))+1)
+	if got := len(env.server.Calls("getChatMember")); got != lookups {
+		t.Errorf("second /groups made %d getChatMember calls, want 0", got-lookups)
+	}
+
+	// Die Auswahl setzt den Kontext für /config per DM
+	if text := answer("groups:select:-100123", testUser, 1); !strings.Contains(text, "keine Berechtigung") {
+		t.Errorf("select by non-admin: answer = %q, want no permission", text)
+	}
+	if text := answer("groups:select:-100123", testAdmin, 2); !strings.Contains(text, "Testgruppe ausgewählt") {
+		t.Errorf("select: answer = %q", text)
+	}
+	if selected := env.bot.SelectedChat(testAdminID); selected != testGroupID {
+		t.Fatalf("selected chat = %d, want %d", selected, testGroupID)
+	}
+
+	env.server.PushUpdate(privateMessage(testAdmin, "/config max_attempts 5"))
+	reply(testAdminID, "Gruppeneinstellung für -100123 aktualisiert")
+	if settings, err := db.GetGroupSettings(testGroupID); err != nil || settings["max_attempts"] != "5" {
+		t.Errorf("group settings = %v (err=%v), want max_attempts=5", settings, err)
+	}
+
+	// Die globale Konfiguration bleibt dem Gruppen-Admin verwehrt
+	env.server.PushUpdate(privateMessage(testAdmin, "/config global max_attempts 5"))
+	reply(testAdminID, "keine Berechtigung")
+
+	if text := answer("groups:select:0", testAdmin, 3); !strings.Contains(text, "Auswahl aufgehoben") {
+		t.Errorf("clear: answer = %q", text)
+	}
+	if selected := env.bot.SelectedChat(testAdminID); selected != 0 {
+		t.Errorf("selected chat after clearing = %d", selected)
+	}
+
+	// Callbacks aus Inline-Nachrichten haben keine Message und keinen Chat
+	env.server.PushUpdate(tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:              "inline",
+		From:            &testUser,
+		InlineMessageID: "inline",
+		Data:            "captcha_solve:-100123:1+1",
+	}})
+	env.waitFor(t, "answerCallbackQuery", 4)
+}
+
+func TestLocalization(t *testing.T) {
+	t.Run("group locale and template override", func(t *testing.T) {
+		env := newTestEnv(t)
+		if err := env.bot.GetDB().SetGroupSetting(testGroupID, "locale", "en"); err != nil {
+			t.Fatal(err)
+		}
+
+		env.server.PushUpdate(groupMessage(testAdmin, "/warn 42 Spam"))
+		calls := env.waitFor(t, "sendMessage", 1)
+		if text := calls[0].Params.Get("text"); !strings.Contains(text, "User warned (1)") {
+			t.Fatalf("warn reply = %q, want English text", text)
+		}
+
+		env.server.PushUpdate(privateMessage(testAdmin, "/template -100123 en warn.success ⚠️ {user} got warning #{count}"))
+		calls = env.waitFor(t, "sendMessage", 2)
+		if text := calls[1].Params.Get("text"); !strings.Contains(text, "warn.success") {
+			t.Fatalf("template reply = %q", text)
+		}
+
+		env.server.PushUpdate(groupMessage(testAdmin, "/warn 42 Spam"))
+		calls = env.waitFor(t, "sendMessage", 3)
+		if text := calls[2].Params.Get("text"); !strings.HasPrefix(text, "⚠️") || !strings.Contains(text, "got warning #2") {
+			t.Errorf("warn reply with template = %q", text)
+		}
+	})
+
+	t.Run("global templates need a bot admin", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.server.PushUpdate(privateMessage(testAdmin, "/template global de warn.success x"))
+		env.waitFor(t, "sendMessage", 1)
+
+		templates, err := env.bot.GetDB().ListMessageTemplates(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(templates) != 0 {
+			t.Errorf("group admin stored %d global templates", len(templates))
+		}
+	})
+
+	t.Run("DMs follow the user's language", func(t *testing.T) {
+		env := newTestEnv(t)
+		user := testUser
+		user.LanguageCode = "en-GB"
+		env.server.PushUpdate(privateMessage(user, "/forgetme"))
+
+		calls := env.waitFor(t, "sendMessage", 1)
+		if text := calls[0].Params.Get("text"); !strings.Contains(text, "Delete data") {
+			t.Errorf("forgetme reply = %q, want English text", text)
+		}
+	})
+}
+
+func TestWelcomeTemplate(t *testing.T) {
+	t.Run("placeholders are escaped per format", func(t *testing.T) {
+		env := newTestEnv(t)
+		chat := &tgbotapi.Chat{ID: testGroupID, Type: "supergroup", Title: "a_b <c>"}
+		user := &tgbotapi.User{ID: 7, FirstName: "x*y"}
+
+		tests := []struct {
+			template string
+			format   string
+			want     string
+		}{
+			{"Willkommen!", "plain", "Willkommen! x*y"},
+			{"*Hi* {mention} in {chat_title}", "markdown", `*Hi* [x\*y](tg://user?id=7) in a\_b <c\>`},
+			{"<b>Hi</b> {mention} in {chat_title}", "html", `<b>Hi</b> <a href="tg://user?id=7">x*y</a> in a_b &lt;c&gt;`},
+		}
+		for _, tt := range tests {
+			if got := env.bot.RenderWelcome(chat, user, tt.template, tt.format).Text; got != tt.want {
+				t.Errorf("RenderWelcome(%q, %s) = %q, want %q", tt.template, tt.format, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("setwelcome from reply is used after the captcha", func(t *testing.T) {
+		env := newTestEnv(t)
+
+		template := message(testGroupID, "supergroup", testUser, "Hallo {mention} in {chat_title}!\n[Regeln](buttonurl:{rules_link})")
+		template.Entities = []tgbotapi.MessageEntity{{Type: "bold", Offset: 0, Length: 5}}
+		update := groupMessage(testAdmin, "/setwelcome")
+		update.Message.Chat.Title = "A & B"
+		update.Message.ReplyToMessage = template
+		env.server.PushUpdate(update)
+
+		calls := env.waitFor(t, "sendMessage", 2)
+		preview := calls[0]
+		if got, want := preview.Params.Get("text"), `<b>Hallo</b> <a href="tg://user?id=11">Admin</a> in A &amp; B!`; got != want {
+			t.Errorf("preview = %q, want %q", got, want)
+		}
+		if mode := preview.Params.Get("parse_mode"); mode != "HTML" {
+			t.Errorf("parse_mode = %q, want HTML", mode)
+		}
+		if markup := preview.Params.Get("reply_markup"); !strings.Contains(markup, "https://t.me/test_bot?start=rules_-100123") {
+			t.Errorf("reply_markup = %s, want rules button", markup)
+		}
+		if format := env.bot.GetChatConfig(testGroupID).Captcha.WelcomeFormat; format != "html" {
+			t.Fatalf("welcome_format = %q, want html", format)
+		}
+
+		newbie := tgbotapi.User{ID: 43, FirstName: "<Neu>"}
+		join := message(testGroupID, "supergroup", newbie, "")
+		join.Chat.Title = "A & B"
+		join.NewChatMembers = []tgbotapi.User{newbie}
+		env.server.PushUpdate(tgbotapi.Update{Message: join})
+		env.waitFor(t, "sendMessage", 3)
+
+		pending, err := env.bot.GetDB().GetPendingUser(newbie.ID, testGroupID)
+		if err != nil {
+			t.Fatalf("pending user not stored: %v", err)
+		}
+		answer := groupMessage(newbie, captchaAnswer(t, pending, true))
+		answer.Message.Chat.Title = "A & B"
+		env.server.PushUpdate(answer)
+
+		calls = env.waitFor(t, "sendMessage", 5)
+		if got, want := calls[4].Params.Get("text"), `<b>Hallo</b> <a href="tg://user?id=43">&lt;Neu&gt;</a> in A &amp; B!`; got != want {
+			t.Errorf("welcome = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestRules(t *testing.T) {
+	env := newTestEnv(t)
+	if err := env.bot.GetDB().SetGroupSetting(testGroupID, "rules_acceptance", "on"); err != nil {
+		t.Fatal(err)
+	}
+
+	rules := message(testGroupID, "supergroup", testAdmin, "1. Kein Spam <bitte>")
+	rules.Entities = []tgbotapi.MessageEntity{{Type: "bold", Offset: 0, Length: 2}}
+	update := groupMessage(testAdmin, "/setrules")
+	update.Message.ReplyToMessage = rules
+	env.server.PushUpdate(update)
+	env.waitFor(t, "sendMessage", 1)
+
+	want := "<b>1.</b> Kein Spam &lt;bitte&gt;"
+	if got, err := env.bot.GetDB().GetGroupRules(testGroupID); err != nil || got != want {
+		t.Fatalf("stored rules = %q (err=%v), want %q", got, err, want)
+	}
+
+	env.server.PushUpdate(groupMessage(testUser, "/rules"))
+	calls := env.waitFor(t, "sendMessage", 2)
+	if text := calls[1].Params.Get("text"); !strings.HasSuffix(text, want) || calls[1].Params.Get("parse_mode") != "HTML" {
+		t.Errorf("/rules = %q (parse_mode %q)", text, calls[1].Params.Get("parse_mode"))
+	}
+
+	// Nach gelöstem Captcha bleibt der User eingeschränkt, bis er die Regeln akzeptiert
+	join := message(testGroupID, "supergroup", testUser, "")
+	join.NewChatMembers = []tgbotapi.User{testUser}
+	env.server.PushUpdate(tgbotapi.Update{Message: join})
+	env.waitFor(t, "sendMessage", 3)
+
+	pending, err := env.bot.GetDB().GetPendingUser(testUserID, testGroupID)
+	if err != nil {
+		t.Fatalf("pending user not stored: %v", err)
+	}
+	env.server.PushUpdate(groupMessage(testUser, captchaAnswer(t, pending, true)))
+
+	calls = env.waitFor(t, "sendMessage", 4)
+	prompt := calls[3]
+	if !strings.Contains(prompt.Params.Get("reply_markup"), "rules:42") || !strings.HasSuffix(prompt.Params.Get("text"), want) {
+		t.Fatalf("rules prompt = %q, markup %s", prompt.Params.Get("text"), prompt.Params.Get("reply_markup"))
+	}
+	if restricts := env.server.Calls("restrictChatMember"); len(restricts) != 1 {
+		t.Fatalf("restrictChatMember called %d times before accepting the rules, want 1", len(restricts))
+	}
+
+	env.server.PushUpdate(tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:      "cb-rules",
+		From:    &testUser,
+		Message: message(testGroupID, "supergroup", tgbotapi.User{ID: telegramtest.BotUserID, IsBot: true}, ""),
+		Data:    "rules:42",
+	}})
+	env.waitFor(t, "restrictChatMember", 2)
+	env.waitFor(t, "sendMessage", 6) // Erfolgsmeldung und Begrüßung
+
+	acceptedAt, err := env.bot.GetDB().GetRulesAcceptance(testGroupID, testUserID)
+	if err != nil || time.Since(acceptedAt) > time.Minute {
+		t.Fatalf("rules acceptance = %s (err=%v), want now", acceptedAt, err)
+	}
+	if _, err := env.bot.GetDB().GetPendingUser(testUserID, testGroupID); err == nil {
+		t.Error("user still pending after accepting the rules")
+	}
+
+	// Der Deep-Link aus {rules_link} zeigt die Regeln per DM
+	env.server.PushUpdate(privateMessage(testUser, "/start rules_-100123"))
+	calls = env.waitFor(t, "sendMessage", 7)
+	if text := calls[6].Params.Get("text"); !strings.Contains(text, want) || !strings.Contains(text, "akzeptiert") {
+		t.Errorf("rules via deep link = %q", text)
+	}
+}
+
+func TestWebhookMode(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	env := newTestEnv(t, func(cfg *config.Config) {
+		cfg.Mode = config.ModeWebhook
+		cfg.Webhook = config.WebhookConfig{
+			URL:         "https://bot.example.com/telegram",
+			ListenAddr:  addr,
+			Path:        "/telegram",
+			SecretToken: "s3cret",
+		}
+	})
+
+	registered := env.waitFor(t, "setWebhook", 1)
+	if got := registered[0].Params.Get("secret_token"); got != "s3cret" {
+		t.Errorf("secret_token = %q, want %q", got, "s3cret")
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+		want   int
+	}{
+		{"wrong secret is rejected", "wrong", http.StatusForbidden},
+		{"valid secret is dispatched", "s3cret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(groupMessage(testAdmin, "/ban 42"))
+			req, _ := http.NewRequest(http.MethodPost, "http://"+addr+"/telegram", bytes.NewReader(body))
+			req.Header.Set("X-Telegram-Bot-Api-Secret-Token", tt.secret)
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+
+	// Nur das Update mit gültigem Secret darf den Ban auslösen
+	env.waitFor(t, "banChatMember", 1)
+	if calls := env.server.Calls("banChatMember"); len(calls) != 1 {
+		t.Errorf("banChatMember called %d times, want 1", len(calls))
+	}
+
+	env.bot.Stop()
+	env.waitFor(t, "deleteWebhook", 1)
+}
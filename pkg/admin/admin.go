@@ -7,6 +7,8 @@ import (
 	"strings"
 	"telegramBot/pkg/bot"
 	"telegramBot/pkg/database"
+	"telegramBot/pkg/duration"
+	"telegramBot/pkg/i18n"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -33,6 +35,10 @@ func NewDeleteHandler() *DeleteHandler {
 	return &DeleteHandler{}
 }
 
+func (h *BanHandler) RequiredPermission(b *bot.Bot, update tgbotapi.Update) (int64, string) {
+	return groupPermission(update, bot.PermBan)
+}
+
 func (h *BanHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
 	return h.handleBan(b, update, false)
 }
@@ -42,139 +48,141 @@ func (h *BanHandler) handleBan(b *bot.Bot, update tgbotapi.Update, isTemporary b
 		return nil
 	}
 
-	// Auto-Sync Gruppen-Admins bei Admin-Actions
-	syncHandler := NewSyncAdminsHandler()
-	if err := syncHandler.SyncGroupAdminsToBot(b, update.Message.Chat.ID); err != nil {
-		log.Printf("Failed to sync group admins: %v", err)
-	}
+	tr := b.ChatLocalizer(update.Message.Chat.ID)
 
-	if !isUserAuthorized(b, update.Message.Chat.ID, update.Message.From.ID) {
-		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, "Du hast keine Berechtigung für diesen Befehl.", 5)
-		return nil
+	var targetUser *tgbotapi.User
+	var reason string
+	var banDuration time.Duration
+	var err error
+	if isTemporary {
+		targetUser, banDuration, reason, err = parseTempBanArgs(b, update.Message)
+	} else {
+		targetUser, reason, err = extractTargetUserAndReason(b, update.Message)
 	}
-
-	targetUser, reason, err := extractTargetUserAndReason(b, update.Message)
 	if err != nil {
-		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, ""+err.Error(), 5)
+		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, tr.Error(err), 5)
 		return nil
 	}
 
 	if targetUser.ID == update.Message.From.ID {
-		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, "Du kannst dich nicht selbst bannen.", 5)
+		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, tr.T("ban.self"), 5)
 		return nil
 	}
 
 	if targetUser.ID != 0 {
 		targetIsAdmin, err := b.IsUserAdmin(update.Message.Chat.ID, targetUser.ID)
 		if err != nil {
-			_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, "Fehler beim Überprüfen der User-Berechtigung.", 5)
+			_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, tr.T("common.check_target_failed"), 5)
 			return fmt.Errorf("failed to check target admin status: %w", err)
 		}
 
 		if targetIsAdmin {
-			_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, "Admins können nicht gebannt werden.", 5)
+			_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, tr.T("ban.target_admin"), 5)
 			return nil
 		}
 	}
 
-	if err := b.BanChatMember(update.Message.Chat.ID, targetUser.ID); err != nil {
-		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, "Fehler beim Bannen des Users. Überprüfe die Bot-Rechte.", 5)
-		return fmt.Errorf("failed to ban user: %w", err)
+	// /tban perm entspricht einem normalen Bann
+	var until time.Time
+	if isTemporary && banDuration != duration.Permanent {
+		until = time.Now().Add(banDuration)
+	}
+
+	if err := banUser(b, update.Message.Chat.ID, targetUser.ID, until, update.Message.From.ID, reason); err != nil {
+		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, tr.T("ban.failed"), 5)
+		return err
 	}
 
-	successMsg := fmt.Sprintf(
-		"User gebannt\n\n"+
-			"User: %s\n"+
-			"Admin: %s",
-		bot.FormatUserName(targetUser),
-		bot.GetUserMention(update.Message.From),
-	)
+	vars := i18n.Vars{
+		"user":  bot.FormatUserName(targetUser),
+		"admin": bot.GetUserMention(update.Message.From),
+	}
+	successMsg := tr.T("ban.success", vars)
+	if !until.IsZero() {
+		vars["until"] = tr.FormatTime(until)
+		successMsg = tr.T("tban.success", vars)
+	}
 
 	if reason != "" {
-		successMsg += fmt.Sprintf("\nGrund: %s", reason)
+		successMsg += tr.T("common.reason", i18n.Vars{"reason": reason})
 	}
 
 	_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, successMsg, 5)
 	return nil
 }
 
+func (h *KickHandler) RequiredPermission(b *bot.Bot, update tgbotapi.Update) (int64, string) {
+	return groupPermission(update, bot.PermKick)
+}
+
 func (h *KickHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
 	if update.Message.Chat.Type == "private" {
 		return nil
 	}
 
-	if !isUserAuthorized(b, update.Message.Chat.ID, update.Message.From.ID) {
-		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, "Du hast keine Berechtigung für diesen Befehl.", 5)
-		return nil
-	}
+	tr := b.ChatLocalizer(update.Message.Chat.ID)
 
 	targetUser, reason, err := extractTargetUserAndReason(b, update.Message)
 	if err != nil {
-		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, ""+err.Error(), 5)
+		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, tr.Error(err), 5)
 		return nil
 	}
 
 	if targetUser.ID == update.Message.From.ID {
-		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, "Du kannst dich nicht selbst kicken.", 5)
+		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, tr.T("kick.self"), 5)
 		return nil
 	}
 
 	if targetUser.ID != 0 {
 		targetIsAdmin, err := b.IsUserAdmin(update.Message.Chat.ID, targetUser.ID)
 		if err != nil {
-			_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, "Fehler beim Überprüfen der User-Berechtigung.", 5)
+			_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, tr.T("common.check_target_failed"), 5)
 			return fmt.Errorf("failed to check target admin status: %w", err)
 		}
 
 		if targetIsAdmin {
-			_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, "Admins können nicht gekickt werden.", 5)
+			_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, tr.T("kick.target_admin"), 5)
 			return nil
 		}
 	}
 
-	if err := b.KickChatMember(update.Message.Chat.ID, targetUser.ID); err != nil {
-		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, "Fehler beim Kicken des Users. Überprüfe die Bot-Rechte.", 5)
-		return fmt.Errorf("failed to kick user: %w", err)
+	if err := kickUser(b, update.Message.Chat.ID, targetUser.ID, update.Message.From.ID, reason); err != nil {
+		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, tr.T("kick.failed"), 5)
+		return err
 	}
 
-	if err := b.UnbanChatMember(update.Message.Chat.ID, targetUser.ID); err != nil {
-		return fmt.Errorf("failed to unban user after kick: %w", err)
-	}
-
-	successMsg := fmt.Sprintf(
-		"User gekickt\n\n"+
-			"User: %s\n"+
-			"Admin: %s",
-		bot.FormatUserName(targetUser),
-		bot.GetUserMention(update.Message.From),
-	)
+	successMsg := tr.T("kick.success", i18n.Vars{
+		"user":  bot.FormatUserName(targetUser),
+		"admin": bot.GetUserMention(update.Message.From),
+	})
 
 	if reason != "" {
-		successMsg += fmt.Sprintf("\nGrund: %s", reason)
+		successMsg += tr.T("common.reason", i18n.Vars{"reason": reason})
 	}
 
 	_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, successMsg, 5)
 	return nil
 }
 
+func (h *MuteHandler) RequiredPermission(b *bot.Bot, update tgbotapi.Update) (int64, string) {
+	return groupPermission(update, bot.PermMute)
+}
+
 func (h *MuteHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
 	if update.Message.Chat.Type == "private" {
 		return nil
 	}
 
-	if !isUserAuthorized(b, update.Message.Chat.ID, update.Message.From.ID) {
-		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, "Du hast keine Berechtigung für diesen Befehl.", 5)
-		return nil
-	}
+	tr := b.ChatLocalizer(update.Message.Chat.ID)
 
-	targetUser, duration, reason, err := h.parseTargetUserDurationAndReason(b, update.Message)
+	targetUser, muteDuration, reason, err := h.parseTargetUserDurationAndReason(b, update.Message)
 	if err != nil {
-		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, ""+err.Error(), 5)
+		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, tr.Error(err), 5)
 		return nil
 	}
 
 	if targetUser.ID == update.Message.From.ID {
-		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, "Du kannst dich nicht selbst muten.", 5)
+		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, tr.T("mute.self"), 5)
 		return nil
 	}
 
@@ -184,16 +192,53 @@ func (h *MuteHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
 	}
 
 	if targetIsAdmin {
-		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, "Admins können nicht gemutet werden.", 5)
+		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, tr.T("mute.target_admin"), 5)
 		return nil
 	}
 
-	muteUntil := time.Now().Add(time.Duration(duration) * time.Hour)
+	// Bei permanentem Mute bleibt muteUntil leer
+	var muteUntil time.Time
+	until := tr.T("common.permanent")
+	if muteDuration != duration.Permanent {
+		muteUntil = time.Now().Add(muteDuration)
+		until = tr.FormatTime(muteUntil)
+	}
+
+	if err := muteUser(b, update.Message.Chat.ID, targetUser.ID, muteUntil, update.Message.From.ID, reason); err != nil {
+		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, tr.T("mute.failed"), 5)
+		return err
+	}
+
+	successMsg := tr.T("mute.success", i18n.Vars{
+		"user":     bot.FormatUserName(targetUser),
+		"duration": duration.Format(muteDuration),
+		"until":    until,
+		"admin":    bot.GetUserMention(update.Message.From),
+	})
+
+	if reason != "" {
+		successMsg += tr.T("common.reason", i18n.Vars{"reason": reason})
+	}
+
+	_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, successMsg, 5)
+	return nil
+}
+
+// permanentMuteUntil wird für Mutes ohne Ablauf gespeichert, damit IsUserMuted sie als aktiv erkennt
+var permanentMuteUntil = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// muteUser schränkt einen User bis until ein, speichert das Mute und plant das Auto-Unmute.
+// Ein leeres until mutet permanent. adminID 0 steht für automatische Aktionen des Bots.
+func muteUser(b *bot.Bot, chatID, userID int64, until time.Time, adminID int64, reason string) error {
+	stored := until
+	if until.IsZero() {
+		stored = permanentMuteUntil
+	}
 
 	mutedUser := database.MutedUser{
-		UserID: targetUser.ID,
-		ChatID: update.Message.Chat.ID,
-		Until:  muteUntil,
+		UserID: userID,
+		ChatID: chatID,
+		Until:  stored,
 	}
 
 	if err := b.GetDB().AddMutedUser(mutedUser); err != nil {
@@ -211,122 +256,191 @@ func (h *MuteHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
 		CanPinMessages:        false,
 	}
 
-	if err := b.RestrictChatMember(update.Message.Chat.ID, targetUser.ID, permissions); err != nil {
-		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, "Fehler beim Muten des Users.", 5)
+	// Die bisherigen Rechte werden gemerkt und beim Unmute wiederhergestellt
+	if err := b.RestrictMember(chatID, userID, permissions); err != nil {
 		return fmt.Errorf("failed to mute user: %w", err)
 	}
 
-	successMsg := fmt.Sprintf(
-		"User gemutet\n\n"+
-			"User: %s\n"+
-			"Dauer: %d Stunden\n"+
-			"Bis: %s\n"+
-			"Admin: %s",
-		bot.FormatUserName(targetUser),
-		duration,
-		muteUntil.Format("02.01.2006 15:04"),
-		bot.GetUserMention(update.Message.From),
-	)
+	// Ein neues Mute ersetzt ein noch laufendes
+	revokeActions(b, chatID, userID, database.ActionMute, adminID)
+	recordAction(b, chatID, userID, adminID, database.ActionMute, reason, until)
 
-	if reason != "" {
-		successMsg += fmt.Sprintf("\nGrund: %s", reason)
+	// Auto-Unmute einplanen (ein früherer Unmute-Job wird durch den neuen ersetzt)
+	b.CancelJobs(bot.JobUnmute, chatID, userID)
+	if until.IsZero() {
+		return nil
 	}
 
-	_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, successMsg, 5)
+	job := database.Job{
+		Kind:   bot.JobUnmute,
+		ChatID: chatID,
+		UserID: userID,
+	}
+	if err := b.ScheduleJob(job, time.Until(until)); err != nil {
+		return fmt.Errorf("failed to schedule unmute: %w", err)
+	}
+
+	return nil
+}
+
+// banUser bannt einen User und speichert den Bann. Ein leeres until bannt permanent,
+// sonst hebt Telegram den Bann selbst auf und ein Unban-Job räumt den Eintrag auf.
+func banUser(b *bot.Bot, chatID, userID int64, until time.Time, adminID int64, reason string) error {
+	var err error
+	if until.IsZero() {
+		err = b.BanChatMember(chatID, userID)
+	} else {
+		err = b.BanChatMemberUntil(chatID, userID, until)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to ban user: %w", err)
+	}
+
+	banned := database.BannedUser{
+		UserID:    userID,
+		ChatID:    chatID,
+		AdminID:   adminID,
+		Reason:    reason,
+		Until:     until,
+		CreatedAt: time.Now(),
+	}
+	if err := b.GetDB().AddBannedUser(banned); err != nil {
+		return fmt.Errorf("failed to add banned user to database: %w", err)
+	}
+
+	revokeActions(b, chatID, userID, database.ActionBan, adminID)
+	recordAction(b, chatID, userID, adminID, database.ActionBan, reason, until)
+
+	// Ein neuer Bann ersetzt einen noch geplanten Unban
+	b.CancelJobs(bot.JobUnban, chatID, userID)
+	if until.IsZero() {
+		return nil
+	}
+
+	job := database.Job{
+		Kind:   bot.JobUnban,
+		ChatID: chatID,
+		UserID: userID,
+	}
+	if err := b.ScheduleJob(job, time.Until(until)); err != nil {
+		return fmt.Errorf("failed to schedule unban: %w", err)
+	}
+
+	return nil
+}
 
-	go func() {
-		time.Sleep(time.Duration(duration) * time.Hour)
-		h.unmuteUser(b, update.Message.Chat.ID, targetUser.ID)
-	}()
+// kickUser entfernt einen User aus der Gruppe, ohne ihn dauerhaft zu bannen
+func kickUser(b *bot.Bot, chatID, userID, adminID int64, reason string) error {
+	if err := b.KickChatMember(chatID, userID); err != nil {
+		return fmt.Errorf("failed to kick user: %w", err)
+	}
 
+	if err := b.UnbanChatMember(chatID, userID); err != nil {
+		return fmt.Errorf("failed to unban user after kick: %w", err)
+	}
+
+	recordAction(b, chatID, userID, adminID, database.ActionKick, reason, time.Time{})
 	return nil
 }
 
-func (h *MuteHandler) parseTargetUserDurationAndReason(b *bot.Bot, message *tgbotapi.Message) (*tgbotapi.User, int, string, error) {
+// recordAction schreibt eine Aktion ins Moderationsprotokoll. Fehler werden nur geloggt,
+// die Aktion selbst ist zu diesem Zeitpunkt bereits ausgeführt.
+func recordAction(b *bot.Bot, chatID, userID, adminID int64, action, reason string, expiresAt time.Time) {
+	entry := database.ModerationAction{
+		ChatID:    chatID,
+		UserID:    userID,
+		AdminID:   adminID,
+		Action:    action,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+	if _, err := b.GetDB().AddModerationAction(entry); err != nil {
+		log.Printf("Failed to record %s of user %d in chat %d: %v", action, userID, chatID, err)
+	}
+}
+
+// revokeActions markiert offene Aktionen eines Typs als aufgehoben
+func revokeActions(b *bot.Bot, chatID, userID int64, action string, revokedBy int64) {
+	if err := b.GetDB().RevokeModerationActions(chatID, userID, action, revokedBy, time.Now()); err != nil {
+		log.Printf("Failed to revoke %s of user %d in chat %d: %v", action, userID, chatID, err)
+	}
+}
+
+func (h *MuteHandler) parseTargetUserDurationAndReason(b *bot.Bot, message *tgbotapi.Message) (*tgbotapi.User, time.Duration, string, error) {
 	args := strings.Fields(message.CommandArguments())
 	var targetUser *tgbotapi.User
-	var duration int
-	var reason string
 	var err error
 
 	if message.ReplyToMessage != nil && message.ReplyToMessage.From != nil {
-		// Reply-to-Message: /mute [Stunden] [Grund]
+		// Reply-to-Message: /mute [Dauer] [Grund]
 		targetUser = message.ReplyToMessage.From
-
-		if len(args) > 0 {
-			// Versuche ersten Arg als Dauer zu parsen
-			duration, err = bot.ParseDuration(args[0])
-			if err != nil {
-				// Wenn nicht parsebar, ist es Teil des Grunds
-				duration = b.GetConfig().Admin.DefaultMuteHours
-				reason = strings.Join(args, " ")
-			} else {
-				// Dauer erfolgreich geparst, Rest ist Grund
-				if len(args) > 1 {
-					reason = strings.Join(args[1:], " ")
-				}
-			}
-		} else {
-			duration = b.GetConfig().Admin.DefaultMuteHours
-		}
 	} else {
-		// Normal: /mute @user [Stunden] [Grund]
+		// Normal: /mute @user [Dauer] [Grund]
 		if len(args) < 1 {
-			return nil, 0, "", fmt.Errorf("Verwendung: /mute @username [Stunden] [Grund] oder als Antwort auf eine Nachricht")
+			return nil, 0, "", i18n.NewError("mute.usage")
 		}
 
 		targetUser, err = parseUserFromArgs(b, message.Chat.ID, args[0])
 		if err != nil {
 			return nil, 0, "", err
 		}
+		args = args[1:]
+	}
 
-		if len(args) > 1 {
-			// Versuche zweiten Arg als Dauer zu parsen
-			duration, err = bot.ParseDuration(args[1])
-			if err != nil {
-				// Wenn nicht parsebar, ist alles ab Arg 1 der Grund
-				duration = b.GetConfig().Admin.DefaultMuteHours
-				reason = strings.Join(args[1:], " ")
-			} else {
-				// Dauer erfolgreich geparst, Rest ist Grund
-				if len(args) > 2 {
-					reason = strings.Join(args[2:], " ")
-				}
-			}
-		} else {
-			duration = b.GetConfig().Admin.DefaultMuteHours
+	admin := b.GetChatConfig(message.Chat.ID).Admin
+	muteDuration := admin.ClampMute(admin.DefaultMute())
+
+	// Das erste Argument ist die Dauer, wenn es wie eine aussieht; sonst beginnt dort der Grund
+	if len(args) > 0 && looksLikeDuration(args[0]) {
+		min, max := admin.MuteLimits()
+		muteDuration, err = bot.ParseDuration(args[0], min, max)
+		if err != nil {
+			return nil, 0, "", err
 		}
+		args = args[1:]
 	}
 
 	if targetUser.ID == 0 {
-		return nil, 0, "", fmt.Errorf("User-ID ist 0 in Reply-Nachricht")
+		return nil, 0, "", i18n.NewError("user.reply_id_zero")
 	}
 	if targetUser.IsBot {
-		return nil, 0, "", fmt.Errorf("Kann keine Aktionen auf Bots ausführen")
+		return nil, 0, "", i18n.NewError("user.is_bot")
 	}
 
-	return targetUser, duration, reason, nil
+	return targetUser, muteDuration, strings.Join(args, " "), nil
 }
 
-func (h *MuteHandler) unmuteUser(b *bot.Bot, chatID, userID int64) {
-	isMuted, err := b.GetDB().IsUserMuted(userID, chatID)
-	if err != nil || !isMuted {
-		return
+// looksLikeDuration erkennt Argumente, die als Dauer gemeint sind ("2", "30m", "perm"),
+// damit Tippfehler wie "2x" als Fehler gemeldet und nicht zum Grund werden
+func looksLikeDuration(arg string) bool {
+	switch strings.ToLower(arg) {
+	case "perm", "permanent", "forever":
+		return true
 	}
+	return arg != "" && arg[0] >= '0' && arg[0] <= '9'
+}
 
-	permissions := tgbotapi.ChatPermissions{
-		CanSendMessages:       true,
-		CanSendMediaMessages:  true,
-		CanSendPolls:          true,
-		CanSendOtherMessages:  true,
-		CanAddWebPagePreviews: true,
-		CanChangeInfo:         false,
-		CanInviteUsers:        false,
-		CanPinMessages:        false,
+// UnmuteJob hebt ein abgelaufenes Mute auf, sofern der User nicht inzwischen länger gemutet wurde
+func UnmuteJob(b *bot.Bot, job database.Job) error {
+	muted, err := b.GetDB().GetMutedUser(job.UserID, job.ChatID)
+	if err == nil && time.Now().Before(muted.Until) {
+		return nil
+	}
+
+	return unmuteUser(b, job.ChatID, job.UserID)
+}
+
+// unmuteUser hebt ein Mute auf und stellt die Rechte von vor dem Mute wieder her
+func unmuteUser(b *bot.Bot, chatID, userID int64) error {
+	if err := b.RestoreMember(chatID, userID, nil); err != nil {
+		return err
 	}
+	return b.GetDB().RemoveMutedUser(userID, chatID)
+}
 
-	b.RestrictChatMember(chatID, userID, permissions)
-	b.GetDB().RemoveMutedUser(userID, chatID)
+func (h *DeleteHandler) RequiredPermission(b *bot.Bot, update tgbotapi.Update) (int64, string) {
+	return groupPermission(update, bot.PermDelete)
 }
 
 func (h *DeleteHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
@@ -334,67 +448,30 @@ func (h *DeleteHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
 		return nil
 	}
 
-	if !isUserAuthorized(b, update.Message.Chat.ID, update.Message.From.ID) {
-		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, "Du hast keine Berechtigung für diesen Befehl.", 5)
-		return nil
-	}
+	tr := b.ChatLocalizer(update.Message.Chat.ID)
 
-	args := update.Message.CommandArguments()
-	if args == "" {
-		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, "Verwendung: /del [Anzahl]\nBeispiel: /del 10", 5)
+	selected, err := selectForDelete(b, update.Message)
+	if err != nil {
+		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, tr.Error(err), 5)
 		return nil
 	}
 
-	count, err := strconv.Atoi(strings.TrimSpace(args))
+	deletedCount, err := deleteSelected(b, update.Message, selected)
 	if err != nil {
-		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, "Ungültige Anzahl. Bitte gib eine Zahl ein.", 5)
-		return nil
+		return err
 	}
 
-	if count < 1 || count > b.GetConfig().Admin.MaxDeleteMessages {
-		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, fmt.Sprintf("Anzahl muss zwischen 1 und %d liegen.", b.GetConfig().Admin.MaxDeleteMessages), 5)
+	if deletedCount == 0 {
+		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, tr.T("del.none"), 5)
 		return nil
 	}
 
-	startMessageID := update.Message.MessageID
-	deletedCount := 0
-
-	// Erweiterte Löschstrategie: Versuche einen größeren Bereich um aktuelle Nachricht
-	// Da Message IDs nicht sequenziell sind, probieren wir verschiedene Bereiche
-	maxTries := count * 3 // Erweitere Suchbereich
-
-	for i := 1; i <= maxTries && deletedCount < count; i++ {
-		// Versuche Nachrichten in beide Richtungen
-		candidates := []int{
-			startMessageID - i, // Rückwärts
-			startMessageID + i, // Vorwärts
-		}
-
-		for _, messageID := range candidates {
-			if messageID > 0 && deletedCount < count {
-				err := b.DeleteMessage(update.Message.Chat.ID, messageID)
-				if err == nil {
-					deletedCount++
-				}
-			}
-		}
-
-		time.Sleep(30 * time.Millisecond)
-	}
-
-	b.DeleteMessage(update.Message.Chat.ID, update.Message.MessageID)
-
-	if deletedCount > 0 {
-		successMsg := fmt.Sprintf(
-			"%d Nachrichten geloescht\n\n"+
-				"Admin: %s",
-			deletedCount,
-			bot.GetUserMention(update.Message.From),
-		)
-
-		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, successMsg, 5)
-	}
+	successMsg := tr.T("del.success", i18n.Vars{
+		"count": deletedCount,
+		"admin": bot.GetUserMention(update.Message.From),
+	})
 
+	_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, successMsg, 5)
 	return nil
 }
 
@@ -408,10 +485,10 @@ func extractTargetUserAndReason(b *bot.Bot, message *tgbotapi.Message) (*tgbotap
 			user.ID, user.UserName, user.FirstName)
 
 		if user.ID == 0 {
-			return nil, "", fmt.Errorf("User-ID ist 0 in Reply-Nachricht")
+			return nil, "", i18n.NewError("user.reply_id_zero")
 		}
 		if user.IsBot {
-			return nil, "", fmt.Errorf("Kann keine Aktionen auf Bots ausführen")
+			return nil, "", i18n.NewError("user.is_bot")
 		}
 
 		// Reason aus Command-Arguments extrahieren
@@ -426,7 +503,7 @@ func extractTargetUserAndReason(b *bot.Bot, message *tgbotapi.Message) (*tgbotap
 	// Priority 2: Parse from command arguments
 	args := strings.Fields(message.CommandArguments())
 	if len(args) < 1 {
-		return nil, "", fmt.Errorf("Verwendung: /%s @username [Grund] oder als Antwort auf eine Nachricht", message.Command())
+		return nil, "", i18n.NewError("common.target_usage", i18n.Vars{"command": message.Command()})
 	}
 
 	user, err := parseUserFromArgs(b, message.Chat.ID, args[0])
@@ -470,7 +547,7 @@ func parseUserFromArgs(b *bot.Bot, chatID int64, arg string) (*tgbotapi.User, er
 		}, nil
 	}
 
-	return nil, fmt.Errorf("Ungültiges Format: %s (verwende @username oder User-ID)", arg)
+	return nil, i18n.NewError("user.invalid_format", i18n.Vars{"input": arg})
 }
 
 // resolveUsernameInChat versucht einen Username über Chat Member API aufzulösen
@@ -478,6 +555,11 @@ func resolveUsernameInChat(b *bot.Bot, chatID int64, username string) (int64, er
 	// Telegram Bot API kann Usernames nicht direkt auflösen
 	// Wir müssen den User über eine Nachricht finden oder Chat-Member enumerieren
 
+	// Autoren der zuletzt gesehenen Nachrichten sind bekannt
+	if userID, ok := b.MessageIndex().FindUser(chatID, username); ok {
+		return userID, nil
+	}
+
 	// Versuche Chat Member API (funktioniert nur bei kleinen Gruppen)
 	config := tgbotapi.ChatAdministratorsConfig{
 		ChatConfig: tgbotapi.ChatConfig{ChatID: chatID},
@@ -492,28 +574,16 @@ func resolveUsernameInChat(b *bot.Bot, chatID int64, username string) (int64, er
 		}
 	}
 
-	return 0, fmt.Errorf("Username @%s nicht gefunden. Bei großen Gruppen verwende 'Auf Nachricht antworten' oder User-ID", username)
+	return 0, i18n.NewError("user.not_found", i18n.Vars{"username": username})
 }
 
-// isUserAuthorized prüft ob User entweder Bot-Admin oder Gruppen-Admin ist
-func isUserAuthorized(b *bot.Bot, chatID, userID int64) bool {
-	// Prüfen ob User bereits Bot-Admin ist
-	cfg := b.GetConfig()
-	for _, adminID := range cfg.Admin.AdminUserIDs {
-		if adminID == userID {
-			return true
-		}
+// groupPermission verlangt ein Recht in der Gruppe, in der ein Command aufgerufen wurde.
+// In DMs wird nichts geprüft, dort antworten die Gruppen-Commands nicht oder nur mit einem Hinweis.
+func groupPermission(update tgbotapi.Update, permission string) (int64, string) {
+	if update.Message == nil || update.Message.Chat.Type == "private" {
+		return 0, ""
 	}
-
-	// Prüfen ob User Gruppen-Admin ist (nur in Gruppen, nicht in DMs)
-	if chatID < 0 { // Negative Chat-IDs sind Gruppen
-		isAdmin, err := b.IsUserAdmin(chatID, userID)
-		if err == nil && isAdmin {
-			return true
-		}
-	}
-
-	return false
+	return update.Message.Chat.ID, permission
 }
 
 type UnmuteHandler struct{}
@@ -522,19 +592,20 @@ func NewUnmuteHandler() *UnmuteHandler {
 	return &UnmuteHandler{}
 }
 
+func (h *UnmuteHandler) RequiredPermission(b *bot.Bot, update tgbotapi.Update) (int64, string) {
+	return groupPermission(update, bot.PermMute)
+}
+
 func (h *UnmuteHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
 	if update.Message.Chat.Type == "private" {
 		return nil
 	}
 
-	if !isUserAuthorized(b, update.Message.Chat.ID, update.Message.From.ID) {
-		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, "Du hast keine Berechtigung für diesen Befehl.", 5)
-		return nil
-	}
+	tr := b.ChatLocalizer(update.Message.Chat.ID)
 
 	targetUser, err := extractTargetUser(b, update.Message)
 	if err != nil {
-		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, ""+err.Error(), 5)
+		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, tr.Error(err), 5)
 		return nil
 	}
 
@@ -544,37 +615,21 @@ func (h *UnmuteHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
 	}
 
 	if !isMuted {
-		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, "Dieser User ist nicht gemutet.", 5)
+		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, tr.T("unmute.not_muted"), 5)
 		return nil
 	}
 
-	permissions := tgbotapi.ChatPermissions{
-		CanSendMessages:       true,
-		CanSendMediaMessages:  true,
-		CanSendPolls:          true,
-		CanSendOtherMessages:  true,
-		CanAddWebPagePreviews: true,
-		CanChangeInfo:         false,
-		CanInviteUsers:        false,
-		CanPinMessages:        false,
-	}
-
-	if err := b.RestrictChatMember(update.Message.Chat.ID, targetUser.ID, permissions); err != nil {
-		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, "Fehler beim Entmuten des Users.", 5)
+	if err := unmuteUser(b, update.Message.Chat.ID, targetUser.ID); err != nil {
+		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, tr.T("unmute.failed"), 5)
 		return fmt.Errorf("failed to unmute user: %w", err)
 	}
+	b.CancelJobs(bot.JobUnmute, update.Message.Chat.ID, targetUser.ID)
+	revokeActions(b, update.Message.Chat.ID, targetUser.ID, database.ActionMute, update.Message.From.ID)
 
-	if err := b.GetDB().RemoveMutedUser(targetUser.ID, update.Message.Chat.ID); err != nil {
-		return fmt.Errorf("failed to remove muted user from database: %w", err)
-	}
-
-	successMsg := fmt.Sprintf(
-		"User entmutet\n\n"+
-			"User: %s\n"+
-			"Admin: %s",
-		bot.FormatUserName(targetUser),
-		bot.GetUserMention(update.Message.From),
-	)
+	successMsg := tr.T("unmute.success", i18n.Vars{
+		"user":  bot.FormatUserName(targetUser),
+		"admin": bot.GetUserMention(update.Message.From),
+	})
 
 	_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, successMsg, 5)
 	return nil
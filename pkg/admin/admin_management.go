@@ -1,17 +1,26 @@
 package admin
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
-	"strconv"
 	"strings"
-	"telegramBot/config"
 	"telegramBot/pkg/bot"
+	"telegramBot/pkg/database"
+	"telegramBot/pkg/i18n"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+// adminAuditLimit begrenzt die Einträge des Audit-Trails in /admins
+const adminAuditLimit = 10
+
+// AddAdminHandler vergibt Bot-Rollen:
+//
+//	/add_admin <user_id|@user> [owner|admin|moderator]
+//	/add_admin [Rolle] (als Antwort auf eine Nachricht des Users)
+//
+// Ohne Rolle wird admin vergeben. Verlangt das Recht manage_admins (Standard: Owner und Admins),
+// vergeben wird höchstens die eigene Rolle.
 type AddAdminHandler struct{}
 
 func NewAddAdminHandler() *AddAdminHandler {
@@ -23,118 +32,54 @@ func (h *AddAdminHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
 		return nil
 	}
 
-	// Prüfen ob User Admin ist (entweder in Config oder Gruppen-Admin)
-	if !h.isUserAuthorized(b, update.Message.Chat.ID, update.Message.From.ID) {
-		_, err := b.SendTemporaryGroupMessage(update.Message.Chat.ID, "❌ Du hast keine Berechtigung für diesen Befehl.", 5)
-		return err
-	}
+	message := update.Message
+	tr := b.Localizer(message.Chat, message.From)
 
-	args := update.Message.CommandArguments()
-	if args == "" {
-		_, err := b.SendTemporaryGroupMessage(update.Message.Chat.ID, "❌ Verwendung: /add_admin <user_id> oder antworte auf eine Nachricht", 5)
-		return err
-	}
-
-	var userID int64
-	var err error
-
-	// Prüfen ob auf eine Nachricht geantwortet wurde
-	if update.Message.ReplyToMessage != nil {
-		userID = update.Message.ReplyToMessage.From.ID
-	} else {
-		// User ID aus Argumenten parsen
-		userID, err = strconv.ParseInt(strings.TrimSpace(args), 10, 64)
-		if err != nil {
-			_, err := b.SendTemporaryGroupMessage(update.Message.Chat.ID, "❌ Ungültige User ID", 5)
-			return err
-		}
-	}
+	actorRole := b.BotRole(message.From.ID)
 
-	// Admin hinzufügen
-	if err := h.addAdminToConfig(userID); err != nil {
-		_, err := b.SendTemporaryGroupMessage(update.Message.Chat.ID, "❌ Fehler beim Hinzufügen des Admins", 5)
+	if message.ReplyToMessage == nil && message.CommandArguments() == "" {
+		_, err := b.SendTemporaryGroupMessage(message.Chat.ID, tr.T("add_admin.usage"), 5)
 		return err
 	}
 
-	// Bot-Config neu laden
-	newConfig, err := config.LoadConfig("config/config.json")
+	target, role, err := extractTargetUserAndReason(b, message)
 	if err != nil {
-		_, err := b.SendTemporaryGroupMessage(update.Message.Chat.ID, "❌ Fehler beim Neuladen der Konfiguration", 5)
+		_, err := b.SendTemporaryGroupMessage(message.Chat.ID, tr.Error(err), 5)
 		return err
 	}
-
-	// Config im Bot aktualisieren
-	*b.GetConfig() = *newConfig
-
-	_, err = b.SendTemporaryGroupMessage(update.Message.Chat.ID, fmt.Sprintf("✅ User %d wurde als Admin hinzugefügt", userID), 5)
-	return err
-}
-
-func (h *AddAdminHandler) isUserAuthorized(b *bot.Bot, chatID, userID int64) bool {
-	// Prüfen ob User bereits Bot-Admin ist
-	cfg := b.GetConfig()
-	for _, adminID := range cfg.Admin.AdminUserIDs {
-		if adminID == userID {
-			return true
-		}
-	}
-
-	// Prüfen ob User Gruppen-Admin ist (nur in Gruppen, nicht in DMs)
-	if chatID < 0 { // Negative Chat-IDs sind Gruppen
-		isAdmin, err := b.IsUserAdmin(chatID, userID)
-		if err == nil && isAdmin {
-			return true
-		}
+	role = strings.ToLower(strings.TrimSpace(role))
+	if role == "" {
+		role = database.RoleAdmin
 	}
-
-	return false
-}
-
-func (h *AddAdminHandler) addAdminToConfig(userID int64) error {
-	configPath := "config/config.json"
-
-	// Config laden
-	data, err := os.ReadFile(configPath)
-	if err != nil {
+	if bot.RoleRank(role) == 0 {
+		_, err := b.SendTemporaryGroupMessage(message.Chat.ID, tr.T("admins.invalid_role", i18n.Vars{"role": role}), 5)
 		return err
 	}
 
-	var cfg map[string]interface{}
-	if err := json.Unmarshal(data, &cfg); err != nil {
+	// Niemand vergibt mehr als die eigene Rolle oder ändert jemanden mit höherer Rolle
+	currentRole := b.BotRole(target.ID)
+	if bot.RoleRank(role) > bot.RoleRank(actorRole) || bot.RoleRank(currentRole) > bot.RoleRank(actorRole) {
+		_, err := b.SendTemporaryGroupMessage(message.Chat.ID, tr.T("add_admin.forbidden", i18n.Vars{"role": role}), 5)
 		return err
 	}
 
-	// Admin-Array holen
-	admin, ok := cfg["admin"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("admin section not found")
-	}
-
-	adminIDs, ok := admin["admin_user_ids"].([]interface{})
-	if !ok {
-		adminIDs = []interface{}{}
-	}
-
-	// Prüfen ob User bereits Admin ist
-	for _, id := range adminIDs {
-		if int64(id.(float64)) == userID {
-			return fmt.Errorf("user is already admin")
+	if err := b.GrantBotRole(target.ID, role, message.From.ID, "/add_admin"); err != nil {
+		if isUserError(err) {
+			_, err := b.SendTemporaryGroupMessage(message.Chat.ID, tr.Error(err), 5)
+			return err
 		}
-	}
-
-	// User hinzufügen
-	adminIDs = append(adminIDs, userID)
-	admin["admin_user_ids"] = adminIDs
-
-	// Config speichern
-	newData, err := json.MarshalIndent(cfg, "", "  ")
-	if err != nil {
+		b.SendTemporaryGroupMessage(message.Chat.ID, tr.T("add_admin.failed"), 5)
 		return err
 	}
 
-	return os.WriteFile(configPath, newData, 0644)
+	_, err = b.SendTemporaryGroupMessage(message.Chat.ID, tr.T("add_admin.success", i18n.Vars{
+		"user": target.ID,
+		"role": role,
+	}), 5)
+	return err
 }
 
+// DelAdminHandler entzieht Bot-Rollen: /del_admin <user_id|@user> oder als Antwort
 type DelAdminHandler struct{}
 
 func NewDelAdminHandler() *DelAdminHandler {
@@ -146,120 +91,120 @@ func (h *DelAdminHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
 		return nil
 	}
 
-	// Prüfen ob User Admin ist (entweder in Config oder Gruppen-Admin)
-	if !h.isUserAuthorized(b, update.Message.Chat.ID, update.Message.From.ID) {
-		_, err := b.SendTemporaryGroupMessage(update.Message.Chat.ID, "❌ Du hast keine Berechtigung für diesen Befehl.", 5)
+	message := update.Message
+	tr := b.Localizer(message.Chat, message.From)
+
+	actorRole := b.BotRole(message.From.ID)
+
+	if message.ReplyToMessage == nil && message.CommandArguments() == "" {
+		_, err := b.SendTemporaryGroupMessage(message.Chat.ID, tr.T("del_admin.usage"), 5)
 		return err
 	}
 
-	args := update.Message.CommandArguments()
-	if args == "" {
-		_, err := b.SendTemporaryGroupMessage(update.Message.Chat.ID, "❌ Verwendung: /del_admin <user_id> oder antworte auf eine Nachricht", 5)
+	target, err := extractTargetUser(b, message)
+	if err != nil {
+		_, err := b.SendTemporaryGroupMessage(message.Chat.ID, tr.Error(err), 5)
 		return err
 	}
 
-	var userID int64
-	var err error
-
-	// Prüfen ob auf eine Nachricht geantwortet wurde
-	if update.Message.ReplyToMessage != nil {
-		userID = update.Message.ReplyToMessage.From.ID
-	} else {
-		// User ID aus Argumenten parsen
-		userID, err = strconv.ParseInt(strings.TrimSpace(args), 10, 64)
-		if err != nil {
-			_, err := b.SendTemporaryGroupMessage(update.Message.Chat.ID, "❌ Ungültige User ID", 5)
-			return err
-		}
+	currentRole := b.BotRole(target.ID)
+	if currentRole == "" {
+		_, err := b.SendTemporaryGroupMessage(message.Chat.ID, tr.T("del_admin.not_admin", i18n.Vars{"user": target.ID}), 5)
+		return err
 	}
-
-	// Admin entfernen
-	if err := h.removeAdminFromConfig(userID); err != nil {
-		_, err := b.SendTemporaryGroupMessage(update.Message.Chat.ID, "❌ Fehler beim Entfernen des Admins oder User ist kein Admin", 5)
+	if bot.RoleRank(currentRole) > bot.RoleRank(actorRole) {
+		_, err := b.SendTemporaryGroupMessage(message.Chat.ID, tr.T("del_admin.forbidden", i18n.Vars{
+			"user": target.ID,
+			"role": currentRole,
+		}), 5)
 		return err
 	}
 
-	// Bot-Config neu laden
-	newConfig, err := config.LoadConfig("config/config.json")
-	if err != nil {
-		_, err := b.SendTemporaryGroupMessage(update.Message.Chat.ID, "❌ Fehler beim Neuladen der Konfiguration", 5)
+	if _, err := b.RevokeBotRole(target.ID, message.From.ID, "/del_admin"); err != nil {
+		if isUserError(err) {
+			_, err := b.SendTemporaryGroupMessage(message.Chat.ID, tr.Error(err), 5)
+			return err
+		}
+		b.SendTemporaryGroupMessage(message.Chat.ID, tr.T("del_admin.failed"), 5)
 		return err
 	}
 
-	// Config im Bot aktualisieren
-	*b.GetConfig() = *newConfig
-
-	_, err = b.SendTemporaryGroupMessage(update.Message.Chat.ID, fmt.Sprintf("✅ User %d wurde als Admin entfernt", userID), 5)
+	_, err = b.SendTemporaryGroupMessage(message.Chat.ID, tr.T("del_admin.success", i18n.Vars{"user": target.ID}), 5)
 	return err
 }
 
-func (h *DelAdminHandler) isUserAuthorized(b *bot.Bot, chatID, userID int64) bool {
-	// Prüfen ob User bereits Bot-Admin ist
-	cfg := b.GetConfig()
-	for _, adminID := range cfg.Admin.AdminUserIDs {
-		if adminID == userID {
-			return true
-		}
-	}
-
-	// Prüfen ob User Gruppen-Admin ist (nur in Gruppen, nicht in DMs)
-	if chatID < 0 { // Negative Chat-IDs sind Gruppen
-		isAdmin, err := b.IsUserAdmin(chatID, userID)
-		if err == nil && isAdmin {
-			return true
-		}
-	}
+// AdminsHandler zeigt Bot-Admins mit ihren Rollen und die letzten Rechtevergaben per DM
+type AdminsHandler struct{}
 
-	return false
+func NewAdminsHandler() *AdminsHandler {
+	return &AdminsHandler{}
 }
 
-func (h *DelAdminHandler) removeAdminFromConfig(userID int64) error {
-	configPath := "config/config.json"
-
-	// Config laden
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return err
+func (h *AdminsHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
+	if update.Message == nil || !update.Message.IsCommand() {
+		return nil
 	}
 
-	var cfg map[string]interface{}
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		return err
+	message := update.Message
+	if message.Chat.Type != "private" {
+		b.DeleteMessage(message.Chat.ID, message.MessageID)
 	}
 
-	// Admin-Array holen
-	admin, ok := cfg["admin"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("admin section not found")
-	}
+	tr := b.UserLocalizer(message.From)
 
-	adminIDs, ok := admin["admin_user_ids"].([]interface{})
-	if !ok {
-		return fmt.Errorf("no admins found")
+	admins, err := b.GetDB().GetBotAdmins()
+	if err != nil {
+		return fmt.Errorf("failed to load bot admins: %w", err)
 	}
-
-	// User entfernen
-	newAdminIDs := []interface{}{}
-	found := false
-	for _, id := range adminIDs {
-		if int64(id.(float64)) != userID {
-			newAdminIDs = append(newAdminIDs, id)
-		} else {
-			found = true
+	audit, err := b.GetDB().GetAdminAudit(adminAuditLimit)
+	if err != nil {
+		return fmt.Errorf("failed to load admin audit: %w", err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(tr.T("admins.title") + "\n")
+	if len(admins) == 0 {
+		sb.WriteString(tr.T("admins.empty") + "\n")
+	}
+	for _, admin := range admins {
+		sb.WriteString(tr.T("admins.entry", i18n.Vars{"user": admin.UserID, "role": admin.Role}) + "\n")
+	}
+
+	if len(audit) > 0 {
+		sb.WriteString("\n" + tr.T("admins.audit_title") + "\n")
+		for _, entry := range audit {
+			actor := fmt.Sprint(entry.ActorID)
+			if entry.ActorID == 0 {
+				actor = tr.T("admins.actor_bot")
+			}
+			sb.WriteString(tr.T("admins.audit_"+entry.Action, i18n.Vars{
+				"date":  tr.FormatTime(entry.CreatedAt),
+				"user":  entry.UserID,
+				"role":  entry.Role,
+				"actor": actor,
+			}) + "\n")
 		}
 	}
 
-	if !found {
-		return fmt.Errorf("user is not admin")
-	}
+	_, err = b.SendMessage(message.From.ID, sb.String())
+	return err
+}
 
-	admin["admin_user_ids"] = newAdminIDs
+// RequiredPermission: Bot-Admins werden global verwaltet
+func (h *AddAdminHandler) RequiredPermission(b *bot.Bot, update tgbotapi.Update) (int64, string) {
+	return 0, bot.PermManageAdmins
+}
 
-	// Config speichern
-	newData, err := json.MarshalIndent(cfg, "", "  ")
-	if err != nil {
-		return err
-	}
+func (h *DelAdminHandler) RequiredPermission(b *bot.Bot, update tgbotapi.Update) (int64, string) {
+	return 0, bot.PermManageAdmins
+}
+
+func (h *AdminsHandler) RequiredPermission(b *bot.Bot, update tgbotapi.Update) (int64, string) {
+	return 0, bot.PermManageAdmins
+}
 
-	return os.WriteFile(configPath, newData, 0644)
+// isUserError erkennt Fehler, die für den User bestimmt sind, z.B. "letzter Owner"
+func isUserError(err error) bool {
+	var userErr *i18n.Error
+	return errors.As(err, &userErr)
 }
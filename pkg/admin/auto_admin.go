@@ -1,15 +1,14 @@
 package admin
 
 import (
-	"encoding/json"
-	"fmt"
 	"log"
-	"os"
 	"telegramBot/pkg/bot"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+// AutoAdminHandler frischt die Rechte von Gruppen-Admins auf. Die Rechte gelten nur in der
+// jeweiligen Gruppe, globale Bot-Rollen vergibt ausschließlich /add_admin.
 type AutoAdminHandler struct{}
 
 func NewAutoAdminHandler() *AutoAdminHandler {
@@ -22,129 +21,47 @@ func (h *AutoAdminHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
 		return nil
 	}
 
-	// Prüfen ob User bereits Bot-Admin ist
-	if h.isBotAdmin(b, update.Message.From.ID) {
-		return nil // Bereits Bot-Admin
+	// Bot-Admins brauchen keine Gruppenrechte
+	if b.BotRole(update.Message.From.ID) != "" {
+		return nil
 	}
 
-	// Alle Gruppen durchgehen und prüfen ob User dort Admin ist
-	return h.checkAndGrantAdminRights(b, update.Message.From.ID)
-}
-
-func (h *AutoAdminHandler) isBotAdmin(b *bot.Bot, userID int64) bool {
-	cfg := b.GetConfig()
-	for _, adminID := range cfg.Admin.AdminUserIDs {
-		if adminID == userID {
-			return true
+	for _, chatID := range h.checkIfUserIsGroupAdmin(b, update.Message.From.ID) {
+		if err := h.SyncGroupAdmins(b, chatID); err != nil {
+			log.Printf("Failed to sync admins of chat %d: %v", chatID, err)
 		}
 	}
-	return false
-}
-
-func (h *AutoAdminHandler) checkAndGrantAdminRights(b *bot.Bot, userID int64) error {
-	// Alle aktiven Chats durchgehen (hier müssten wir eine Chat-Liste haben)
-	// Da wir keine Chat-Liste haben, prüfen wir alle bekannten Gruppen aus der DB
-
-	// Alternative: Bei der ersten DM eines Users prüfen wir alle Gruppen
-	// Dies ist ein vereinfachter Ansatz - in der Praxis würde man die Chat-IDs speichern
-
-	isGroupAdmin := h.checkIfUserIsGroupAdmin(b, userID)
-
-	if isGroupAdmin {
-		return h.addUserToBotAdmins(userID)
-	}
-
 	return nil
 }
 
-func (h *AutoAdminHandler) checkIfUserIsGroupAdmin(b *bot.Bot, userID int64) bool {
-	// Da wir keine Liste aller Gruppen haben, können wir das nur bei Commands prüfen
-	// Diese Funktion wird bei DM-Kontakt aufgerufen
-	// Für eine vollständige Implementierung müssten wir eine Gruppen-Liste in der DB speichern
-
-	// Für jetzt return false - die Logik wird in anderen Handlern integriert
-	return false
-}
-
-func (h *AutoAdminHandler) addUserToBotAdmins(userID int64) error {
-	configPath := "config/config.json"
-
-	// Config laden
-	data, err := os.ReadFile(configPath)
+// checkIfUserIsGroupAdmin liefert die bekannten Gruppen, in denen der User bei Telegram Admin ist
+func (h *AutoAdminHandler) checkIfUserIsGroupAdmin(b *bot.Bot, userID int64) []int64 {
+	chats, err := b.GetDB().GetKnownChats()
 	if err != nil {
-		return err
-	}
-
-	var cfg map[string]interface{}
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		return err
-	}
-
-	// Admin-Array holen
-	admin, ok := cfg["admin"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("admin section not found")
-	}
-
-	adminIDs, ok := admin["admin_user_ids"].([]interface{})
-	if !ok {
-		adminIDs = []interface{}{}
+		log.Printf("Failed to load known chats: %v", err)
+		return nil
 	}
 
-	// Prüfen ob User bereits Admin ist
-	for _, id := range adminIDs {
-		if int64(id.(float64)) == userID {
-			return nil // Bereits Admin
+	var chatIDs []int64
+	for _, chat := range chats {
+		if chat.Type != "group" && chat.Type != "supergroup" {
+			continue
+		}
+		if isAdmin, err := b.IsUserAdmin(chat.ChatID, userID); err == nil && isAdmin {
+			chatIDs = append(chatIDs, chat.ChatID)
 		}
 	}
-
-	// User hinzufügen
-	adminIDs = append(adminIDs, userID)
-	admin["admin_user_ids"] = adminIDs
-
-	// Config speichern
-	newData, err := json.MarshalIndent(cfg, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	if err := os.WriteFile(configPath, newData, 0644); err != nil {
-		return err
-	}
-
-	log.Printf("Auto-Admin: User %d wurde automatisch als Bot-Admin hinzugefügt", userID)
-	return nil
+	return chatIDs
 }
 
-// SyncGroupAdmins synchronisiert Gruppen-Admins mit Bot-Admins
+// SyncGroupAdmins gleicht die Admins einer Gruppe ab, ohne globale Rechte zu vergeben
 func (h *AutoAdminHandler) SyncGroupAdmins(b *bot.Bot, chatID int64) error {
-	// Alle Admins der Gruppe holen
-	config := tgbotapi.ChatAdministratorsConfig{
-		ChatConfig: tgbotapi.ChatConfig{ChatID: chatID},
-	}
-
-	admins, err := b.GetAPI().GetChatAdministrators(config)
+	added, removed, err := b.SyncGroupAdmins(chatID)
 	if err != nil {
-		return fmt.Errorf("failed to get chat administrators: %w", err)
+		return err
 	}
-
-	// Für jeden Admin prüfen und ggf. als Bot-Admin hinzufügen
-	for _, admin := range admins {
-		if admin.User.IsBot {
-			continue // Bots überspringen
-		}
-
-		if !h.isBotAdmin(b, admin.User.ID) {
-			if err := h.addUserToBotAdmins(admin.User.ID); err != nil {
-				log.Printf("Failed to add group admin %d as bot admin: %v", admin.User.ID, err)
-			} else {
-				log.Printf("Auto-Admin: Group admin %d (@%s) wurde als Bot-Admin hinzugefügt",
-					admin.User.ID, admin.User.UserName)
-			}
-		}
+	if added > 0 || removed > 0 {
+		log.Printf("Synced admins of chat %d: %d added, %d removed", chatID, added, removed)
 	}
-
-	// Bot-Config neu laden (wird vom aufrufenden Code gemacht)
-
 	return nil
 }
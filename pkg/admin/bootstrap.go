@@ -1,16 +1,16 @@
 package admin
 
 import (
-	"encoding/json"
+	"fmt"
 	"log"
-	"os"
-	"telegramBot/config"
+	"strings"
 	"telegramBot/pkg/bot"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
-// BootstrapHandler macht den ersten User automatisch zum Admin
+// BootstrapHandler weist im privaten Chat auf /claim hin, solange der Bot keine Admins hat.
+// Wer den Bot zuerst anschreibt, wird dadurch nicht mehr automatisch Owner.
 type BootstrapHandler struct{}
 
 func NewBootstrapHandler() *BootstrapHandler {
@@ -24,74 +24,56 @@ func (h *BootstrapHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
 	}
 
 	// Nur wenn noch keine Admins vorhanden sind
-	if len(b.GetConfig().Admin.AdminUserIDs) > 0 {
+	count, err := b.GetDB().CountBotAdmins("")
+	if err != nil {
+		return fmt.Errorf("failed to count bot admins: %w", err)
+	}
+	if count > 0 {
 		return nil
 	}
 
-	// Ersten User als Admin hinzufügen
-	userID := update.Message.From.ID
-	username := bot.GetUserIdentifier(update.Message.From)
-
-	if err := h.addFirstAdmin(userID); err != nil {
-		log.Printf("Failed to bootstrap first admin: %v", err)
-		return err
+	// Ist das Token abgelaufen, steht danach ein neues im Log
+	if err := b.RenewClaimToken(); err != nil {
+		log.Printf("Failed to renew claim token: %v", err)
 	}
 
-	// Config neu laden
-	newConfig, err := config.LoadConfig("config/config.json")
-	if err != nil {
-		log.Printf("Failed to reload config after bootstrap: %v", err)
-		return err
-	}
-
-	*b.GetConfig() = *newConfig
-
-	log.Printf("🚀 Bootstrap: First admin added - %d (%s)", userID, username)
-
-	// Willkommensnachricht senden
-	welcomeMsg := `🎉 Willkommen als erster Bot-Administrator!
-
-Du wurdest automatisch als Admin hinzugefügt, da noch keine Admins konfiguriert waren.
-
-💡 Was du jetzt tun kannst:
-• /config - Bot-Einstellungen anpassen
-• /help - Alle verfügbaren Commands anzeigen
-• /add_admin @user - Weitere Admins hinzufügen
+	_, err = b.SendMessage(update.Message.Chat.ID, b.UserLocalizer(update.Message.From).T("bootstrap.claim_hint"))
+	return err
+}
 
-✅ Du hast jetzt volle Bot-Administrator-Rechte!`
+// ClaimHandler macht den Absender zum ersten Owner: /claim <token> mit dem Token aus dem Log
+type ClaimHandler struct{}
 
-	b.SendMessage(update.Message.Chat.ID, welcomeMsg)
-	return nil
+func NewClaimHandler() *ClaimHandler {
+	return &ClaimHandler{}
 }
 
-func (h *BootstrapHandler) addFirstAdmin(userID int64) error {
-	configPath := "config/config.json"
+func (h *ClaimHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
+	message := update.Message
+	tr := b.Localizer(message.Chat, message.From)
 
-	// Config laden
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return err
+	// Das Token gehört nicht in eine Gruppe, die Nachricht wird sofort gelöscht
+	if message.Chat.Type != "private" {
+		b.DeleteMessage(message.Chat.ID, message.MessageID)
+		_, _ = b.SendTemporaryGroupMessage(message.Chat.ID, tr.T("claim.dm_only"), 5)
+		return nil
 	}
 
-	var cfg map[string]interface{}
-	if err := json.Unmarshal(data, &cfg); err != nil {
+	token := strings.TrimSpace(message.CommandArguments())
+	if token == "" {
+		_, err := b.SendMessage(message.Chat.ID, tr.T("claim.usage"))
 		return err
 	}
 
-	// Admin-Array holen
-	admin, ok := cfg["admin"].(map[string]interface{})
-	if !ok {
-		return nil
-	}
-
-	// User als ersten Admin hinzufügen
-	admin["admin_user_ids"] = []interface{}{userID}
-
-	// Config speichern
-	newData, err := json.MarshalIndent(cfg, "", "  ")
-	if err != nil {
+	if err := b.RedeemClaimToken(token, message.From.ID); err != nil {
+		if isUserError(err) {
+			_, err := b.SendMessage(message.Chat.ID, tr.Error(err))
+			return err
+		}
+		b.SendMessage(message.Chat.ID, tr.T("claim.failed"))
 		return err
 	}
 
-	return os.WriteFile(configPath, newData, 0644)
+	_, err := b.SendMessage(message.Chat.ID, tr.T("bootstrap.welcome"))
+	return err
 }
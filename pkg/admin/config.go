@@ -1,13 +1,14 @@
 package admin
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
 	"strconv"
 	"strings"
 	"telegramBot/config"
 	"telegramBot/pkg/bot"
+	"telegramBot/pkg/duration"
+	"telegramBot/pkg/i18n"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -18,6 +19,15 @@ func NewConfigHandler() *ConfigHandler {
 	return &ConfigHandler{}
 }
 
+// RequiredPermission verlangt config in der Gruppe, die configTarget ermittelt, sonst global
+func (h *ConfigHandler) RequiredPermission(b *bot.Bot, update tgbotapi.Update) (int64, string) {
+	if update.Message == nil || update.Message.Chat.Type != "private" {
+		return 0, ""
+	}
+	targetChatID, _ := configTarget(b, update.Message)
+	return targetChatID, bot.PermConfig
+}
+
 func (h *ConfigHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
 	if update.Message == nil || !update.Message.IsCommand() {
 		return nil
@@ -28,203 +38,210 @@ func (h *ConfigHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
 		return nil
 	}
 
-	// Auto-Sync: Prüfen ob User Gruppen-Admin ist (wird bei erstem DM gemacht)
-	h.AutoSyncGroupAdmins(b, update.Message.From.ID)
+	tr := b.UserLocalizer(update.Message.From)
 
-	// Prüfen ob User Admin ist (nach möglicher Auto-Sync)
-	if !h.isUserAdmin(b, update.Message.From.ID) {
-		_, err := b.SendMessage(update.Message.Chat.ID, "❌ Du hast keine Berechtigung für diesen Befehl.")
-		return err
+	// Gruppen-Config: /config [<chat_id>] [<schlüssel> <wert> | reset <schlüssel>]
+	targetChatID, args := configTarget(b, update.Message)
+	if targetChatID != 0 {
+		return h.handleGroupConfig(b, tr, update.Message, targetChatID, args)
 	}
 
-	args := update.Message.CommandArguments()
 	if args == "" {
-		return h.showConfigMenu(b, update.Message.Chat.ID)
+		return h.showConfigMenu(b, tr, update.Message.Chat.ID)
 	}
 
 	parts := strings.SplitN(args, " ", 2)
 	if len(parts) < 2 {
-		return h.showConfigMenu(b, update.Message.Chat.ID)
+		return h.showConfigMenu(b, tr, update.Message.Chat.ID)
 	}
 
 	key := parts[0]
 	value := parts[1]
 
-	return h.updateConfig(b, update.Message.Chat.ID, key, value)
+	return h.updateConfig(b, tr, update.Message.Chat.ID, key, value)
 }
 
-func (h *ConfigHandler) isUserAdmin(b *bot.Bot, userID int64) bool {
-	cfg := b.GetConfig()
-	for _, adminID := range cfg.Admin.AdminUserIDs {
-		if adminID == userID {
-			return true
+// configTarget ermittelt die Gruppe, auf die sich /config bezieht, und die restlichen Argumente.
+// Eine Gruppen-ID geht vor "global", das wiederum vor der per /groups gewählten Gruppe.
+func configTarget(b *bot.Bot, message *tgbotapi.Message) (int64, string) {
+	args := strings.TrimSpace(message.CommandArguments())
+	if targetChatID, rest, ok := parseTargetChat(args); ok {
+		return targetChatID, rest
+	}
+
+	parts := strings.SplitN(args, " ", 2)
+	if parts[0] == "global" {
+		if len(parts) > 1 {
+			return 0, strings.TrimSpace(parts[1])
 		}
+		return 0, ""
 	}
-	return false
+	return b.SelectedChat(message.From.ID), args
 }
 
-// AutoSyncGroupAdmins prüft bei DM ob User Gruppen-Admin ist und fügt ihn automatisch hinzu
-func (h *ConfigHandler) AutoSyncGroupAdmins(b *bot.Bot, userID int64) error {
-	// Wenn bereits Bot-Admin, nichts tun
-	if h.isUserAdmin(b, userID) {
-		return nil
+// parseTargetChat erkennt eine Gruppen-ID (negativ) als erstes Argument
+func parseTargetChat(args string) (int64, string, bool) {
+	parts := strings.SplitN(args, " ", 2)
+	chatID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || chatID >= 0 {
+		return 0, "", false
 	}
 
-	// Alle bekannten Chats aus der Datenbank holen
-	// Da wir keine Chat-Liste haben, implementieren wir das später
-	// Für jetzt loggen wir nur die Anfrage
-
-	return nil
+	rest := ""
+	if len(parts) > 1 {
+		rest = strings.TrimSpace(parts[1])
+	}
+	return chatID, rest, true
 }
 
-func (h *ConfigHandler) showConfigMenu(b *bot.Bot, chatID int64) error {
-	cfg := b.GetConfig()
+func (h *ConfigHandler) handleGroupConfig(b *bot.Bot, tr *bot.Localizer, message *tgbotapi.Message, targetChatID int64, args string) error {
+	if args == "" {
+		return h.showGroupConfigMenu(b, tr, message.Chat.ID, targetChatID)
+	}
+
+	parts := strings.SplitN(args, " ", 2)
+	if len(parts) < 2 {
+		return h.showGroupConfigMenu(b, tr, message.Chat.ID, targetChatID)
+	}
 
-	text := fmt.Sprintf(`⚙️ Bot Konfiguration
+	if parts[0] == "reset" {
+		key := strings.TrimSpace(parts[1])
+		if _, ok := config.LookupSetting(key); !ok {
+			_, err := b.SendMessage(message.Chat.ID, tr.T("config.unknown_key_message", i18n.Vars{"key": key}))
+			return err
+		}
 
-📋 Verfügbare Konfigurationsschlüssel:
+		if err := b.GetDB().RemoveGroupSetting(targetChatID, key); err != nil {
+			b.SendMessage(message.Chat.ID, tr.T("config.group_reset_failed"))
+			return err
+		}
 
-🔒 Captcha Einstellungen:
-• timeout_minutes = %d
-  └─ Zeitlimit für Captcha in Minuten (1-60)
+		_, err := b.SendMessage(message.Chat.ID, tr.T("config.group_reset", i18n.Vars{"key": key, "chat": targetChatID}))
+		return err
+	}
 
-• max_attempts = %d
-  └─ Maximale Versuche für Captcha (1-10)
+	key := parts[0]
+	value := parts[1]
 
-• welcome_message = "%s"
-  └─ Willkommensnachricht für neue User
+	setting, ok := config.LookupSetting(key)
+	if !ok {
+		_, err := b.SendMessage(message.Chat.ID, tr.T("config.unknown_key_message", i18n.Vars{"key": key}))
+		return err
+	}
+
+	if _, err := setting.Parse(value); err != nil {
+		_, err := b.SendMessage(message.Chat.ID, tr.T("config.invalid_value", i18n.Vars{"error": tr.Error(err)}))
+		return err
+	}
+
+	if err := b.GetDB().SetGroupSetting(targetChatID, key, value); err != nil {
+		b.SendMessage(message.Chat.ID, tr.T("config.group_save_failed"))
+		return err
+	}
 
-• message_delete_delay_minutes = %d
-  └─ Löschzeit für Willkommensnachrichten (1-60)
+	_, err := b.SendMessage(message.Chat.ID, tr.T("config.group_updated", i18n.Vars{"chat": targetChatID, "key": key, "value": value}))
+	return err
+}
 
-• success_message_delete_delay_minutes = %d
-  └─ Löschzeit für Erfolgsnachrichten (1-60)
+func (h *ConfigHandler) showConfigMenu(b *bot.Bot, tr *bot.Localizer, chatID int64) error {
+	cfg := b.GetConfig()
 
-👑 Admin Einstellungen:
-• default_mute_hours = %d
-  └─ Standard Mute Dauer in Stunden (1-168)
+	var sb strings.Builder
+	sb.WriteString(tr.T("config.menu_title") + "\n\n")
+	sb.WriteString(tr.T("config.menu_keys") + "\n")
 
-• max_delete_messages = %d
-  └─ Max löschbare Nachrichten pro Command (1-1000)
+	section := ""
+	for _, setting := range config.Settings {
+		if setting.Section != section {
+			section = setting.Section
+			sb.WriteString("\n" + tr.T(configSectionKey(section)) + "\n")
+		}
 
-📝 Verwendung:
-/config <schlüssel> <wert>
+		value, _ := cfg.GetValue(setting.Key)
+		sb.WriteString(fmt.Sprintf("• %s = %s\n  └─ %s%s\n\n", setting.Key, value, tr.T("setting."+setting.Key), settingRange(setting)))
+	}
 
-📌 Beispiele:
-• /config timeout_minutes 10
-• /config welcome_message "Hallo! Willkommen!"
-• /config success_message_delete_delay_minutes 2
-• /config max_attempts 5`,
-		cfg.Captcha.TimeoutMinutes,
-		cfg.Captcha.MaxAttempts,
-		cfg.Captcha.WelcomeMessage,
-		cfg.Captcha.MessageDeleteDelayMinutes,
-		cfg.Captcha.SuccessMessageDeleteDelayMinutes,
-		cfg.Admin.DefaultMuteHours,
-		cfg.Admin.MaxDeleteMessages)
+	sb.WriteString(tr.T("config.menu_usage"))
 
-	_, err := b.SendMessage(chatID, text)
+	_, err := b.SendMessage(chatID, sb.String())
 	return err
 }
 
-func (h *ConfigHandler) updateConfig(b *bot.Bot, chatID int64, key, value string) error {
-	configPath := "config/config.json"
+// configSectionKey liefert den Katalog-Schlüssel der Überschrift eines Abschnitts
+func configSectionKey(section string) string {
+	switch section {
+	case "captcha", "i18n", "logging":
+		return "config.section_" + section
+	default:
+		return "config.section_admin"
+	}
+}
 
-	// Config laden
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		b.SendMessage(chatID, "❌ Fehler beim Lesen der Konfigurationsdatei.")
-		return err
+// settingRange beschreibt die erlaubten Werte, z.B. " (1-60)" oder " (math, emoji)"
+func settingRange(setting config.Setting) string {
+	switch {
+	case setting.Numeric:
+		return fmt.Sprintf(" (%d-%d)", setting.Min, setting.Max)
+	case setting.Unit != 0:
+		return fmt.Sprintf(" (%s-%s)", duration.Format(time.Duration(setting.Min)*setting.Unit), duration.Format(time.Duration(setting.Max)*setting.Unit))
+	case len(setting.Options) > 0:
+		return fmt.Sprintf(" (%s)", strings.Join(setting.Options, ", "))
 	}
+	return ""
+}
 
-	var cfg map[string]interface{}
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		b.SendMessage(chatID, "❌ Fehler beim Parsen der Konfigurationsdatei.")
+func (h *ConfigHandler) showGroupConfigMenu(b *bot.Bot, tr *bot.Localizer, chatID, targetChatID int64) error {
+	overrides, err := b.GetDB().GetGroupSettings(targetChatID)
+	if err != nil {
+		b.SendMessage(chatID, tr.T("config.group_load_failed"))
 		return err
 	}
 
-	// Config-Wert aktualisieren
-	success := false
-	switch key {
-	case "timeout_minutes":
-		if val, err := strconv.Atoi(value); err == nil && val > 0 {
-			if captcha, ok := cfg["captcha"].(map[string]interface{}); ok {
-				captcha["timeout_minutes"] = val
-				success = true
-			}
-		}
-	case "max_attempts":
-		if val, err := strconv.Atoi(value); err == nil && val > 0 {
-			if captcha, ok := cfg["captcha"].(map[string]interface{}); ok {
-				captcha["max_attempts"] = val
-				success = true
-			}
-		}
-	case "welcome_message":
-		if captcha, ok := cfg["captcha"].(map[string]interface{}); ok {
-			captcha["welcome_message"] = value
-			success = true
-		}
-	case "message_delete_delay_minutes":
-		if val, err := strconv.Atoi(value); err == nil && val > 0 {
-			if captcha, ok := cfg["captcha"].(map[string]interface{}); ok {
-				captcha["message_delete_delay_minutes"] = val
-				success = true
-			}
-		}
-	case "success_message_delete_delay_minutes":
-		if val, err := strconv.Atoi(value); err == nil && val > 0 {
-			if captcha, ok := cfg["captcha"].(map[string]interface{}); ok {
-				captcha["success_message_delete_delay_minutes"] = val
-				success = true
-			}
-		}
-	case "default_mute_hours":
-		if val, err := strconv.Atoi(value); err == nil && val > 0 {
-			if admin, ok := cfg["admin"].(map[string]interface{}); ok {
-				admin["default_mute_hours"] = val
-				success = true
-			}
+	cfg := b.GetChatConfig(targetChatID)
+
+	var sb strings.Builder
+	sb.WriteString(tr.T("config.group_title", i18n.Vars{"chat": targetChatID}) + "\n")
+
+	section := ""
+	for _, setting := range config.Settings {
+		if setting.Section != section {
+			section = setting.Section
+			sb.WriteString("\n" + tr.T(configSectionKey(section)) + "\n")
 		}
-	case "max_delete_messages":
-		if val, err := strconv.Atoi(value); err == nil && val > 0 {
-			if admin, ok := cfg["admin"].(map[string]interface{}); ok {
-				admin["max_delete_messages"] = val
-				success = true
-			}
+
+		value, _ := cfg.GetValue(setting.Key)
+		source := tr.T("config.source_global")
+		if _, ok := overrides[setting.Key]; ok {
+			source = tr.T("config.source_group")
 		}
-	default:
-		b.SendMessage(chatID, fmt.Sprintf("❌ Unbekannter Konfigurationsschlüssel: %s", key))
-		return nil
+		sb.WriteString(fmt.Sprintf("• %s = %s (%s)\n", setting.Key, value, source))
 	}
 
-	if !success {
-		b.SendMessage(chatID, "❌ Ungültiger Wert für den angegebenen Schlüssel.")
-		return nil
-	}
+	sb.WriteString("\n" + tr.T("config.group_usage", i18n.Vars{"chat": targetChatID}))
 
-	// Config speichern
-	newData, err := json.MarshalIndent(cfg, "", "  ")
-	if err != nil {
-		b.SendMessage(chatID, "❌ Fehler beim Erstellen der neuen Konfiguration.")
-		return err
+	_, err = b.SendMessage(chatID, sb.String())
+	return err
+}
+
+func (h *ConfigHandler) updateConfig(b *bot.Bot, tr *bot.Localizer, chatID int64, key, value string) error {
+	// Config-Wert validieren
+	setting, ok := config.LookupSetting(key)
+	if !ok {
+		b.SendMessage(chatID, tr.T("config.unknown_key_message", i18n.Vars{"key": key}))
+		return nil
 	}
 
-	if err := os.WriteFile(configPath, newData, 0644); err != nil {
-		b.SendMessage(chatID, "❌ Fehler beim Speichern der Konfigurationsdatei.")
-		return err
+	if _, err := setting.Parse(value); err != nil {
+		b.SendMessage(chatID, tr.T("config.invalid_value", i18n.Vars{"error": tr.Error(err)}))
+		return nil
 	}
 
-	// Bot-Config neu laden
-	newConfig, err := config.LoadConfig(configPath)
-	if err != nil {
-		b.SendMessage(chatID, "❌ Fehler beim Neuladen der Konfiguration.")
+	// Globale Werte liegen in der Datenbank, die Config-Datei wird nicht geschrieben
+	if err := b.SetGlobalSetting(key, value); err != nil {
+		b.SendMessage(chatID, tr.T("config.save_failed"))
 		return err
 	}
 
-	// Config im Bot aktualisieren
-	*b.GetConfig() = *newConfig
-
-	b.SendMessage(chatID, fmt.Sprintf("✅ Konfiguration erfolgreich aktualisiert!\n%s = %s", key, value))
+	b.SendMessage(chatID, tr.T("config.updated", i18n.Vars{"key": key, "value": value}))
 	return nil
 }
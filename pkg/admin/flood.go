@@ -0,0 +1,164 @@
+package admin
+
+import (
+	"fmt"
+	"sync"
+	"telegramBot/config"
+	"telegramBot/pkg/bot"
+	"telegramBot/pkg/duration"
+	"telegramBot/pkg/i18n"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	defaultFloodWindow = 5 * time.Second
+	floodSweepInterval = time.Minute
+)
+
+type floodKey struct {
+	chatID int64
+	userID int64
+}
+
+// FloodHandler erkennt pro Chat und User zu viele Nachrichten in einem gleitenden Zeitfenster.
+// Die Zeitstempel liegen nur im Speicher, nach einem Neustart beginnt die Zählung neu.
+type FloodHandler struct {
+	mu        sync.Mutex
+	windows   map[floodKey][]time.Time
+	lastSweep time.Time
+}
+
+func NewFloodHandler() *FloodHandler {
+	return &FloodHandler{
+		windows: make(map[floodKey][]time.Time),
+	}
+}
+
+func (h *FloodHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
+	message := update.Message
+	if message == nil || message.From == nil || message.Chat.Type == "private" {
+		return nil
+	}
+
+	cfg := b.GetChatConfig(message.Chat.ID)
+	if cfg.Admin.FloodMaxMessages <= 0 {
+		return nil
+	}
+
+	window := time.Duration(cfg.Admin.FloodWindowSeconds) * time.Second
+	if window <= 0 {
+		window = defaultFloodWindow
+	}
+
+	count := h.record(floodKey{message.Chat.ID, message.From.ID}, message.Time(), window)
+	if count <= cfg.Admin.FloodMaxMessages {
+		return nil
+	}
+
+	// Rechte-Check erst beim Auslösen, damit nicht jede Nachricht eine API-Anfrage kostet.
+	// Wer selbst stummschalten darf, wird vom Flood-Schutz ausgenommen.
+	if b.HasPermission(message.Chat.ID, message.From.ID, bot.PermMute) {
+		return nil
+	}
+
+	return h.punish(b, cfg, message, count, window)
+}
+
+// record speichert eine Nachricht und liefert die Anzahl der Nachrichten im Zeitfenster
+func (h *FloodHandler) record(key floodKey, sent time.Time, window time.Duration) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	if sent.IsZero() || sent.After(now) {
+		sent = now
+	}
+
+	if now.Sub(h.lastSweep) > floodSweepInterval {
+		h.sweep(now, window)
+		h.lastSweep = now
+	}
+
+	timestamps := append(h.windows[key], sent)
+	cutoff := now.Add(-window)
+	start := 0
+	for start < len(timestamps) && timestamps[start].Before(cutoff) {
+		start++
+	}
+	timestamps = timestamps[start:]
+	h.windows[key] = timestamps
+
+	return len(timestamps)
+}
+
+// reset vergisst das Zeitfenster eines Users, z.B. nachdem er bestraft wurde
+func (h *FloodHandler) reset(key floodKey) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.windows, key)
+}
+
+// sweep entfernt User, die seit einem Zeitfenster nichts geschrieben haben
+func (h *FloodHandler) sweep(now time.Time, window time.Duration) {
+	if window < floodSweepInterval {
+		window = floodSweepInterval
+	}
+	for key, timestamps := range h.windows {
+		if len(timestamps) == 0 || now.Sub(timestamps[len(timestamps)-1]) > window {
+			delete(h.windows, key)
+		}
+	}
+}
+
+func (h *FloodHandler) punish(b *bot.Bot, cfg *config.Config, message *tgbotapi.Message, count int, window time.Duration) error {
+	chatID := message.Chat.ID
+	user := message.From
+	action := cfg.Admin.FloodAction
+	if action == "" {
+		action = config.FloodActionDelete
+	}
+
+	b.GetEventLogger().LogEvent("FLOOD", chatID, user.ID, bot.GetUserIdentifier(user),
+		fmt.Sprintf("%d messages in %s, action: %s", count, window, action))
+
+	if err := b.DeleteMessage(chatID, message.MessageID); err != nil {
+		return fmt.Errorf("failed to delete flood message: %w", err)
+	}
+
+	tr := b.ChatLocalizer(chatID)
+
+	var notice string
+	switch action {
+	case config.FloodActionDelete:
+		// Jede weitere Nachricht im Zeitfenster wird gelöscht, ohne den Chat zuzuspammen
+		return nil
+	case config.FloodActionMute:
+		muteDuration := cfg.Admin.ClampMute(cfg.Admin.FloodMute())
+		var until time.Time
+		if muteDuration != duration.Permanent {
+			until = time.Now().Add(muteDuration)
+		}
+		if err := muteUser(b, chatID, user.ID, until, 0, "Flooding"); err != nil {
+			return err
+		}
+		notice = tr.T("flood.muted", i18n.Vars{"user": bot.FormatUserName(user), "duration": duration.Format(muteDuration)})
+	case config.FloodActionKick:
+		if err := kickUser(b, chatID, user.ID, 0, "Flooding"); err != nil {
+			return err
+		}
+		notice = tr.T("flood.kicked", i18n.Vars{"user": bot.FormatUserName(user)})
+	case config.FloodActionBan:
+		if err := banUser(b, chatID, user.ID, time.Time{}, 0, "Flooding"); err != nil {
+			return err
+		}
+		notice = tr.T("flood.banned", i18n.Vars{"user": bot.FormatUserName(user)})
+	default:
+		return fmt.Errorf("unknown flood action: %s", action)
+	}
+
+	h.reset(floodKey{chatID, user.ID})
+	_, _ = b.SendTemporaryGroupMessage(chatID, notice, 10)
+	return nil
+}
@@ -0,0 +1,185 @@
+package admin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"telegramBot/pkg/bot"
+	"telegramBot/pkg/database"
+	"telegramBot/pkg/i18n"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const groupsCallbackPrefix = "groups"
+
+// GroupsHandler listet per DM die Gruppen, die der User verwalten kann, und lässt eine davon
+// als Kontext für spätere DM-Commands wie /config auswählen
+type GroupsHandler struct{}
+
+// GroupsCallbackHandler verarbeitet die Auswahl-Buttons von /groups
+type GroupsCallbackHandler struct{}
+
+func NewGroupsHandler() *GroupsHandler {
+	return &GroupsHandler{}
+}
+
+func NewGroupsCallbackHandler() *GroupsCallbackHandler {
+	return &GroupsCallbackHandler{}
+}
+
+func (h *GroupsHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
+	message := update.Message
+	tr := b.Localizer(message.Chat, message.From)
+
+	if message.Chat.Type != "private" {
+		_, _ = b.SendTemporaryGroupMessage(message.Chat.ID, tr.T("groups.dm_only"), 5)
+		return nil
+	}
+
+	text, keyboard, err := renderGroups(b, tr, message.From.ID)
+	if err != nil {
+		b.SendMessage(message.Chat.ID, tr.T("groups.load_failed"))
+		return err
+	}
+	if keyboard == nil {
+		_, err = b.SendMessage(message.Chat.ID, text)
+		return err
+	}
+	_, err = b.SendMessageWithKeyboard(message.Chat.ID, text, *keyboard)
+	return err
+}
+
+func (h *GroupsCallbackHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
+	callback := update.CallbackQuery
+	if callback == nil || callback.Message == nil {
+		return nil
+	}
+
+	tr := b.UserLocalizer(callback.From)
+	chatID, err := parseGroupsCallbackData(callback.Data)
+	if err != nil {
+		b.GetAPI().Request(tgbotapi.NewCallback(callback.ID, tr.T("common.invalid_request")))
+		return err
+	}
+
+	// Die Rechte könnten seit dem Anzeigen der Liste entzogen worden sein
+	answer := tr.T("groups.cleared")
+	if chatID != 0 {
+		chat, err := b.GetDB().GetKnownChat(chatID)
+		if err != nil {
+			b.GetAPI().Request(tgbotapi.NewCallback(callback.ID, tr.T("groups.load_failed")))
+			return fmt.Errorf("failed to load chat %d: %w", chatID, err)
+		}
+		if chat == nil || !b.CanManageChat(chatID, callback.From.ID) {
+			b.GetAPI().Request(tgbotapi.NewCallback(callback.ID, tr.T("common.no_permission")))
+			return nil
+		}
+		answer = tr.T("groups.selected", i18n.Vars{"title": chatTitle(*chat)})
+	}
+
+	if err := b.GetDB().SetSelectedChat(callback.From.ID, chatID); err != nil {
+		b.GetAPI().Request(tgbotapi.NewCallback(callback.ID, tr.T("groups.save_failed")))
+		return fmt.Errorf("failed to save selected chat: %w", err)
+	}
+
+	text, keyboard, err := renderGroups(b, tr, callback.From.ID)
+	if err != nil {
+		b.GetAPI().Request(tgbotapi.NewCallback(callback.ID, answer))
+		return err
+	}
+	if keyboard == nil {
+		keyboard = &tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}
+	}
+	if err := b.EditMessageWithKeyboard(callback.Message.Chat.ID, callback.Message.MessageID, text, *keyboard); err != nil {
+		b.GetAPI().Request(tgbotapi.NewCallback(callback.ID, answer))
+		return fmt.Errorf("failed to edit group list: %w", err)
+	}
+
+	b.GetAPI().Request(tgbotapi.NewCallback(callback.ID, answer))
+	return nil
+}
+
+// renderGroups baut die Liste der verwaltbaren Gruppen samt Auswahl-Buttons (nil ohne Gruppen)
+func renderGroups(b *bot.Bot, tr *bot.Localizer, userID int64) (string, *tgbotapi.InlineKeyboardMarkup, error) {
+	chats, err := b.ManagedChats(userID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load managed chats: %w", err)
+	}
+	if len(chats) == 0 {
+		return tr.T("groups.empty"), nil, nil
+	}
+
+	selected := b.SelectedChat(userID)
+
+	var sb strings.Builder
+	sb.WriteString(tr.T("groups.title") + "\n\n")
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, chat := range chats {
+		marker := "•"
+		if chat.ChatID == selected {
+			marker = "✅"
+		}
+		sb.WriteString(tr.T("groups.entry", i18n.Vars{"marker": marker, "title": chatTitle(chat), "chat": chat.ChatID}))
+		if chat.BotStatus != "" && chat.BotStatus != "administrator" {
+			sb.WriteString(" " + tr.T("groups.bot_not_admin"))
+		}
+		sb.WriteString("\n")
+
+		label := chatTitle(chat)
+		if chat.ChatID == selected {
+			label = "✅ " + label
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, groupsCallbackData(chat.ChatID)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(tr.T("groups.button_global"), groupsCallbackData(0)),
+	))
+
+	sb.WriteString("\n" + tr.T("groups.hint"))
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return sb.String(), &keyboard, nil
+}
+
+// chatTitle liefert den Titel eines Chats, ohne Titel seine ID
+func chatTitle(chat database.KnownChat) string {
+	if chat.Title == "" {
+		return strconv.FormatInt(chat.ChatID, 10)
+	}
+	return chat.Title
+}
+
+// Callback-Daten: groups:select:<chat_id>, 0 hebt die Auswahl auf
+func groupsCallbackData(chatID int64) string {
+	return fmt.Sprintf("%s:select:%d", groupsCallbackPrefix, chatID)
+}
+
+func parseGroupsCallbackData(data string) (int64, error) {
+	parts := strings.Split(data, ":")
+	if len(parts) != 3 || parts[0] != groupsCallbackPrefix || parts[1] != "select" {
+		return 0, fmt.Errorf("invalid groups callback: %s", data)
+	}
+	chatID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || chatID > 0 {
+		return 0, fmt.Errorf("invalid groups callback: %s", data)
+	}
+	return chatID, nil
+}
+
+// dmTargetChat ermittelt für DM-Commands die Gruppe: eine Gruppen-ID als erstes Argument
+// geht vor der per /groups gewählten Gruppe. Zurück kommen die restlichen Argumente.
+func dmTargetChat(b *bot.Bot, userID int64, args []string) (int64, []string, bool) {
+	if len(args) > 0 {
+		if chatID, err := strconv.ParseInt(args[0], 10, 64); err == nil && chatID < 0 {
+			return chatID, args[1:], true
+		}
+	}
+	if chatID := b.SelectedChat(userID); chatID != 0 {
+		return chatID, args, true
+	}
+	return 0, nil, false
+}
@@ -0,0 +1,360 @@
+package admin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"telegramBot/pkg/bot"
+	"telegramBot/pkg/database"
+	"telegramBot/pkg/i18n"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	modListPageSize       = 10
+	modListCallbackPrefix = "modlist"
+)
+
+// Listenarten für /banlist, /mutelist und /history
+const (
+	modListBans    = "bans"
+	modListMutes   = "mutes"
+	modListHistory = "history"
+)
+
+// actionLabels ordnet den Aktionen ihre Katalog-Schlüssel zu
+var actionLabels = map[string]string{
+	database.ActionBan:  "modlist.action_ban",
+	database.ActionMute: "modlist.action_mute",
+	database.ActionKick: "modlist.action_kick",
+	database.ActionWarn: "modlist.action_warn",
+}
+
+// modListPermissions ordnet jeder Liste das Recht zu, das sie verlangt
+var modListPermissions = map[string]string{
+	modListBans:    bot.PermBan,
+	modListMutes:   bot.PermMute,
+	modListHistory: bot.PermWarn,
+}
+
+// modList beschreibt eine seitenweise abrufbare Liste aus moderation_actions
+type modList struct {
+	kind   string
+	chatID int64
+	userID int64 // nur für history
+}
+
+type BanListHandler struct{}
+type MuteListHandler struct{}
+type HistoryHandler struct{}
+type ModListCallbackHandler struct{}
+
+func NewBanListHandler() *BanListHandler {
+	return &BanListHandler{}
+}
+
+func NewMuteListHandler() *MuteListHandler {
+	return &MuteListHandler{}
+}
+
+func NewHistoryHandler() *HistoryHandler {
+	return &HistoryHandler{}
+}
+
+func NewModListCallbackHandler() *ModListCallbackHandler {
+	return &ModListCallbackHandler{}
+}
+
+func (h *BanListHandler) RequiredPermission(b *bot.Bot, update tgbotapi.Update) (int64, string) {
+	return modListPermission(b, update, modListBans)
+}
+
+func (h *MuteListHandler) RequiredPermission(b *bot.Bot, update tgbotapi.Update) (int64, string) {
+	return modListPermission(b, update, modListMutes)
+}
+
+func (h *HistoryHandler) RequiredPermission(b *bot.Bot, update tgbotapi.Update) (int64, string) {
+	return modListPermission(b, update, modListHistory)
+}
+
+// modListPermission verlangt das Recht der Liste in der Gruppe, per DM in der angegebenen
+// oder per /groups gewählten Gruppe. Ohne Gruppe wird nichts geprüft, der Handler zeigt dann die Hilfe.
+func modListPermission(b *bot.Bot, update tgbotapi.Update, kind string) (int64, string) {
+	message := update.Message
+	if message == nil {
+		return 0, ""
+	}
+
+	chatID := message.Chat.ID
+	if message.Chat.Type == "private" {
+		id, _, ok := dmTargetChat(b, message.From.ID, strings.Fields(message.CommandArguments()))
+		if !ok {
+			return 0, ""
+		}
+		chatID = id
+	}
+	return chatID, modListPermissions[kind]
+}
+
+func (h *BanListHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
+	return handleModListCommand(b, update, modListBans)
+}
+
+func (h *MuteListHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
+	return handleModListCommand(b, update, modListMutes)
+}
+
+func (h *HistoryHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
+	return handleModListCommand(b, update, modListHistory)
+}
+
+// handleModListCommand funktioniert in der Gruppe (/banlist, /history @user) und per DM
+// mit Gruppen-ID (/banlist -100123, /history -100123 42) oder der per /groups gewählten Gruppe.
+// Die Liste kommt immer per DM.
+func handleModListCommand(b *bot.Bot, update tgbotapi.Update, kind string) error {
+	message := update.Message
+	inGroup := message.Chat.Type != "private"
+	list := modList{kind: kind, chatID: message.Chat.ID}
+	tr := b.Localizer(message.Chat, message.From)
+	dm := b.UserLocalizer(message.From)
+
+	reply := func(text string) {
+		if inGroup {
+			_, _ = b.SendTemporaryGroupMessage(message.Chat.ID, text, 5)
+		} else {
+			_, _ = b.SendMessage(message.Chat.ID, text)
+		}
+	}
+
+	args := strings.Fields(message.CommandArguments())
+	if !inGroup {
+		chatID, rest, ok := dmTargetChat(b, message.From.ID, args)
+		if !ok {
+			reply(tr.T(modListUsage(kind)))
+			return nil
+		}
+		list.chatID = chatID
+		args = rest
+	}
+
+	if kind == modListHistory {
+		var target *tgbotapi.User
+		var err error
+		switch {
+		case inGroup:
+			target, err = extractTargetUser(b, message)
+		case len(args) > 0:
+			target, err = parseUserFromArgs(b, list.chatID, args[0])
+		default:
+			err = i18n.NewError(modListUsage(kind))
+		}
+		if err != nil {
+			reply(tr.Error(err))
+			return nil
+		}
+		list.userID = target.ID
+	}
+
+	text, keyboard, err := renderModList(b, dm, list, 0)
+	if err != nil {
+		reply(tr.T("modlist.load_failed"))
+		return err
+	}
+
+	if keyboard != nil {
+		_, err = b.SendMessageWithKeyboard(message.From.ID, text, *keyboard)
+	} else {
+		_, err = b.SendMessage(message.From.ID, text)
+	}
+	if err != nil {
+		reply(tr.T("common.dm_failed"))
+		return nil
+	}
+
+	if inGroup {
+		reply(tr.T("modlist.sent_dm"))
+	}
+	return nil
+}
+
+// modListUsage liefert den Katalog-Schlüssel des Hilfetexts
+func modListUsage(kind string) string {
+	switch kind {
+	case modListBans:
+		return "modlist.usage_bans"
+	case modListMutes:
+		return "modlist.usage_mutes"
+	default:
+		return "modlist.usage_history"
+	}
+}
+
+// renderModList baut eine Seite der Liste samt Blätter-Buttons (nil, wenn alles auf eine Seite passt)
+func renderModList(b *bot.Bot, tr *bot.Localizer, list modList, page int) (string, *tgbotapi.InlineKeyboardMarkup, error) {
+	offset := page * modListPageSize
+
+	var actions []database.ModerationAction
+	var total int
+	var err error
+	var title string
+
+	switch list.kind {
+	case modListBans:
+		actions, total, err = b.GetDB().GetActiveModerationActions(list.chatID, database.ActionBan, time.Now(), modListPageSize, offset)
+		title = tr.T("modlist.title_bans", i18n.Vars{"chat": list.chatID})
+	case modListMutes:
+		actions, total, err = b.GetDB().GetActiveModerationActions(list.chatID, database.ActionMute, time.Now(), modListPageSize, offset)
+		title = tr.T("modlist.title_mutes", i18n.Vars{"chat": list.chatID})
+	case modListHistory:
+		actions, total, err = b.GetDB().GetModerationHistory(list.chatID, list.userID, modListPageSize, offset)
+		title = tr.T("modlist.title_history", i18n.Vars{"user": list.userID, "chat": list.chatID})
+	default:
+		return "", nil, fmt.Errorf("unknown moderation list: %s", list.kind)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load moderation actions: %w", err)
+	}
+
+	pages := (total + modListPageSize - 1) / modListPageSize
+	if pages == 0 {
+		pages = 1
+	}
+
+	var sb strings.Builder
+	sb.WriteString(title + "\n" + tr.T("modlist.page", i18n.Vars{"page": page + 1, "pages": pages, "total": total}) + "\n\n")
+
+	if total == 0 {
+		sb.WriteString(tr.T("modlist.empty"))
+	}
+	for _, action := range actions {
+		sb.WriteString(formatModAction(tr, action, list.kind == modListHistory))
+		sb.WriteString("\n")
+	}
+
+	if pages == 1 {
+		return sb.String(), nil, nil
+	}
+
+	var row []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(tr.T("common.back"), modListCallbackData(list, page-1)))
+	}
+	if page < pages-1 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(tr.T("common.next"), modListCallbackData(list, page+1)))
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(row)
+
+	return sb.String(), &keyboard, nil
+}
+
+func formatModAction(tr *bot.Localizer, action database.ModerationAction, history bool) string {
+	var sb strings.Builder
+	if history {
+		label := action.Action
+		if key, ok := actionLabels[action.Action]; ok {
+			label = tr.T(key)
+		}
+		sb.WriteString(tr.T("modlist.entry_history", i18n.Vars{"action": label, "time": tr.FormatTime(action.CreatedAt)}))
+	} else {
+		sb.WriteString(tr.T("modlist.entry_active", i18n.Vars{"user": action.UserID, "time": tr.FormatTime(action.CreatedAt)}))
+	}
+
+	switch {
+	case !action.ExpiresAt.IsZero():
+		sb.WriteString(tr.T("modlist.until", i18n.Vars{"time": tr.FormatTime(action.ExpiresAt)}))
+	case action.Action == database.ActionBan || action.Action == database.ActionMute:
+		sb.WriteString(tr.T("modlist.unlimited"))
+	}
+
+	admin := "Bot"
+	if action.AdminID != 0 {
+		admin = strconv.FormatInt(action.AdminID, 10)
+	}
+	reason := action.Reason
+	if reason == "" {
+		reason = tr.T("common.no_reason")
+	}
+	sb.WriteString("\n  " + tr.T("modlist.details", i18n.Vars{"reason": reason, "admin": admin}))
+
+	if history {
+		switch {
+		case !action.RevokedAt.IsZero():
+			sb.WriteString("\n  " + tr.T("modlist.revoked", i18n.Vars{"admin": action.RevokedBy, "time": tr.FormatTime(action.RevokedAt)}))
+		case !action.ExpiresAt.IsZero() && time.Now().After(action.ExpiresAt):
+			sb.WriteString("\n  " + tr.T("modlist.expired"))
+		}
+	}
+
+	return sb.String()
+}
+
+// Callback-Daten: modlist:<art>:<chat_id>:<user_id>:<seite>
+func modListCallbackData(list modList, page int) string {
+	return fmt.Sprintf("%s:%s:%d:%d:%d", modListCallbackPrefix, list.kind, list.chatID, list.userID, page)
+}
+
+func parseModListCallbackData(data string) (modList, int, error) {
+	parts := strings.Split(data, ":")
+	if len(parts) != 5 || parts[0] != modListCallbackPrefix {
+		return modList{}, 0, fmt.Errorf("invalid moderation list callback: %s", data)
+	}
+	if _, ok := modListPermissions[parts[1]]; !ok {
+		return modList{}, 0, fmt.Errorf("unknown moderation list: %s", parts[1])
+	}
+
+	chatID, err1 := strconv.ParseInt(parts[2], 10, 64)
+	userID, err2 := strconv.ParseInt(parts[3], 10, 64)
+	page, err3 := strconv.Atoi(parts[4])
+	if err1 != nil || err2 != nil || err3 != nil || page < 0 {
+		return modList{}, 0, fmt.Errorf("invalid moderation list callback: %s", data)
+	}
+
+	return modList{kind: parts[1], chatID: chatID, userID: userID}, page, nil
+}
+
+// RequiredPermission prüft bei jedem Blättern erneut, der User könnte das Recht inzwischen verloren haben
+func (h *ModListCallbackHandler) RequiredPermission(b *bot.Bot, update tgbotapi.Update) (int64, string) {
+	if update.CallbackQuery == nil {
+		return 0, ""
+	}
+	list, _, err := parseModListCallbackData(update.CallbackQuery.Data)
+	if err != nil {
+		return 0, ""
+	}
+	return list.chatID, modListPermissions[list.kind]
+}
+
+func (h *ModListCallbackHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
+	callback := update.CallbackQuery
+	if callback == nil || callback.Message == nil {
+		return nil
+	}
+
+	tr := b.UserLocalizer(callback.From)
+	list, page, err := parseModListCallbackData(callback.Data)
+	if err != nil {
+		b.GetAPI().Request(tgbotapi.NewCallback(callback.ID, tr.T("common.invalid_request")))
+		return err
+	}
+
+	text, keyboard, err := renderModList(b, tr, list, page)
+	if err != nil {
+		b.GetAPI().Request(tgbotapi.NewCallback(callback.ID, tr.T("modlist.load_failed")))
+		return err
+	}
+
+	// Passt die Liste inzwischen auf eine Seite, werden die Buttons entfernt
+	if keyboard == nil {
+		keyboard = &tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}
+	}
+
+	if err := b.EditMessageWithKeyboard(callback.Message.Chat.ID, callback.Message.MessageID, text, *keyboard); err != nil {
+		b.GetAPI().Request(tgbotapi.NewCallback(callback.ID, tr.T("modlist.update_failed")))
+		return fmt.Errorf("failed to edit moderation list: %w", err)
+	}
+
+	b.GetAPI().Request(tgbotapi.NewCallback(callback.ID, ""))
+	return nil
+}
@@ -2,6 +2,7 @@ package admin
 
 import (
 	"telegramBot/pkg/bot"
+	"telegramBot/pkg/i18n"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -12,29 +13,26 @@ func NewPermissionsHandler() *PermissionsHandler {
 	return &PermissionsHandler{}
 }
 
+func (h *PermissionsHandler) RequiredPermission(b *bot.Bot, update tgbotapi.Update) (int64, string) {
+	return groupPermission(update, bot.PermConfig)
+}
+
 func (h *PermissionsHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
 	if update.Message.Chat.Type == "private" {
 		return nil
 	}
 
-	isAdmin, err := b.IsUserAdmin(update.Message.Chat.ID, update.Message.From.ID)
-	if err != nil {
-		return err
-	}
-
-	if !isAdmin {
-		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, "Du hast keine Berechtigung für diesen Befehl.", 5)
-		return nil
-	}
+	tr := b.ChatLocalizer(update.Message.Chat.ID)
 
-	hasPerms, status, err := b.CheckRequiredPermissions(update.Message.Chat.ID)
+	dm := b.UserLocalizer(update.Message.From)
+	hasPerms, status, err := b.CheckRequiredPermissions(update.Message.Chat.ID, dm)
 	if err != nil {
-		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, "Fehler beim Überprüfen der Bot-Rechte: "+err.Error(), 5)
+		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, tr.T("permissions.check_failed", i18n.Vars{"error": err.Error()}), 5)
 		return err
 	}
 
-	if err := h.sendPermissionsDM(b, update.Message.From.ID, status, hasPerms); err != nil {
-		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, "Ich konnte dir keine private Nachricht senden. Starte zuerst eine Unterhaltung mit mir.", 5)
+	if err := h.sendPermissionsDM(b, dm, update.Message.From.ID, status, hasPerms); err != nil {
+		_, _ = b.SendTemporaryGroupMessage(update.Message.Chat.ID, tr.T("common.dm_failed"), 5)
 		return nil
 	}
 
@@ -42,19 +40,13 @@ func (h *PermissionsHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
 	return nil
 }
 
-func (h *PermissionsHandler) sendPermissionsDM(b *bot.Bot, userID int64, status string, hasAllPerms bool) error {
+func (h *PermissionsHandler) sendPermissionsDM(b *bot.Bot, tr *bot.Localizer, userID int64, status string, hasAllPerms bool) error {
 	message := status + "\n\n"
 
 	if !hasAllPerms {
-		message += "WARNUNG: Dem Bot fehlen wichtige Berechtigungen!\n\n"
-		message += "So aktivierst du die Berechtigungen:\n"
-		message += "1. Gehe zu den Gruppeneinstellungen\n"
-		message += "2. Waehle 'Administratoren'\n"
-		message += "3. Waehle den Bot aus\n"
-		message += "4. Aktiviere die fehlenden Rechte\n\n"
-		message += "Ohne diese Rechte funktionieren Commands wie /ban, /kick und /mute nicht!"
+		message += tr.T("permissions.warning")
 	} else {
-		message += "Der Bot ist korrekt konfiguriert und einsatzbereit."
+		message += tr.T("permissions.ready")
 	}
 
 	_, err := b.SendMessage(userID, message)
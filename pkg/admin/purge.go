@@ -0,0 +1,177 @@
+package admin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"telegramBot/pkg/bot"
+	"telegramBot/pkg/duration"
+	"telegramBot/pkg/i18n"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// PurgeHandler löscht die letzten Nachrichten eines Users:
+//
+//	/purge @user [Anzahl]
+//	/purge [Anzahl] (als Antwort auf eine Nachricht des Users)
+type PurgeHandler struct{}
+
+func NewPurgeHandler() *PurgeHandler {
+	return &PurgeHandler{}
+}
+
+func (h *PurgeHandler) RequiredPermission(b *bot.Bot, update tgbotapi.Update) (int64, string) {
+	return groupPermission(update, bot.PermDelete)
+}
+
+func (h *PurgeHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
+	message := update.Message
+	if message.Chat.Type == "private" {
+		return nil
+	}
+
+	chatID := message.Chat.ID
+	tr := b.ChatLocalizer(chatID)
+
+	targetUser, rest, err := extractTargetUserAndReason(b, message)
+	if err != nil {
+		_, _ = b.SendTemporaryGroupMessage(chatID, tr.Error(err), 5)
+		return nil
+	}
+
+	// Ohne Anzahl werden alle bekannten Nachrichten des Users gelöscht (bis max_delete_messages)
+	maxDelete := b.GetChatConfig(chatID).Admin.MaxDeleteMessages
+	count := maxDelete
+	if rest != "" {
+		count, err = strconv.Atoi(rest)
+		if err != nil {
+			_, _ = b.SendTemporaryGroupMessage(chatID, tr.T("del.invalid_count"), 5)
+			return nil
+		}
+		if count < 1 || count > maxDelete {
+			_, _ = b.SendTemporaryGroupMessage(chatID, tr.T("del.count_range", i18n.Vars{"max": maxDelete}), 5)
+			return nil
+		}
+	}
+
+	var selected []int
+	for _, indexed := range b.MessageIndex().Messages(chatID) {
+		if indexed.UserID == targetUser.ID && indexed.ID != message.MessageID {
+			selected = append(selected, indexed.ID)
+		}
+	}
+	selected = newest(selected, count)
+
+	deleted, err := deleteSelected(b, message, selected)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		_, _ = b.SendTemporaryGroupMessage(chatID, tr.T("del.none"), 5)
+		return nil
+	}
+
+	_, _ = b.SendTemporaryGroupMessage(chatID, tr.T("purge.success", i18n.Vars{
+		"count": deleted,
+		"user":  bot.FormatUserName(targetUser),
+		"admin": bot.GetUserMention(message.From),
+	}), 5)
+	return nil
+}
+
+// selectForDelete wählt die Nachrichten für /del aus dem Nachrichten-Index:
+//
+//	/del <Anzahl>                  - die letzten N Nachrichten vor dem Command
+//	/del from-reply (als Antwort)  - die beantwortete Nachricht und alles danach
+//	/del since <Dauer>             - alles aus der angegebenen Zeitspanne, z.B. 10m
+//
+// Ein Fehler ist ein i18n.Error für den Admin.
+func selectForDelete(b *bot.Bot, message *tgbotapi.Message) ([]int, error) {
+	chatID := message.Chat.ID
+	maxDelete := b.GetChatConfig(chatID).Admin.MaxDeleteMessages
+	args := strings.Fields(message.CommandArguments())
+	if len(args) == 0 {
+		return nil, i18n.NewError("del.usage")
+	}
+
+	// Nur Nachrichten vor dem Command, spätere gehören nicht zum Auftrag
+	var before []bot.IndexedMessage
+	for _, indexed := range b.MessageIndex().Messages(chatID) {
+		if indexed.ID < message.MessageID {
+			before = append(before, indexed)
+		}
+	}
+
+	var selected []int
+	switch strings.ToLower(args[0]) {
+	case "from-reply":
+		if message.ReplyToMessage == nil {
+			return nil, i18n.NewError("del.reply_required")
+		}
+		for _, indexed := range before {
+			if indexed.ID >= message.ReplyToMessage.MessageID {
+				selected = append(selected, indexed.ID)
+			}
+		}
+		// Die beantwortete Nachricht selbst auch dann löschen, wenn sie vor dem Index liegt
+		if len(selected) == 0 || selected[0] != message.ReplyToMessage.MessageID {
+			selected = append([]int{message.ReplyToMessage.MessageID}, selected...)
+		}
+
+	case "since":
+		if len(args) < 2 {
+			return nil, i18n.NewError("del.usage")
+		}
+		span, err := duration.Parse(args[1])
+		if err != nil {
+			return nil, err
+		}
+		if span == duration.Permanent {
+			return nil, i18n.NewError("del.since_permanent")
+		}
+		cutoff := message.Time().Add(-span)
+		for _, indexed := range before {
+			if !indexed.Date.Before(cutoff) {
+				selected = append(selected, indexed.ID)
+			}
+		}
+
+	default:
+		count, err := strconv.Atoi(args[0])
+		if err != nil {
+			return nil, i18n.NewError("del.invalid_count")
+		}
+		if count < 1 || count > maxDelete {
+			return nil, i18n.NewError("del.count_range", i18n.Vars{"max": maxDelete})
+		}
+		for _, indexed := range before {
+			selected = append(selected, indexed.ID)
+		}
+		return newest(selected, count), nil
+	}
+
+	return newest(selected, maxDelete), nil
+}
+
+// newest behält die neuesten n IDs einer aufsteigend sortierten Liste
+func newest(ids []int, n int) []int {
+	if len(ids) > n {
+		return ids[len(ids)-n:]
+	}
+	return ids
+}
+
+// deleteSelected löscht die ausgewählten Nachrichten zusammen mit dem Command in einem Auftrag
+// und liefert die Anzahl der gelöschten Nachrichten ohne den Command
+func deleteSelected(b *bot.Bot, command *tgbotapi.Message, selected []int) (int, error) {
+	if len(selected) == 0 {
+		b.DeleteMessage(command.Chat.ID, command.MessageID)
+		return 0, nil
+	}
+
+	if _, err := b.DeleteMessages(command.Chat.ID, append(selected, command.MessageID)); err != nil {
+		return 0, fmt.Errorf("failed to delete %d messages: %w", len(selected), err)
+	}
+	return len(selected), nil
+}
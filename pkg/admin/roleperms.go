@@ -0,0 +1,165 @@
+package admin
+
+import (
+	"fmt"
+	"strings"
+	"telegramBot/pkg/bot"
+	"telegramBot/pkg/database"
+	"telegramBot/pkg/i18n"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// RolePermsHandler zeigt und ändert die Rechte der Rollen, global oder je Gruppe:
+//
+//	/roleperms <gruppen_id|global>                            - Rechte aller Rollen anzeigen
+//	/roleperms <gruppen_id|global> <rolle> <recht,...|none>   - Rechte einer Rolle festlegen
+//	/roleperms <gruppen_id|global> <rolle> reset              - wieder die übergeordneten Rechte
+//
+// Die Rechte einer Gruppe gehen vor den globalen, die vor dem Standard.
+type RolePermsHandler struct{}
+
+func NewRolePermsHandler() *RolePermsHandler {
+	return &RolePermsHandler{}
+}
+
+// RequiredPermission verlangt manage_admins in der Gruppe, um deren Rollen es geht
+func (h *RolePermsHandler) RequiredPermission(b *bot.Bot, update tgbotapi.Update) (int64, string) {
+	if update.Message == nil || update.Message.Chat.Type != "private" {
+		return 0, ""
+	}
+	fields := strings.Fields(update.Message.CommandArguments())
+	if len(fields) == 0 {
+		return 0, ""
+	}
+	chatID, ok := parseScope(fields[0])
+	if !ok {
+		return 0, ""
+	}
+	return chatID, bot.PermManageAdmins
+}
+
+func (h *RolePermsHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
+	message := update.Message
+	tr := b.Localizer(message.Chat, message.From)
+
+	if message.Chat.Type != "private" {
+		_, _ = b.SendTemporaryGroupMessage(message.Chat.ID, tr.T("roleperms.dm_only"), 5)
+		return nil
+	}
+
+	usage := tr.T("roleperms.usage", i18n.Vars{
+		"roles":       strings.Join(append(append([]string{}, bot.BotRoles...), bot.TelegramRoles...), ", "),
+		"permissions": strings.Join(bot.Permissions, ", "),
+	})
+
+	fields := strings.Fields(message.CommandArguments())
+	if len(fields) == 0 || len(fields) == 2 {
+		_, err := b.SendMessage(message.Chat.ID, usage)
+		return err
+	}
+	chatID, ok := parseScope(fields[0])
+	if !ok {
+		_, err := b.SendMessage(message.Chat.ID, usage)
+		return err
+	}
+
+	if len(fields) == 1 {
+		return h.show(b, tr, message.Chat.ID, chatID)
+	}
+
+	role := strings.ToLower(fields[1])
+	scope := templateScope(tr, chatID)
+
+	if len(fields) == 3 && fields[2] == "reset" {
+		removed, err := b.ResetRolePermissions(chatID, role)
+		if err != nil {
+			return h.fail(b, tr, message.Chat.ID, err)
+		}
+		key := "roleperms.reset"
+		if !removed {
+			key = "roleperms.not_set"
+		}
+		_, err = b.SendMessage(message.Chat.ID, tr.T(key, i18n.Vars{"role": role, "scope": scope}))
+		return err
+	}
+
+	// Rechte dürfen mit Komma oder Leerzeichen getrennt werden, "none" entzieht alle
+	var permissions []string
+	for _, field := range fields[2:] {
+		for _, permission := range strings.Split(strings.ToLower(field), ",") {
+			if permission != "" && permission != "none" {
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+
+	if err := b.SetRolePermissions(chatID, role, permissions); err != nil {
+		return h.fail(b, tr, message.Chat.ID, err)
+	}
+
+	_, err := b.SendMessage(message.Chat.ID, tr.T("roleperms.updated", i18n.Vars{
+		"role":        role,
+		"scope":       scope,
+		"permissions": formatPermissions(tr, permissions),
+	}))
+	return err
+}
+
+// fail meldet Eingabefehler wie eine unbekannte Rolle und alle anderen als Speicherfehler
+func (h *RolePermsHandler) fail(b *bot.Bot, tr *bot.Localizer, replyTo int64, err error) error {
+	if isUserError(err) {
+		_, err := b.SendMessage(replyTo, tr.Error(err))
+		return err
+	}
+	b.SendMessage(replyTo, tr.T("roleperms.save_failed"))
+	return fmt.Errorf("failed to save role permissions: %w", err)
+}
+
+func (h *RolePermsHandler) show(b *bot.Bot, tr *bot.Localizer, replyTo, chatID int64) error {
+	global, err := b.GetDB().GetRolePermissions(0)
+	if err != nil {
+		b.SendMessage(replyTo, tr.T("roleperms.load_failed"))
+		return fmt.Errorf("failed to load role permissions: %w", err)
+	}
+	group := map[string][]string{}
+	if chatID != 0 {
+		if group, err = b.GetDB().GetRolePermissions(chatID); err != nil {
+			b.SendMessage(replyTo, tr.T("roleperms.load_failed"))
+			return fmt.Errorf("failed to load role permissions: %w", err)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(tr.T("roleperms.title", i18n.Vars{"scope": templateScope(tr, chatID)}) + "\n\n")
+	for _, roles := range [][]string{bot.BotRoles, bot.TelegramRoles} {
+		for _, role := range roles {
+			source := "roleperms.source_default"
+			switch {
+			case role == database.RoleOwner:
+				source = "roleperms.source_fixed"
+			case group[role] != nil:
+				source = "roleperms.source_group"
+			case global[role] != nil:
+				source = "roleperms.source_global"
+			}
+			sb.WriteString(tr.T("roleperms.entry", i18n.Vars{
+				"role":        role,
+				"permissions": formatPermissions(tr, b.RolePermissions(chatID, role)),
+				"source":      tr.T(source),
+			}) + "\n")
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString(tr.T("roleperms.hint"))
+
+	_, err = b.SendMessage(replyTo, sb.String())
+	return err
+}
+
+func formatPermissions(tr *bot.Localizer, permissions []string) string {
+	if len(permissions) == 0 {
+		return tr.T("roleperms.none")
+	}
+	return strings.Join(permissions, ", ")
+}
@@ -0,0 +1,172 @@
+package admin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"telegramBot/pkg/bot"
+	"telegramBot/pkg/i18n"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// rulesMessageSeconds bestimmt, wie lange per /rules in der Gruppe gezeigte Regeln stehen bleiben
+const rulesMessageSeconds = 300
+
+// SetRulesHandler setzt die Regeln einer Gruppe:
+//
+//	/setrules <text>          - Text samt Formatierung übernehmen
+//	/setrules (als Antwort)   - die beantwortete Nachricht übernehmen
+//	/setrules reset           - Regeln entfernen
+type SetRulesHandler struct{}
+
+func NewSetRulesHandler() *SetRulesHandler {
+	return &SetRulesHandler{}
+}
+
+func (h *SetRulesHandler) RequiredPermission(b *bot.Bot, update tgbotapi.Update) (int64, string) {
+	return groupPermission(update, bot.PermConfig)
+}
+
+func (h *SetRulesHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
+	message := update.Message
+	chatID := message.Chat.ID
+	tr := b.Localizer(message.Chat, message.From)
+
+	if message.Chat.Type == "private" {
+		_, err := b.SendMessage(chatID, tr.T("rules.group_only"))
+		return err
+	}
+
+	args := strings.TrimSpace(message.CommandArguments())
+	if args == "reset" {
+		removed, err := b.GetDB().RemoveGroupRules(chatID)
+		if err != nil {
+			_, _ = b.SendTemporaryGroupMessage(chatID, tr.T("rules.save_failed"), 10)
+			return fmt.Errorf("failed to remove rules: %w", err)
+		}
+		key := "rules.removed"
+		if !removed {
+			key = "rules.none"
+		}
+		_, err = b.SendTemporaryGroupMessage(chatID, tr.T(key), 10)
+		return err
+	}
+
+	// Regeln werden als HTML gespeichert, damit die Formatierung der Nachricht erhalten bleibt
+	var rules string
+	switch {
+	case message.ReplyToMessage != nil:
+		rules = strings.TrimSpace(bot.MessageHTML(message.ReplyToMessage))
+		if rules == "" {
+			_, _ = b.SendTemporaryGroupMessage(chatID, tr.T("rules.empty"), 10)
+			return nil
+		}
+	case args != "":
+		rules = bot.CommandArgumentsHTML(message)
+	default:
+		_, _ = b.SendTemporaryGroupMessage(chatID, tr.T("rules.usage"), 30)
+		return nil
+	}
+
+	if err := b.GetDB().SetGroupRules(chatID, rules, message.From.ID); err != nil {
+		_, _ = b.SendTemporaryGroupMessage(chatID, tr.T("rules.save_failed"), 10)
+		return fmt.Errorf("failed to save rules: %w", err)
+	}
+
+	_, err := b.SendTemporaryGroupMessage(chatID, tr.T("rules.saved"), 10)
+	return err
+}
+
+// RulesHandler zeigt die Regeln einer Gruppe, in der Gruppe selbst oder per DM mit /rules <gruppen_id>
+type RulesHandler struct{}
+
+func NewRulesHandler() *RulesHandler {
+	return &RulesHandler{}
+}
+
+func (h *RulesHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
+	message := update.Message
+	tr := b.Localizer(message.Chat, message.From)
+
+	if message.Chat.Type == "private" {
+		targetChatID, _, ok := parseTargetChat(strings.TrimSpace(message.CommandArguments()))
+		if !ok {
+			_, err := b.SendMessage(message.Chat.ID, tr.T("rules.usage_dm"))
+			return err
+		}
+		return showRulesDM(b, tr, message, targetChatID)
+	}
+
+	rules, err := b.GetDB().GetGroupRules(message.Chat.ID)
+	if err != nil {
+		_, _ = b.SendTemporaryGroupMessage(message.Chat.ID, tr.T("rules.load_failed"), 10)
+		return fmt.Errorf("failed to load rules: %w", err)
+	}
+	if rules == "" {
+		_, err := b.SendTemporaryGroupMessage(message.Chat.ID, tr.T("rules.none"), 10)
+		return err
+	}
+
+	sent, err := b.SendHTMLMessage(message.Chat.ID, rulesTitle(tr, message.Chat.Title)+"\n\n"+rules, nil)
+	if err == nil {
+		b.ScheduleMessageDeletion(message.Chat.ID, sent.MessageID, rulesMessageSeconds*time.Second)
+	}
+	return err
+}
+
+// StartHandler beantwortet /start im privaten Chat. Der Deep-Link aus {rules_link}
+// (/start rules_<gruppen_id>) zeigt die Regeln, sonst weist der Bootstrap ggf. auf /claim hin.
+type StartHandler struct{}
+
+func NewStartHandler() *StartHandler {
+	return &StartHandler{}
+}
+
+func (h *StartHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
+	message := update.Message
+	if message.Chat.Type != "private" {
+		return nil
+	}
+
+	payload := strings.TrimSpace(message.CommandArguments())
+	if strings.HasPrefix(payload, bot.RulesStartPrefix) {
+		chatID, err := strconv.ParseInt(strings.TrimPrefix(payload, bot.RulesStartPrefix), 10, 64)
+		if err == nil && chatID < 0 {
+			return showRulesDM(b, b.UserLocalizer(message.From), message, chatID)
+		}
+	}
+
+	return NewBootstrapHandler().Handle(b, update)
+}
+
+// showRulesDM schickt die Regeln einer Gruppe per DM, ggf. mit dem Zeitpunkt der Zustimmung
+func showRulesDM(b *bot.Bot, tr *bot.Localizer, message *tgbotapi.Message, chatID int64) error {
+	rules, err := b.GetDB().GetGroupRules(chatID)
+	if err != nil {
+		_, _ = b.SendMessage(message.Chat.ID, tr.T("rules.load_failed"))
+		return fmt.Errorf("failed to load rules: %w", err)
+	}
+	if rules == "" {
+		_, err := b.SendMessage(message.Chat.ID, tr.T("rules.none"))
+		return err
+	}
+
+	title := strconv.FormatInt(chatID, 10)
+	if chat, err := b.GetAPI().GetChat(tgbotapi.ChatInfoConfig{ChatConfig: tgbotapi.ChatConfig{ChatID: chatID}}); err == nil && chat.Title != "" {
+		title = chat.Title
+	}
+
+	text := rulesTitle(tr, title) + "\n\n" + rules
+	if acceptedAt, err := b.GetDB().GetRulesAcceptance(chatID, message.From.ID); err == nil && !acceptedAt.IsZero() {
+		text += "\n\n" + bot.EscapeHTML(tr.T("rules.accepted_at", i18n.Vars{"time": tr.FormatTime(acceptedAt)}))
+	}
+
+	_, err = b.SendHTMLMessage(message.Chat.ID, text, nil)
+	return err
+}
+
+func rulesTitle(tr *bot.Localizer, chatTitle string) string {
+	return bot.EscapeHTML(tr.T("rules.title", i18n.Vars{"chat": chatTitle}))
+}
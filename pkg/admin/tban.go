@@ -0,0 +1,181 @@
+package admin
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"telegramBot/pkg/bot"
+	"telegramBot/pkg/database"
+	"telegramBot/pkg/i18n"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+type TempBanHandler struct {
+	ban BanHandler
+}
+type UnbanHandler struct{}
+type TempBansHandler struct{}
+
+func NewTempBanHandler() *TempBanHandler {
+	return &TempBanHandler{}
+}
+
+func NewUnbanHandler() *UnbanHandler {
+	return &UnbanHandler{}
+}
+
+func NewTempBansHandler() *TempBansHandler {
+	return &TempBansHandler{}
+}
+
+func (h *TempBanHandler) RequiredPermission(b *bot.Bot, update tgbotapi.Update) (int64, string) {
+	return groupPermission(update, bot.PermBan)
+}
+
+func (h *TempBanHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
+	return h.ban.handleBan(b, update, true)
+}
+
+// parseTempBanArgs liest /tban @user <Dauer> [Grund] bzw. /tban <Dauer> [Grund] als Antwort
+func parseTempBanArgs(b *bot.Bot, message *tgbotapi.Message) (*tgbotapi.User, time.Duration, string, error) {
+	usage := i18n.NewError("tban.usage")
+	args := strings.Fields(message.CommandArguments())
+
+	var targetUser *tgbotapi.User
+	if message.ReplyToMessage != nil && message.ReplyToMessage.From != nil {
+		targetUser = message.ReplyToMessage.From
+	} else {
+		if len(args) < 1 {
+			return nil, 0, "", usage
+		}
+
+		var err error
+		targetUser, err = parseUserFromArgs(b, message.Chat.ID, args[0])
+		if err != nil {
+			return nil, 0, "", err
+		}
+		args = args[1:]
+	}
+
+	if len(args) < 1 {
+		return nil, 0, "", usage
+	}
+
+	min, max := b.GetChatConfig(message.Chat.ID).Admin.BanLimits()
+	banDuration, err := bot.ParseDuration(args[0], min, max)
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	if targetUser.ID == 0 {
+		return nil, 0, "", i18n.NewError("user.reply_id_zero")
+	}
+	if targetUser.IsBot {
+		return nil, 0, "", i18n.NewError("user.is_bot")
+	}
+
+	return targetUser, banDuration, strings.Join(args[1:], " "), nil
+}
+
+func (h *UnbanHandler) RequiredPermission(b *bot.Bot, update tgbotapi.Update) (int64, string) {
+	return groupPermission(update, bot.PermBan)
+}
+
+func (h *UnbanHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
+	if update.Message.Chat.Type == "private" {
+		return nil
+	}
+
+	chatID := update.Message.Chat.ID
+	tr := b.ChatLocalizer(chatID)
+
+	targetUser, err := extractTargetUser(b, update.Message)
+	if err != nil {
+		_, _ = b.SendTemporaryGroupMessage(chatID, tr.Error(err), 5)
+		return nil
+	}
+
+	if err := unbanUser(b, chatID, targetUser.ID); err != nil {
+		_, _ = b.SendTemporaryGroupMessage(chatID, tr.T("unban.failed"), 5)
+		return err
+	}
+	revokeActions(b, chatID, targetUser.ID, database.ActionBan, update.Message.From.ID)
+
+	successMsg := tr.T("unban.success", i18n.Vars{
+		"user":  bot.FormatUserName(targetUser),
+		"admin": bot.GetUserMention(update.Message.From),
+	})
+
+	_, _ = b.SendTemporaryGroupMessage(chatID, successMsg, 5)
+	return nil
+}
+
+func unbanUser(b *bot.Bot, chatID, userID int64) error {
+	if err := b.UnbanChatMember(chatID, userID); err != nil {
+		return fmt.Errorf("failed to unban user: %w", err)
+	}
+
+	b.CancelJobs(bot.JobUnban, chatID, userID)
+	if err := b.GetDB().RemoveBannedUser(userID, chatID); err != nil {
+		return fmt.Errorf("failed to remove banned user from database: %w", err)
+	}
+
+	return nil
+}
+
+// UnbanJob räumt einen abgelaufenen temporären Bann auf, sofern der User nicht inzwischen erneut gebannt wurde
+func UnbanJob(b *bot.Bot, job database.Job) error {
+	banned, err := b.GetDB().GetBannedUser(job.UserID, job.ChatID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if err == nil && (banned.Until.IsZero() || time.Now().Before(banned.Until)) {
+		return nil
+	}
+
+	return unbanUser(b, job.ChatID, job.UserID)
+}
+
+func (h *TempBansHandler) RequiredPermission(b *bot.Bot, update tgbotapi.Update) (int64, string) {
+	return groupPermission(update, bot.PermBan)
+}
+
+func (h *TempBansHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
+	if update.Message.Chat.Type == "private" {
+		return nil
+	}
+
+	chatID := update.Message.Chat.ID
+	tr := b.ChatLocalizer(chatID)
+
+	bans, err := b.GetDB().GetTempBans(chatID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to load temp bans: %w", err)
+	}
+
+	if len(bans) == 0 {
+		_, _ = b.SendTemporaryGroupMessage(chatID, tr.T("tbans.none"), 10)
+		return nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(tr.T("tbans.header", i18n.Vars{"count": len(bans)}) + "\n\n")
+	for _, ban := range bans {
+		reason := ban.Reason
+		if reason == "" {
+			reason = tr.T("common.no_reason")
+		}
+		sb.WriteString(tr.T("tbans.entry", i18n.Vars{
+			"user":   ban.UserID,
+			"until":  tr.FormatTime(ban.Until),
+			"reason": reason,
+			"admin":  ban.AdminID,
+		}) + "\n")
+	}
+
+	_, _ = b.SendTemporaryGroupMessage(chatID, sb.String(), 30)
+	return nil
+}
@@ -0,0 +1,217 @@
+package admin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"telegramBot/pkg/bot"
+	"telegramBot/pkg/database"
+	"telegramBot/pkg/i18n"
+	"time"
+	"unicode"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// TemplateHandler verwaltet eigene Texte, die den mitgelieferten Katalog überschreiben:
+//
+//	/template <gruppen_id|global> <sprache>                     - eigene Texte auflisten
+//	/template <gruppen_id|global> <sprache> <schlüssel>         - Text anzeigen
+//	/template <gruppen_id|global> <sprache> <schlüssel> <text>  - Text setzen
+//	/template <gruppen_id|global> <sprache> reset <schlüssel>   - eigenen Text entfernen
+type TemplateHandler struct{}
+
+func NewTemplateHandler() *TemplateHandler {
+	return &TemplateHandler{}
+}
+
+// RequiredPermission verlangt config in der Gruppe der Texte, für globale Texte global
+func (h *TemplateHandler) RequiredPermission(b *bot.Bot, update tgbotapi.Update) (int64, string) {
+	if update.Message == nil || update.Message.Chat.Type != "private" {
+		return 0, ""
+	}
+	fields, _ := splitTemplateArgs(update.Message.CommandArguments())
+	if len(fields) < 2 {
+		return 0, ""
+	}
+	chatID, ok := parseScope(fields[0])
+	if !ok {
+		return 0, ""
+	}
+	return chatID, bot.PermConfig
+}
+
+func (h *TemplateHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
+	message := update.Message
+	tr := b.Localizer(message.Chat, message.From)
+
+	if message.Chat.Type != "private" {
+		_, _ = b.SendTemporaryGroupMessage(message.Chat.ID, tr.T("template.dm_only"), 5)
+		return nil
+	}
+
+	fields, text := splitTemplateArgs(message.CommandArguments())
+	if len(fields) < 2 {
+		_, err := b.SendMessage(message.Chat.ID, tr.T("template.usage"))
+		return err
+	}
+
+	// Chat 0 steht für die globalen Texte, die in allen Gruppen und DMs gelten
+	chatID, ok := parseScope(fields[0])
+	if !ok {
+		_, err := b.SendMessage(message.Chat.ID, tr.T("template.usage"))
+		return err
+	}
+
+	locale := fields[1]
+	if !i18n.Supported(locale) {
+		_, err := b.SendMessage(message.Chat.ID, tr.T("template.unknown_locale", i18n.Vars{
+			"locale":  locale,
+			"locales": strings.Join(i18n.Locales(), ", "),
+		}))
+		return err
+	}
+
+	switch {
+	case len(fields) == 2:
+		return h.list(b, tr, message.Chat.ID, chatID, locale)
+	case fields[2] == "reset" && len(fields) == 4 && text == "":
+		return h.reset(b, tr, message.Chat.ID, chatID, locale, fields[3])
+	}
+
+	key := fields[2]
+	if _, ok := i18n.Lookup(i18n.DefaultLocale, key); !ok {
+		_, err := b.SendMessage(message.Chat.ID, tr.T("template.unknown_key", i18n.Vars{"key": key}))
+		return err
+	}
+
+	if text == "" {
+		return h.show(b, tr, message.Chat.ID, chatID, locale, key)
+	}
+
+	tmpl := database.MessageTemplate{
+		ChatID:    chatID,
+		Locale:    locale,
+		Key:       key,
+		Text:      text,
+		UpdatedBy: message.From.ID,
+		UpdatedAt: time.Now(),
+	}
+	if err := b.GetDB().SetMessageTemplate(tmpl); err != nil {
+		_, _ = b.SendMessage(message.Chat.ID, tr.T("template.save_failed"))
+		return fmt.Errorf("failed to save message template: %w", err)
+	}
+
+	_, err := b.SendMessage(message.Chat.ID, tr.T("template.saved", i18n.Vars{"key": key, "locale": locale, "scope": templateScope(tr, chatID)}))
+	return err
+}
+
+func (h *TemplateHandler) list(b *bot.Bot, tr *bot.Localizer, replyTo, chatID int64, locale string) error {
+	templates, err := b.GetDB().ListMessageTemplates(chatID)
+	if err != nil {
+		_, _ = b.SendMessage(replyTo, tr.T("template.load_failed"))
+		return fmt.Errorf("failed to load message templates: %w", err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(tr.T("template.list_title", i18n.Vars{"locale": locale, "scope": templateScope(tr, chatID)}) + "\n\n")
+
+	count := 0
+	for _, tmpl := range templates {
+		if tmpl.Locale != locale {
+			continue
+		}
+		count++
+		sb.WriteString(fmt.Sprintf("• %s\n", tmpl.Key))
+	}
+	if count == 0 {
+		sb.WriteString(tr.T("template.list_empty") + "\n")
+	}
+
+	sb.WriteString("\n" + tr.T("template.usage"))
+
+	_, err = b.SendMessage(replyTo, sb.String())
+	return err
+}
+
+func (h *TemplateHandler) show(b *bot.Bot, tr *bot.Localizer, replyTo, chatID int64, locale, key string) error {
+	templates, err := b.GetDB().GetMessageTemplates(chatID, locale)
+	if err != nil {
+		_, _ = b.SendMessage(replyTo, tr.T("template.load_failed"))
+		return fmt.Errorf("failed to load message templates: %w", err)
+	}
+
+	current, custom := templates[key]
+	if !custom {
+		current = i18n.T(locale, key)
+	}
+
+	source := tr.T("template.source_default")
+	if custom {
+		source = tr.T("template.source_custom")
+	}
+
+	_, err = b.SendMessage(replyTo, tr.T("template.show", i18n.Vars{
+		"key":    key,
+		"locale": locale,
+		"source": source,
+		"text":   current,
+	}))
+	return err
+}
+
+func (h *TemplateHandler) reset(b *bot.Bot, tr *bot.Localizer, replyTo, chatID int64, locale, key string) error {
+	removed, err := b.GetDB().RemoveMessageTemplate(chatID, locale, key)
+	if err != nil {
+		_, _ = b.SendMessage(replyTo, tr.T("template.save_failed"))
+		return fmt.Errorf("failed to remove message template: %w", err)
+	}
+
+	if !removed {
+		_, err := b.SendMessage(replyTo, tr.T("template.not_set", i18n.Vars{"key": key}))
+		return err
+	}
+
+	_, err = b.SendMessage(replyTo, tr.T("template.removed", i18n.Vars{"key": key, "locale": locale, "scope": templateScope(tr, chatID)}))
+	return err
+}
+
+func templateScope(tr *bot.Localizer, chatID int64) string {
+	if chatID == 0 {
+		return tr.T("template.scope_global")
+	}
+	return tr.T("template.scope_group", i18n.Vars{"chat": chatID})
+}
+
+// parseScope liest "global" (Chat 0) oder eine Gruppen-ID
+func parseScope(arg string) (int64, bool) {
+	if arg == "global" {
+		return 0, true
+	}
+	chatID, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || chatID >= 0 {
+		return 0, false
+	}
+	return chatID, true
+}
+
+// splitTemplateArgs trennt bis zu vier Wörter ab. Der Rest ab dem vierten Wort ist der
+// Text des Templates und behält Zeilenumbrüche und Leerzeichen.
+func splitTemplateArgs(args string) ([]string, string) {
+	var fields []string
+	rest := strings.TrimSpace(args)
+	for len(fields) < 3 && rest != "" {
+		end := strings.IndexFunc(rest, unicode.IsSpace)
+		if end < 0 {
+			end = len(rest)
+		}
+		fields = append(fields, rest[:end])
+		rest = strings.TrimSpace(rest[end:])
+	}
+
+	// Bei "reset" folgt nur noch der Schlüssel
+	if len(fields) == 3 && fields[2] == "reset" && rest != "" && !strings.ContainsFunc(rest, unicode.IsSpace) {
+		return append(fields, rest), ""
+	}
+	return fields, rest
+}
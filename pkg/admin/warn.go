@@ -0,0 +1,329 @@
+package admin
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"telegramBot/config"
+	"telegramBot/pkg/bot"
+	"telegramBot/pkg/database"
+	"telegramBot/pkg/duration"
+	"telegramBot/pkg/i18n"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+type WarnHandler struct{}
+type UnwarnHandler struct{}
+type WarnsHandler struct{}
+type ResetWarnsHandler struct{}
+
+func NewWarnHandler() *WarnHandler {
+	return &WarnHandler{}
+}
+
+func NewUnwarnHandler() *UnwarnHandler {
+	return &UnwarnHandler{}
+}
+
+func NewWarnsHandler() *WarnsHandler {
+	return &WarnsHandler{}
+}
+
+func NewResetWarnsHandler() *ResetWarnsHandler {
+	return &ResetWarnsHandler{}
+}
+
+func (h *WarnHandler) RequiredPermission(b *bot.Bot, update tgbotapi.Update) (int64, string) {
+	return groupPermission(update, bot.PermWarn)
+}
+
+func (h *WarnHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
+	if update.Message.Chat.Type == "private" {
+		return nil
+	}
+
+	chatID := update.Message.Chat.ID
+	tr := b.ChatLocalizer(chatID)
+
+	targetUser, reason, err := extractTargetUserAndReason(b, update.Message)
+	if err != nil {
+		_, _ = b.SendTemporaryGroupMessage(chatID, tr.Error(err), 5)
+		return nil
+	}
+
+	if targetUser.ID == update.Message.From.ID {
+		_, _ = b.SendTemporaryGroupMessage(chatID, tr.T("warn.self"), 5)
+		return nil
+	}
+
+	targetIsAdmin, err := b.IsUserAdmin(chatID, targetUser.ID)
+	if err != nil {
+		_, _ = b.SendTemporaryGroupMessage(chatID, tr.T("common.check_target_failed"), 5)
+		return fmt.Errorf("failed to check target admin status: %w", err)
+	}
+
+	if targetIsAdmin {
+		_, _ = b.SendTemporaryGroupMessage(chatID, tr.T("warn.target_admin"), 5)
+		return nil
+	}
+
+	warning := database.Warning{
+		ChatID:    chatID,
+		UserID:    targetUser.ID,
+		AdminID:   update.Message.From.ID,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	}
+	if _, err := b.GetDB().AddWarning(warning); err != nil {
+		return fmt.Errorf("failed to add warning: %w", err)
+	}
+
+	cfg := b.GetChatConfig(chatID)
+
+	var expiresAt time.Time
+	if expiry := cfg.Admin.WarnExpiry(); expiry > 0 {
+		expiresAt = warning.CreatedAt.Add(expiry)
+	}
+	recordAction(b, chatID, targetUser.ID, update.Message.From.ID, database.ActionWarn, reason, expiresAt)
+	warnings, err := activeWarnings(b, cfg, chatID, targetUser.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load warnings: %w", err)
+	}
+	count := len(warnings)
+
+	msg := tr.T("warn.success", i18n.Vars{
+		"count": count,
+		"user":  bot.FormatUserName(targetUser),
+		"admin": bot.GetUserMention(update.Message.From),
+	})
+
+	if reason != "" {
+		msg += tr.T("common.reason", i18n.Vars{"reason": reason})
+	}
+
+	ladder, err := config.ParseWarnLadder(cfg.Admin.WarnLadder)
+	if err != nil {
+		log.Printf("Invalid warn ladder for chat %d: %v", chatID, err)
+	}
+
+	if step, ok := config.WarnStepFor(ladder, count); ok {
+		result, err := escalateWarning(b, tr, chatID, targetUser.ID, update.Message.From.ID, step)
+		if err != nil {
+			log.Printf("Failed to escalate warning for user %d in chat %d: %v", targetUser.ID, chatID, err)
+			msg += "\n\n" + tr.T("warn.escalation_failed")
+		} else {
+			msg += "\n\n" + result
+		}
+	} else if next, ok := nextWarnStep(ladder, count); ok {
+		msg += "\n\n" + tr.T("warn.next_step", i18n.Vars{"count": next.Count, "action": describeWarnStep(tr, next)})
+	}
+
+	_, _ = b.SendTemporaryGroupMessage(chatID, msg, 10)
+	return nil
+}
+
+// escalateWarning führt eine Stufe der Eskalationsleiter über dieselbe Logik wie /mute, /kick und /ban aus
+func escalateWarning(b *bot.Bot, tr *bot.Localizer, chatID, userID, adminID int64, step config.WarnStep) (string, error) {
+	switch step.Action {
+	case config.WarnActionMute:
+		reason := tr.T("warn.ladder_reason", i18n.Vars{"count": step.Count})
+		// Die Leiter darf die Mute-Grenzen des Chats nicht umgehen
+		muteDuration := b.GetChatConfig(chatID).Admin.ClampMute(step.Duration)
+		if muteDuration == duration.Permanent {
+			if err := muteUser(b, chatID, userID, time.Time{}, adminID, reason); err != nil {
+				return "", err
+			}
+			return tr.T("warn.auto_mute_permanent"), nil
+		}
+		until := time.Now().Add(muteDuration)
+		if err := muteUser(b, chatID, userID, until, adminID, reason); err != nil {
+			return "", err
+		}
+		return tr.T("warn.auto_mute", i18n.Vars{"until": tr.FormatTime(until)}), nil
+	case config.WarnActionKick:
+		if err := kickUser(b, chatID, userID, adminID, tr.T("warn.ladder_reason", i18n.Vars{"count": step.Count})); err != nil {
+			return "", err
+		}
+		return tr.T("warn.auto_kick"), nil
+	case config.WarnActionBan:
+		reason := tr.T("warn.ladder_reason", i18n.Vars{"count": step.Count})
+		if err := banUser(b, chatID, userID, time.Time{}, adminID, reason); err != nil {
+			return "", err
+		}
+		return tr.T("warn.auto_ban"), nil
+	}
+	return "", fmt.Errorf("unknown warn action: %s", step.Action)
+}
+
+func nextWarnStep(ladder []config.WarnStep, count int) (config.WarnStep, bool) {
+	for _, step := range ladder {
+		if step.Count > count {
+			return step, true
+		}
+	}
+	return config.WarnStep{}, false
+}
+
+func describeWarnStep(tr *bot.Localizer, step config.WarnStep) string {
+	switch step.Action {
+	case config.WarnActionMute:
+		return tr.T("warn.step_mute", i18n.Vars{"duration": duration.Format(step.Duration)})
+	case config.WarnActionKick:
+		return tr.T("warn.step_kick")
+	default:
+		return tr.T("warn.step_ban")
+	}
+}
+
+// activeWarnings liefert die noch nicht verfallenen Verwarnungen eines Users
+func activeWarnings(b *bot.Bot, cfg *config.Config, chatID, userID int64) ([]database.Warning, error) {
+	var since time.Time
+	if expiry := cfg.Admin.WarnExpiry(); expiry > 0 {
+		since = time.Now().Add(-expiry)
+	}
+	return b.GetDB().GetWarnings(chatID, userID, since)
+}
+
+func (h *UnwarnHandler) RequiredPermission(b *bot.Bot, update tgbotapi.Update) (int64, string) {
+	return groupPermission(update, bot.PermWarn)
+}
+
+func (h *UnwarnHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
+	if update.Message.Chat.Type == "private" {
+		return nil
+	}
+
+	chatID := update.Message.Chat.ID
+	tr := b.ChatLocalizer(chatID)
+
+	targetUser, err := extractTargetUser(b, update.Message)
+	if err != nil {
+		_, _ = b.SendTemporaryGroupMessage(chatID, tr.Error(err), 5)
+		return nil
+	}
+
+	warnings, err := activeWarnings(b, b.GetChatConfig(chatID), chatID, targetUser.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load warnings: %w", err)
+	}
+
+	if len(warnings) == 0 {
+		_, _ = b.SendTemporaryGroupMessage(chatID, tr.T("warn.none", i18n.Vars{"user": bot.FormatUserName(targetUser)}), 5)
+		return nil
+	}
+
+	// Die jüngste Verwarnung wird zurückgenommen
+	latest := warnings[len(warnings)-1]
+	if err := b.GetDB().RemoveWarning(latest.ID); err != nil {
+		return fmt.Errorf("failed to remove warning: %w", err)
+	}
+	if err := b.GetDB().RevokeLatestModerationAction(chatID, targetUser.ID, database.ActionWarn, update.Message.From.ID, time.Now()); err != nil {
+		log.Printf("Failed to revoke warning of user %d in chat %d: %v", targetUser.ID, chatID, err)
+	}
+
+	msg := tr.T("unwarn.success", i18n.Vars{
+		"user":      bot.FormatUserName(targetUser),
+		"remaining": len(warnings) - 1,
+		"admin":     bot.GetUserMention(update.Message.From),
+	})
+
+	_, _ = b.SendTemporaryGroupMessage(chatID, msg, 5)
+	return nil
+}
+
+// RequiredPermission verlangt das Recht nur für fremde Verwarnungen, die eigenen darf jeder sehen
+func (h *WarnsHandler) RequiredPermission(b *bot.Bot, update tgbotapi.Update) (int64, string) {
+	if update.Message == nil || (update.Message.ReplyToMessage == nil && update.Message.CommandArguments() == "") {
+		return 0, ""
+	}
+	return groupPermission(update, bot.PermWarn)
+}
+
+func (h *WarnsHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
+	if update.Message.Chat.Type == "private" {
+		return nil
+	}
+
+	chatID := update.Message.Chat.ID
+	tr := b.ChatLocalizer(chatID)
+
+	// Ohne Ziel zeigt /warns die eigenen Verwarnungen, fremde nur mit dem Recht warn
+	targetUser := update.Message.From
+	if update.Message.ReplyToMessage != nil || update.Message.CommandArguments() != "" {
+		var err error
+		targetUser, err = extractTargetUser(b, update.Message)
+		if err != nil {
+			_, _ = b.SendTemporaryGroupMessage(chatID, tr.Error(err), 5)
+			return nil
+		}
+	}
+
+	cfg := b.GetChatConfig(chatID)
+	warnings, err := activeWarnings(b, cfg, chatID, targetUser.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load warnings: %w", err)
+	}
+
+	if len(warnings) == 0 {
+		_, _ = b.SendTemporaryGroupMessage(chatID, tr.T("warn.none", i18n.Vars{"user": bot.FormatUserName(targetUser)}), 10)
+		return nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(tr.T("warns.header", i18n.Vars{"user": bot.FormatUserName(targetUser), "count": len(warnings)}) + "\n\n")
+	for i, warning := range warnings {
+		reason := warning.Reason
+		if reason == "" {
+			reason = tr.T("common.no_reason")
+		}
+		sb.WriteString(tr.T("warns.entry", i18n.Vars{
+			"index":  i + 1,
+			"time":   tr.FormatTime(warning.CreatedAt),
+			"reason": reason,
+			"admin":  warning.AdminID,
+		}) + "\n")
+	}
+
+	if expiry := cfg.Admin.WarnExpiry(); expiry > 0 {
+		sb.WriteString("\n" + tr.T("warns.expiry", i18n.Vars{"duration": duration.Format(expiry)}))
+	}
+
+	_, _ = b.SendTemporaryGroupMessage(chatID, sb.String(), 15)
+	return nil
+}
+
+func (h *ResetWarnsHandler) RequiredPermission(b *bot.Bot, update tgbotapi.Update) (int64, string) {
+	return groupPermission(update, bot.PermWarn)
+}
+
+func (h *ResetWarnsHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
+	if update.Message.Chat.Type == "private" {
+		return nil
+	}
+
+	chatID := update.Message.Chat.ID
+	tr := b.ChatLocalizer(chatID)
+
+	targetUser, err := extractTargetUser(b, update.Message)
+	if err != nil {
+		_, _ = b.SendTemporaryGroupMessage(chatID, tr.Error(err), 5)
+		return nil
+	}
+
+	removed, err := b.GetDB().RemoveWarnings(chatID, targetUser.ID)
+	if err != nil {
+		return fmt.Errorf("failed to reset warnings: %w", err)
+	}
+	revokeActions(b, chatID, targetUser.ID, database.ActionWarn, update.Message.From.ID)
+
+	msg := tr.T("resetwarns.success", i18n.Vars{
+		"user":    bot.FormatUserName(targetUser),
+		"removed": removed,
+		"admin":   bot.GetUserMention(update.Message.From),
+	})
+
+	_, _ = b.SendTemporaryGroupMessage(chatID, msg, 5)
+	return nil
+}
@@ -0,0 +1,96 @@
+package admin
+
+import (
+	"fmt"
+	"strings"
+	"telegramBot/config"
+	"telegramBot/pkg/bot"
+	"telegramBot/pkg/i18n"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// welcomePreviewSeconds bestimmt, wie lange Vorschau und Bestätigung von /setwelcome stehen bleiben
+const welcomePreviewSeconds = 60
+
+// SetWelcomeHandler setzt die Willkommensnachricht einer Gruppe:
+//
+//	/setwelcome <text>           - Text samt Formatierung übernehmen
+//	/setwelcome (als Antwort)    - die beantwortete Nachricht samt URL-Buttons übernehmen
+//	/setwelcome reset            - wieder die globale Willkommensnachricht verwenden
+type SetWelcomeHandler struct{}
+
+func NewSetWelcomeHandler() *SetWelcomeHandler {
+	return &SetWelcomeHandler{}
+}
+
+func (h *SetWelcomeHandler) RequiredPermission(b *bot.Bot, update tgbotapi.Update) (int64, string) {
+	return groupPermission(update, bot.PermConfig)
+}
+
+func (h *SetWelcomeHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
+	message := update.Message
+	chatID := message.Chat.ID
+	tr := b.Localizer(message.Chat, message.From)
+
+	if message.Chat.Type == "private" {
+		_, err := b.SendMessage(chatID, tr.T("welcome.group_only"))
+		return err
+	}
+
+	args := strings.TrimSpace(message.CommandArguments())
+	if args == "reset" {
+		return h.reset(b, tr, chatID)
+	}
+
+	// Vorlagen aus Telegram-Nachrichten werden als HTML gespeichert, damit die Formatierung erhalten bleibt
+	var template string
+	switch {
+	case message.ReplyToMessage != nil:
+		template = strings.TrimSpace(bot.MessageHTML(message.ReplyToMessage) + bot.WelcomeButtonLines(message.ReplyToMessage.ReplyMarkup))
+		if template == "" {
+			_, _ = b.SendTemporaryGroupMessage(chatID, tr.T("welcome.empty"), 10)
+			return nil
+		}
+	case args != "":
+		template = bot.CommandArgumentsHTML(message)
+	default:
+		_, _ = b.SendTemporaryGroupMessage(chatID, tr.T("welcome.usage", i18n.Vars{
+			"placeholders": "{" + strings.Join(bot.WelcomePlaceholders, "}, {") + "}",
+		}), 30)
+		return nil
+	}
+
+	// Erst die Vorschau senden: lehnt Telegram die Vorlage ab, wird sie nicht gespeichert
+	preview, err := b.GetAPI().Send(b.RenderWelcome(message.Chat, message.From, template, config.WelcomeFormatHTML))
+	if err != nil {
+		_, _ = b.SendTemporaryGroupMessage(chatID, tr.T("welcome.invalid", i18n.Vars{"error": err.Error()}), 15)
+		return nil
+	}
+	b.ScheduleMessageDeletion(chatID, preview.MessageID, welcomePreviewSeconds*time.Second)
+
+	if err := b.GetDB().SetGroupSetting(chatID, "welcome_message", template); err != nil {
+		_, _ = b.SendTemporaryGroupMessage(chatID, tr.T("welcome.save_failed"), 10)
+		return fmt.Errorf("failed to save welcome message: %w", err)
+	}
+	if err := b.GetDB().SetGroupSetting(chatID, "welcome_format", config.WelcomeFormatHTML); err != nil {
+		_, _ = b.SendTemporaryGroupMessage(chatID, tr.T("welcome.save_failed"), 10)
+		return fmt.Errorf("failed to save welcome format: %w", err)
+	}
+
+	_, err = b.SendTemporaryGroupMessage(chatID, tr.T("welcome.saved"), welcomePreviewSeconds)
+	return err
+}
+
+func (h *SetWelcomeHandler) reset(b *bot.Bot, tr *bot.Localizer, chatID int64) error {
+	for _, key := range []string{"welcome_message", "welcome_format"} {
+		if err := b.GetDB().RemoveGroupSetting(chatID, key); err != nil {
+			_, _ = b.SendTemporaryGroupMessage(chatID, tr.T("welcome.save_failed"), 10)
+			return fmt.Errorf("failed to reset welcome message: %w", err)
+		}
+	}
+
+	_, err := b.SendTemporaryGroupMessage(chatID, tr.T("welcome.reset"), 10)
+	return err
+}
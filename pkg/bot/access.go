@@ -0,0 +1,180 @@
+package bot
+
+import (
+	"errors"
+	"log"
+	"slices"
+	"telegramBot/pkg/database"
+	"telegramBot/pkg/i18n"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Rechte, die Commands über PermissionHandler verlangen
+const (
+	PermBan          = "ban"
+	PermKick         = "kick"
+	PermMute         = "mute"
+	PermDelete       = "delete"
+	PermWarn         = "warn"
+	PermConfig       = "config"
+	PermManageAdmins = "manage_admins"
+)
+
+// Permissions sind alle Rechte in der Reihenfolge, in der sie angezeigt werden
+var Permissions = []string{PermBan, PermKick, PermMute, PermDelete, PermWarn, PermConfig, PermManageAdmins}
+
+// Rollen für die Admin-Rechte bei Telegram. Sie gelten nur in der jeweiligen Gruppe
+// und werden in group_admins gespeichert (siehe telegramRoles).
+const (
+	TelegramCreator    = "tg_creator"     // Ersteller der Gruppe
+	TelegramRestrict   = "tg_restrict"    // can_restrict_members
+	TelegramDelete     = "tg_delete"      // can_delete_messages
+	TelegramChangeInfo = "tg_change_info" // can_change_info
+	TelegramPromote    = "tg_promote"     // can_promote_members
+)
+
+// TelegramRoles sind alle Rollen, die aus Telegram-Rechten abgeleitet werden
+var TelegramRoles = []string{TelegramCreator, TelegramRestrict, TelegramDelete, TelegramChangeInfo, TelegramPromote}
+
+// defaultRolePermissions gilt, solange weder die Gruppe noch global etwas festgelegt ist.
+// Owner haben immer alle Rechte und fehlen deshalb hier.
+var defaultRolePermissions = map[string][]string{
+	database.RoleAdmin:     Permissions,
+	database.RoleModerator: {PermBan, PermKick, PermMute, PermDelete, PermWarn},
+	TelegramCreator:        Permissions,
+	TelegramRestrict:       {PermBan, PermKick, PermMute, PermWarn},
+	TelegramDelete:         {PermDelete},
+	TelegramChangeInfo:     {PermConfig},
+	TelegramPromote:        {},
+}
+
+// ErrPermissionDenied landet im Command-Log, wenn handleUpdate einen Command ablehnt
+var ErrPermissionDenied = errors.New("permission denied")
+
+// PermissionHandler ist ein Handler, der ein Recht verlangt. handleUpdate prüft es,
+// bevor Handle aufgerufen wird, und meldet eine Ablehnung selbst.
+type PermissionHandler interface {
+	Handler
+	// RequiredPermission liefert den Chat, in dem das Recht gelten muss (0 = global),
+	// und das Recht selbst. "" bedeutet, dass keine Prüfung nötig ist.
+	RequiredPermission(bot *Bot, update tgbotapi.Update) (chatID int64, permission string)
+}
+
+// IsPermission prüft, ob es ein Recht mit diesem Namen gibt
+func IsPermission(permission string) bool {
+	return slices.Contains(Permissions, permission)
+}
+
+// IsRole prüft, ob es eine Bot- oder Telegram-Rolle mit diesem Namen gibt
+func IsRole(role string) bool {
+	return slices.Contains(BotRoles, role) || slices.Contains(TelegramRoles, role)
+}
+
+// RolePermissions liefert die Rechte einer Rolle in einem Chat: die Einstellung der Gruppe
+// geht vor der globalen (Chat 0), die vor dem Standard.
+func (b *Bot) RolePermissions(chatID int64, role string) []string {
+	if role == database.RoleOwner {
+		return Permissions
+	}
+
+	scopes := []int64{chatID}
+	if chatID != 0 {
+		scopes = append(scopes, 0)
+	}
+	for _, scope := range scopes {
+		overrides, err := b.db.GetRolePermissions(scope)
+		if err != nil {
+			log.Printf("Failed to load role permissions of chat %d: %v", scope, err)
+			break
+		}
+		if permissions, ok := overrides[role]; ok {
+			return permissions
+		}
+	}
+	return defaultRolePermissions[role]
+}
+
+// SetRolePermissions legt die Rechte einer Rolle in einem Chat fest (0 = global)
+func (b *Bot) SetRolePermissions(chatID int64, role string, permissions []string) error {
+	if err := checkEditableRole(role); err != nil {
+		return err
+	}
+	for _, permission := range permissions {
+		if !IsPermission(permission) {
+			return i18n.NewError("roleperms.unknown_permission", i18n.Vars{"permission": permission})
+		}
+	}
+	return b.db.SetRolePermissions(chatID, role, permissions)
+}
+
+// ResetRolePermissions stellt für eine Rolle in einem Chat wieder die übergeordneten Rechte her
+func (b *Bot) ResetRolePermissions(chatID int64, role string) (bool, error) {
+	if err := checkEditableRole(role); err != nil {
+		return false, err
+	}
+	return b.db.RemoveRolePermissions(chatID, role)
+}
+
+func checkEditableRole(role string) error {
+	if role == database.RoleOwner {
+		return i18n.NewError("roleperms.owner_fixed")
+	}
+	if !IsRole(role) {
+		return i18n.NewError("roleperms.unknown_role", i18n.Vars{"role": role})
+	}
+	return nil
+}
+
+// HasPermission prüft, ob ein User in einem Chat ein Recht hat. Es zählt die Bot-Rolle,
+// in Gruppen zusätzlich die Admin-Rechte des Users bei Telegram.
+func (b *Bot) HasPermission(chatID, userID int64, permission string) bool {
+	if role := b.BotRole(userID); role != "" && slices.Contains(b.RolePermissions(chatID, role), permission) {
+		return true
+	}
+
+	// Negative Chat-IDs sind Gruppen, nur dort gibt es Telegram-Admins
+	if chatID >= 0 {
+		return false
+	}
+	roles, err := b.telegramRoles(chatID, userID)
+	if err != nil {
+		log.Printf("Failed to load member %d of chat %d: %v", userID, chatID, err)
+		return false
+	}
+	for _, role := range roles {
+		if slices.Contains(b.RolePermissions(chatID, role), permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize setzt das Recht durch, das ein Handler verlangt, und meldet eine Ablehnung dem User
+func (b *Bot) authorize(handler Handler, update tgbotapi.Update) bool {
+	permissionHandler, ok := handler.(PermissionHandler)
+	if !ok {
+		return true
+	}
+
+	chatID, permission := permissionHandler.RequiredPermission(b, update)
+	user := update.SentFrom()
+	if permission == "" || (user != nil && b.HasPermission(chatID, user.ID, permission)) {
+		return true
+	}
+
+	if callback := update.CallbackQuery; callback != nil {
+		b.api.Request(tgbotapi.NewCallback(callback.ID, b.UserLocalizer(callback.From).T("common.no_permission")))
+		return false
+	}
+
+	if message := update.Message; message != nil {
+		tr := b.Localizer(message.Chat, message.From)
+		if message.Chat.Type == "private" {
+			b.SendMessage(message.Chat.ID, "❌ "+tr.T("common.no_permission"))
+		} else {
+			b.SendTemporaryGroupMessage(message.Chat.ID, tr.T("common.no_permission"), 5)
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"telegramBot/pkg/database"
+	"telegramBot/pkg/i18n"
+	"time"
+)
+
+// BotRoles sind alle Rollen der Bot-Admins, absteigend nach Rechten:
+//
+//	owner     - alles, auch andere Owner und Admins verwalten
+//	admin     - globale Config, Admins und Moderatoren verwalten
+//	moderator - Moderations-Commands in allen Gruppen
+var BotRoles = []string{database.RoleOwner, database.RoleAdmin, database.RoleModerator}
+
+// RoleRank ordnet die Rollen: je höher, desto mehr Rechte, 0 = keine Rolle
+func RoleRank(role string) int {
+	switch role {
+	case database.RoleOwner:
+		return 3
+	case database.RoleAdmin:
+		return 2
+	case database.RoleModerator:
+		return 1
+	}
+	return 0
+}
+
+// BotRole liefert die Rolle eines Users als Bot-Admin, ohne Rolle ""
+func (b *Bot) BotRole(userID int64) string {
+	admin, err := b.db.GetBotAdmin(userID)
+	if err != nil {
+		log.Printf("Failed to load bot role of user %d: %v", userID, err)
+		return ""
+	}
+	if admin == nil {
+		return ""
+	}
+	return admin.Role
+}
+
+// HasBotRole prüft, ob ein User mindestens die angegebene Rolle hat
+func (b *Bot) HasBotRole(userID int64, role string) bool {
+	return RoleRank(b.BotRole(userID)) >= RoleRank(role) && RoleRank(role) > 0
+}
+
+// GrantBotRole vergibt eine Rolle oder ändert sie. actorID 0 steht für den Bot selbst.
+func (b *Bot) GrantBotRole(userID int64, role string, actorID int64, note string) error {
+	if RoleRank(role) == 0 {
+		return i18n.NewError("admins.invalid_role", i18n.Vars{"role": role})
+	}
+	if role != database.RoleOwner {
+		if err := b.checkLastOwner(userID); err != nil {
+			return err
+		}
+	}
+
+	if err := b.db.SetBotAdmin(database.BotAdmin{
+		UserID:    userID,
+		Role:      role,
+		GrantedBy: actorID,
+		GrantedAt: time.Now(),
+	}, note); err != nil {
+		return fmt.Errorf("failed to grant role %s to user %d: %w", role, userID, err)
+	}
+	return nil
+}
+
+// RevokeBotRole entzieht einem User seine Rolle und meldet, ob er eine hatte
+func (b *Bot) RevokeBotRole(userID, actorID int64, note string) (bool, error) {
+	if err := b.checkLastOwner(userID); err != nil {
+		return false, err
+	}
+
+	removed, err := b.db.RemoveBotAdmin(userID, actorID, note)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke role of user %d: %w", userID, err)
+	}
+	return removed, nil
+}
+
+// checkLastOwner verhindert, dass der letzte Owner seine Rolle verliert
+func (b *Bot) checkLastOwner(userID int64) error {
+	if b.BotRole(userID) != database.RoleOwner {
+		return nil
+	}
+	owners, err := b.db.CountBotAdmins(database.RoleOwner)
+	if err != nil {
+		return fmt.Errorf("failed to count owners: %w", err)
+	}
+	if owners <= 1 {
+		return i18n.NewError("admins.last_owner")
+	}
+	return nil
+}
+
+// importConfigAdmins übernimmt admin_user_ids einmalig aus der Config: der erste Eintrag
+// wird Owner, alle weiteren Admins. Danach zählt nur noch die Datenbank.
+func (b *Bot) importConfigAdmins() error {
+	userIDs := b.GetConfig().Admin.AdminUserIDs
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	var admins []database.BotAdmin
+	for i, userID := range userIDs {
+		role := database.RoleAdmin
+		if i == 0 {
+			role = database.RoleOwner
+		}
+		admins = append(admins, database.BotAdmin{UserID: userID, Role: role, GrantedAt: now})
+	}
+
+	imported, err := b.db.ImportBotAdmins(admins, "config admin_user_ids")
+	if err != nil {
+		return fmt.Errorf("failed to import bot admins: %w", err)
+	}
+	if imported > 0 {
+		log.Printf("Imported %d bot admins from admin_user_ids, the list in the config is no longer used", imported)
+	}
+	return nil
+}
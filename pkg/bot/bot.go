@@ -3,9 +3,15 @@ package bot
 import (
 	"fmt"
 	"log"
+	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"telegramBot/config"
 	"telegramBot/pkg/database"
+	"telegramBot/pkg/duration"
+	"telegramBot/pkg/i18n"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -13,11 +19,22 @@ import (
 
 type Bot struct {
 	api         *tgbotapi.BotAPI
-	config      *config.Config
+	config      atomic.Pointer[config.Config] // wird nie verändert, sondern als Kopie ersetzt
 	db          *database.DB
 	handlers    map[string]Handler
 	logger      *CommandLogger
 	eventLogger *EventLogger
+	scheduler   *Scheduler
+	messages    *MessageIndex
+	queue       *RequestQueue
+	dispatcher  *Dispatcher
+	settingsMu  sync.Mutex
+	claim       claimState
+	chats       chatRegistry
+	// revokedAdmins sind die User, denen revokeSyncedAdmins beim Erstellen die Rolle entzogen hat
+	revokedAdmins []int64
+	webhookMu     sync.Mutex
+	webhook       *webhookServer
 }
 
 type Handler interface {
@@ -25,8 +42,23 @@ type Handler interface {
 }
 
 func NewBot(cfg *config.Config) (*Bot, error) {
-	api, err := tgbotapi.NewBotAPI(cfg.BotToken)
+	return NewBotWithClient(cfg, &http.Client{})
+}
+
+// NewBotWithClient erstellt den Bot mit einem eigenen HTTP-Client, z.B. für Tests
+// gegen einen lokalen Fake-Server. Der Endpoint kommt aus cfg.APIEndpoint
+// (Format wie tgbotapi.APIEndpoint), leer bedeutet die offizielle Bot API.
+func NewBotWithClient(cfg *config.Config, client tgbotapi.HTTPClient) (*Bot, error) {
+	endpoint := cfg.APIEndpoint
+	if endpoint == "" {
+		endpoint = tgbotapi.APIEndpoint
+	}
+
+	// Alle Aufrufe über tgbotapi laufen durch die Warteschlange
+	queue := NewRequestQueue(client, cfg.RateLimit)
+	api, err := tgbotapi.NewBotAPIWithClient(cfg.BotToken, endpoint, queue)
 	if err != nil {
+		queue.Close()
 		return nil, fmt.Errorf("failed to create bot: %w", err)
 	}
 
@@ -34,45 +66,114 @@ func NewBot(cfg *config.Config) (*Bot, error) {
 
 	db, err := database.NewDB(cfg.Database.FilePath)
 	if err != nil {
+		queue.Close()
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
 
-	logger, err := NewCommandLogger("commands.log")
+	logOpts := logOptions(cfg.Logging)
+
+	commandLog := cfg.Logging.CommandLog
+	if commandLog == "" {
+		commandLog = "commands.log"
+	}
+	logger, err := NewCommandLogger(commandLog, logOpts)
 	if err != nil {
+		db.Close()
+		queue.Close()
 		return nil, fmt.Errorf("failed to initialize command logger: %w", err)
 	}
 
-	eventLogger, err := NewEventLogger("events.log")
+	eventLog := cfg.Logging.EventLog
+	if eventLog == "" {
+		eventLog = "events.log"
+	}
+	eventLogger, err := NewEventLogger(eventLog, logOpts)
 	if err != nil {
+		logger.Close()
+		db.Close()
+		queue.Close()
 		return nil, fmt.Errorf("failed to initialize event logger: %w", err)
 	}
 
 	bot := &Bot{
 		api:         api,
-		config:      cfg,
 		db:          db,
 		handlers:    make(map[string]Handler),
 		logger:      logger,
 		eventLogger: eventLogger,
+		messages:    NewMessageIndex(),
+		queue:       queue,
 	}
+	bot.config.Store(cfg)
+
+	// Bei Fehlern in der Einrichtung wieder alles schließen, was schon geöffnet ist
+	fail := func(err error) (*Bot, error) {
+		queue.Close()
+		logger.Close()
+		eventLogger.Close()
+		db.Close()
+		return nil, err
+	}
+	if err := bot.importConfigAdmins(); err != nil {
+		return fail(err)
+	}
+	if err := bot.revokeSyncedAdmins(); err != nil {
+		return fail(err)
+	}
+	if err := bot.loadGlobalSettings(); err != nil {
+		return fail(err)
+	}
+
+	bot.scheduler = NewScheduler(bot)
+	bot.dispatcher = NewDispatcher(cfg.Dispatcher, bot.handleUpdate)
 
 	return bot, nil
 }
 
+// logOptions übersetzt die Logging-Konfiguration; rotate_after wurde in LoadConfig bereits geprüft
+func logOptions(cfg config.LoggingConfig) LogOptions {
+	opts := LogOptions{
+		Format:     cfg.Format,
+		MaxSize:    int64(cfg.MaxSizeMB) * 1024 * 1024,
+		MaxBackups: cfg.MaxBackups,
+		HashSalt:   cfg.HashSalt,
+	}
+	if cfg.RotateAfter != "" {
+		if d, err := duration.Parse(cfg.RotateAfter); err == nil && d != duration.Permanent {
+			opts.RotateAfter = d
+		}
+	}
+	return opts
+}
+
 func (b *Bot) RegisterHandler(command string, handler Handler) {
 	b.handlers[command] = handler
 }
 
 func (b *Bot) Start() error {
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
-
-	updates := b.api.GetUpdatesChan(u)
+	b.schedulePurge()
+	b.scheduleReconcile()
+	b.issueClaimTokenIfNeeded()
+	b.notifyRevokedAdmins()
+	b.scheduler.Start()
+
+	var updates tgbotapi.UpdatesChannel
+	if b.GetConfig().Mode == config.ModeWebhook {
+		var err error
+		updates, err = b.startWebhook()
+		if err != nil {
+			return err
+		}
+	} else {
+		u := tgbotapi.NewUpdate(0)
+		u.Timeout = 60
+		updates = b.api.GetUpdatesChan(u)
+	}
 
 	log.Printf("Bot %s started successfully", b.api.Self.UserName)
 
 	for update := range updates {
-		go b.handleUpdate(update)
+		b.dispatcher.Submit(update)
 	}
 
 	return nil
@@ -85,7 +186,16 @@ func (b *Bot) handleUpdate(update tgbotapi.Update) {
 		}
 	}()
 
+	b.trackChat(update)
+	if update.MyChatMember != nil {
+		b.recordMembership(update.MyChatMember)
+		return
+	}
+
 	if update.Message != nil {
+		// Alle Gruppen-Nachrichten merken, auch Commands, damit /del sie gezielt löschen kann
+		b.indexMessage(update.Message)
+
 		if update.Message.IsCommand() {
 			command := update.Message.Command()
 			if handler, exists := b.handlers[command]; exists {
@@ -94,21 +204,19 @@ func (b *Bot) handleUpdate(update tgbotapi.Update) {
 
 				// Für Gruppen-Commands: Lösche Command-Nachricht nach 5 Sekunden
 				if update.Message.Chat.Type != "private" {
-					go func() {
-						time.Sleep(5 * time.Second)
-						b.DeleteMessage(update.Message.Chat.ID, update.Message.MessageID)
-						log.Printf("DEBUG: Auto-deleted command message %d", update.Message.MessageID)
-					}()
+					b.ScheduleMessageDeletion(update.Message.Chat.ID, update.Message.MessageID, 5*time.Second)
 				}
 
-				err := handler.Handle(b, update)
-				result := "SUCCESS"
-				if err != nil {
-					result = "ERROR: " + err.Error()
-					log.Printf("Error handling command %s: %v", command, err)
+				start := time.Now()
+				err := ErrPermissionDenied
+				if b.authorize(handler, update) {
+					err = handler.Handle(b, update)
+					if err != nil {
+						log.Printf("Error handling command %s: %v", command, err)
+					}
 				}
 
-				b.logger.LogCommand(update.Message.Chat.ID, update.Message.From.ID, username, command, args, result)
+				b.logger.LogCommand(update.Message.Chat.ID, update.Message.From.ID, username, command, args, time.Since(start), err)
 				return
 			}
 		}
@@ -155,10 +263,9 @@ func (b *Bot) handleUpdate(update tgbotapi.Update) {
 			}
 		}
 
-		// Log alle Nachrichten (außer Commands, die werden separat geloggt)
+		// Log alle Nachrichten (außer Commands, die werden separat geloggt), je nach message_logging der Gruppe
 		if !update.Message.IsCommand() {
-			username := GetUserIdentifier(update.Message.From)
-			b.eventLogger.LogMessage(update.Message.Chat.ID, update.Message.From.ID, username, update.Message.Text)
+			b.logMessage(update.Message)
 		}
 
 		// Zuerst Captcha-Message Handler prüfen
@@ -168,6 +275,13 @@ func (b *Bot) handleUpdate(update tgbotapi.Update) {
 			}
 		}
 
+		// Flood-Schutz vor dem normalen Message Handler
+		if handler, exists := b.handlers["flood"]; exists {
+			if err := handler.Handle(b, update); err != nil {
+				log.Printf("Error handling flood check: %v", err)
+			}
+		}
+
 		// Dann normalen Message Handler
 		if handler, exists := b.handlers["message"]; exists {
 			if err := handler.Handle(b, update); err != nil {
@@ -177,6 +291,18 @@ func (b *Bot) handleUpdate(update tgbotapi.Update) {
 	}
 
 	if update.CallbackQuery != nil {
+		// Callbacks mit eigenem Präfix ("präfix:...") gehen an "callback_<präfix>", alle anderen an "callback"
+		prefix := strings.SplitN(update.CallbackQuery.Data, ":", 2)[0]
+		if handler, exists := b.handlers["callback_"+prefix]; exists {
+			if !b.authorize(handler, update) {
+				return
+			}
+			if err := handler.Handle(b, update); err != nil {
+				log.Printf("Error handling %s callback: %v", prefix, err)
+			}
+			return
+		}
+
 		if handler, exists := b.handlers["callback"]; exists {
 			if err := handler.Handle(b, update); err != nil {
 				log.Printf("Error handling callback: %v", err)
@@ -195,11 +321,7 @@ func (b *Bot) SendTemporaryGroupMessage(chatID int64, text string, deleteAfterSe
 	sentMsg, err := b.api.Send(msg)
 
 	if err == nil && deleteAfterSeconds > 0 {
-		go func() {
-			time.Sleep(time.Duration(deleteAfterSeconds) * time.Second)
-			b.DeleteMessage(chatID, sentMsg.MessageID)
-			log.Printf("DEBUG: Auto-deleted bot response message %d", sentMsg.MessageID)
-		}()
+		b.ScheduleMessageDeletion(chatID, sentMsg.MessageID, time.Duration(deleteAfterSeconds)*time.Second)
 	}
 
 	return sentMsg, err
@@ -210,10 +332,7 @@ func (b *Bot) SendTemporaryMessage(chatID int64, text string, deleteAfterSeconds
 	sentMsg, err := b.api.Send(msg)
 
 	if err == nil && deleteAfterSeconds > 0 {
-		go func() {
-			time.Sleep(time.Duration(deleteAfterSeconds) * time.Second)
-			b.DeleteMessage(chatID, sentMsg.MessageID)
-		}()
+		b.ScheduleMessageDeletion(chatID, sentMsg.MessageID, time.Duration(deleteAfterSeconds)*time.Second)
 	}
 
 	return sentMsg, err
@@ -227,27 +346,10 @@ func (b *Bot) SendTemporaryMessageAndDeleteCommand(chatID int64, text string, co
 		log.Printf("DEBUG: Scheduled deletion in %d seconds - Bot message ID: %d, Command message ID: %d",
 			deleteAfterSeconds, sentMsg.MessageID, commandMessageID)
 
-		go func() {
-			time.Sleep(time.Duration(deleteAfterSeconds) * time.Second)
-
-			log.Printf("DEBUG: Attempting to delete messages - Bot: %d, Command: %d", sentMsg.MessageID, commandMessageID)
-
-			// Lösche sowohl Bot-Antwort als auch Command-Nachricht
-			err1 := b.DeleteMessage(chatID, sentMsg.MessageID)
-			err2 := b.DeleteMessage(chatID, commandMessageID)
-
-			if err1 != nil {
-				log.Printf("DEBUG: Failed to delete bot message %d: %v", sentMsg.MessageID, err1)
-			} else {
-				log.Printf("DEBUG: Successfully deleted bot message %d", sentMsg.MessageID)
-			}
-
-			if err2 != nil {
-				log.Printf("DEBUG: Failed to delete command message %d: %v", commandMessageID, err2)
-			} else {
-				log.Printf("DEBUG: Successfully deleted command message %d", commandMessageID)
-			}
-		}()
+		// Lösche sowohl Bot-Antwort als auch Command-Nachricht
+		delay := time.Duration(deleteAfterSeconds) * time.Second
+		b.ScheduleMessageDeletion(chatID, sentMsg.MessageID, delay)
+		b.ScheduleMessageDeletion(chatID, commandMessageID, delay)
 	} else {
 		log.Printf("DEBUG: No deletion scheduled - err: %v, deleteAfterSeconds: %d", err, deleteAfterSeconds)
 	}
@@ -261,10 +363,65 @@ func (b *Bot) SendMessageWithKeyboard(chatID int64, text string, keyboard tgbota
 	return b.api.Send(msg)
 }
 
+// SendHTMLMessage sendet einen HTML-formatierten Text mit optionalem Inline-Keyboard
+func (b *Bot) SendHTMLMessage(chatID int64, text string, keyboard *tgbotapi.InlineKeyboardMarkup) (tgbotapi.Message, error) {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = tgbotapi.ModeHTML
+	if keyboard != nil {
+		msg.ReplyMarkup = *keyboard
+	}
+	return b.api.Send(msg)
+}
+
+// EditMessageWithKeyboard ersetzt Text und Inline-Keyboard einer bereits gesendeten Nachricht
+func (b *Bot) EditMessageWithKeyboard(chatID int64, messageID int, text string, keyboard tgbotapi.InlineKeyboardMarkup) error {
+	edit := tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, text, keyboard)
+	_, err := b.api.Request(edit)
+	return err
+}
+
+// SendPhoto sendet ein PNG-Bild mit Beschriftung und optionalem Inline-Keyboard
+func (b *Bot) SendPhoto(chatID int64, image []byte, caption string, keyboard *tgbotapi.InlineKeyboardMarkup) (tgbotapi.Message, error) {
+	photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileBytes{Name: "captcha.png", Bytes: image})
+	photo.Caption = caption
+	if keyboard != nil {
+		photo.ReplyMarkup = *keyboard
+	}
+	return b.api.Send(photo)
+}
+
 func (b *Bot) DeleteMessage(chatID int64, messageID int) error {
 	deleteMsg := tgbotapi.NewDeleteMessage(chatID, messageID)
-	_, err := b.api.Request(deleteMsg)
-	return err
+	if _, err := b.api.Request(deleteMsg); err != nil {
+		return err
+	}
+	b.messages.Remove(chatID, []int{messageID})
+	return nil
+}
+
+// deleteMessagesLimit ist die Obergrenze von deleteMessages pro Aufruf
+const deleteMessagesLimit = 100
+
+// DeleteMessages löscht mehrere Nachrichten gebündelt per deleteMessages und liefert die Anzahl
+// der übergebenen Nachrichten. Telegram überspringt bereits gelöschte Nachrichten ohne Fehler.
+func (b *Bot) DeleteMessages(chatID int64, messageIDs []int) (int, error) {
+	deleted := 0
+	for start := 0; start < len(messageIDs); start += deleteMessagesLimit {
+		batch := messageIDs[start:min(start+deleteMessagesLimit, len(messageIDs))]
+
+		params := tgbotapi.Params{}
+		params.AddNonZero64("chat_id", chatID)
+		if err := params.AddInterface("message_ids", batch); err != nil {
+			return deleted, fmt.Errorf("failed to encode message ids: %w", err)
+		}
+
+		if _, err := b.api.MakeRequest("deleteMessages", params); err != nil {
+			return deleted, fmt.Errorf("failed to delete messages: %w", err)
+		}
+		b.messages.Remove(chatID, batch)
+		deleted += len(batch)
+	}
+	return deleted, nil
 }
 
 func (b *Bot) RestrictChatMember(chatID, userID int64, permissions tgbotapi.ChatPermissions) error {
@@ -301,6 +458,19 @@ func (b *Bot) BanChatMember(chatID, userID int64) error {
 	return err
 }
 
+// BanChatMemberUntil bannt einen User bis zu einem Zeitpunkt; Telegram hebt den Bann danach selbst auf
+func (b *Bot) BanChatMemberUntil(chatID, userID int64, until time.Time) error {
+	banConfig := tgbotapi.BanChatMemberConfig{
+		ChatMemberConfig: tgbotapi.ChatMemberConfig{
+			ChatID: chatID,
+			UserID: userID,
+		},
+		UntilDate: until.Unix(),
+	}
+	_, err := b.api.Request(banConfig)
+	return err
+}
+
 func (b *Bot) UnbanChatMember(chatID, userID int64) error {
 	unbanConfig := tgbotapi.UnbanChatMemberConfig{
 		ChatMemberConfig: tgbotapi.ChatMemberConfig{
@@ -328,8 +498,67 @@ func (b *Bot) IsUserAdmin(chatID, userID int64) (bool, error) {
 	return status == "administrator" || status == "creator", nil
 }
 
+// GetConfig liefert die globale Config. Sie darf nicht verändert werden, Änderungen laufen
+// über SetGlobalSetting.
 func (b *Bot) GetConfig() *config.Config {
-	return b.config
+	return b.config.Load()
+}
+
+// GetChatConfig liefert die Config eines Chats inklusive gruppenspezifischer Overrides.
+// Ohne Overrides (oder bei DB-Fehlern) gilt die globale Config.
+func (b *Bot) GetChatConfig(chatID int64) *config.Config {
+	global := b.GetConfig()
+	overrides, err := b.db.GetGroupSettings(chatID)
+	if err != nil {
+		log.Printf("Failed to load group settings for chat %d: %v", chatID, err)
+		return global
+	}
+	if len(overrides) == 0 {
+		return global
+	}
+
+	cfg, errs := global.WithOverrides(overrides)
+	for _, err := range errs {
+		log.Printf("Ignoring invalid group setting for chat %d: %v", chatID, err)
+	}
+	return cfg
+}
+
+// globalSettingsChatID ist die Chat-ID in group_settings, unter der /config per DM globale Werte speichert
+const globalSettingsChatID = 0
+
+// loadGlobalSettings legt die per /config gespeicherten globalen Werte über die Config-Datei
+func (b *Bot) loadGlobalSettings() error {
+	overrides, err := b.db.GetGroupSettings(globalSettingsChatID)
+	if err != nil {
+		return fmt.Errorf("failed to load global settings: %w", err)
+	}
+
+	cfg, errs := b.GetConfig().WithOverrides(overrides)
+	for _, err := range errs {
+		log.Printf("Ignoring invalid global setting: %v", err)
+	}
+	b.config.Store(cfg)
+	return nil
+}
+
+// SetGlobalSetting ändert einen Config-Wert für alle Gruppen. Der Wert wird in der Datenbank
+// gespeichert und beim Start über die Config-Datei gelegt, die Datei selbst bleibt unverändert.
+func (b *Bot) SetGlobalSetting(key, value string) error {
+	b.settingsMu.Lock()
+	defer b.settingsMu.Unlock()
+
+	// Leser sehen entweder die alte oder die neue Kopie, nie eine halb geänderte
+	cfg := *b.GetConfig()
+	if err := cfg.SetValue(key, value); err != nil {
+		return err
+	}
+	if err := b.db.SetGroupSetting(globalSettingsChatID, key, value); err != nil {
+		return fmt.Errorf("failed to save global setting %s: %w", key, err)
+	}
+
+	b.config.Store(&cfg)
+	return nil
 }
 
 func (b *Bot) GetDB() *database.DB {
@@ -345,7 +574,15 @@ func (b *Bot) GetEventLogger() *EventLogger {
 }
 
 func (b *Bot) Stop() error {
-	b.api.StopReceivingUpdates()
+	if b.GetConfig().Mode == config.ModeWebhook {
+		b.stopWebhook()
+	} else {
+		b.api.StopReceivingUpdates()
+	}
+	// Erst alle angenommenen Updates abarbeiten, dann Scheduler, API-Warteschlange und DB schließen
+	b.dispatcher.Stop()
+	b.scheduler.Stop()
+	b.queue.Close()
 	if b.logger != nil {
 		b.logger.Close()
 	}
@@ -355,11 +592,16 @@ func (b *Bot) Stop() error {
 	return b.db.Close()
 }
 
+// GetUserMention liefert eine Erwähnung für Nachrichten ohne ParseMode: @username oder den
+// Vornamen. Anklickbare Erwähnungen ohne Username erzeugt FormatMention.
 func GetUserMention(user *tgbotapi.User) string {
 	if user.UserName != "" {
 		return "@" + user.UserName
 	}
-	return fmt.Sprintf("[%s](tg://user?id=%d)", user.FirstName, user.ID)
+	if user.FirstName != "" {
+		return user.FirstName
+	}
+	return fmt.Sprintf("User %d", user.ID)
 }
 
 func FormatUserName(user *tgbotapi.User) string {
@@ -373,20 +615,27 @@ func FormatUserName(user *tgbotapi.User) string {
 	return name
 }
 
-func ParseDuration(input string) (int, error) {
+// ParseDuration liest eine Dauer mit Einheiten (siehe duration.Parse) und prüft sie gegen
+// die Grenzen min/max. Eine Zahl ohne Einheit wird wie bisher als Stunden verstanden.
+func ParseDuration(input string, min, max time.Duration) (time.Duration, error) {
 	input = strings.TrimSpace(input)
-	if input == "" {
-		return 1, nil
-	}
 
-	var hours int
-	if _, err := fmt.Sscanf(input, "%d", &hours); err != nil {
-		return 0, fmt.Errorf("invalid duration format")
+	var d time.Duration
+	if hours, err := strconv.Atoi(input); err == nil {
+		if hours < 1 {
+			return 0, i18n.NewError("duration.not_positive", i18n.Vars{"input": input})
+		}
+		d = time.Duration(hours) * time.Hour
+	} else {
+		d, err = duration.Parse(input)
+		if err != nil {
+			return 0, err
+		}
 	}
 
-	if hours < 1 || hours > 24*7 {
-		return 0, fmt.Errorf("duration must be between 1 hour and 7 days")
+	if err := duration.Check(d, min, max); err != nil {
+		return 0, err
 	}
 
-	return hours, nil
+	return d, nil
 }
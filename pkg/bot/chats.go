@@ -0,0 +1,210 @@
+package bot
+
+import (
+	"encoding/json"
+	"log"
+	"slices"
+	"sync"
+	"telegramBot/pkg/database"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// chatRegistry merkt sich, welcher Titel und Typ je Chat zuletzt gespeichert wurde,
+// damit nicht jede Nachricht einen Schreibzugriff auslöst
+type chatRegistry struct {
+	mu   sync.Mutex
+	seen map[int64]string
+	// unmanaged merkt sich für ManagedChats, wann ein User in einem Chat zuletzt ohne
+	// Rechte war. Admins stehen ohnehin in group_admins.
+	unmanaged map[chatMember]time.Time
+}
+
+type chatMember struct {
+	chatID int64
+	userID int64
+}
+
+// trackChat speichert Gruppen und Kanäle, aus denen ein Update kommt. Private Chats
+// werden nicht erfasst, sie stehen für einzelne User.
+func (b *Bot) trackChat(update tgbotapi.Update) {
+	// FromChat prüft CallbackQuery.Message nicht, Callbacks aus Inline-Nachrichten haben keine
+	if update.CallbackQuery != nil && update.CallbackQuery.Message == nil {
+		return
+	}
+	chat := update.FromChat()
+	if chat == nil || chat.IsPrivate() {
+		return
+	}
+
+	key := chat.Type + "\x00" + chat.Title
+	b.chats.mu.Lock()
+	defer b.chats.mu.Unlock()
+	if b.chats.seen == nil {
+		b.chats.seen = make(map[int64]string)
+	}
+	if b.chats.seen[chat.ID] == key {
+		return
+	}
+
+	if err := b.db.TouchKnownChat(chat.ID, chat.Title, chat.Type, time.Now()); err != nil {
+		log.Printf("Failed to store chat %d: %v", chat.ID, err)
+		return
+	}
+	b.chats.seen[chat.ID] = key
+}
+
+// recordMembership speichert, wann der Bot einem Chat beigetreten ist oder ihn verlassen hat,
+// und mit welchen Rechten er dort Admin ist
+func (b *Bot) recordMembership(event *tgbotapi.ChatMemberUpdated) {
+	if event.Chat.IsPrivate() {
+		return
+	}
+
+	at := time.Unix(int64(event.Date), 0)
+	chat := database.KnownChat{
+		ChatID:    event.Chat.ID,
+		Title:     event.Chat.Title,
+		Type:      event.Chat.Type,
+		BotStatus: event.NewChatMember.Status,
+		UpdatedAt: at,
+	}
+
+	stored, err := b.db.GetKnownChat(chat.ChatID)
+	if err != nil {
+		log.Printf("Failed to load chat %d: %v", chat.ChatID, err)
+	}
+	if stored != nil {
+		chat.JoinedAt = stored.JoinedAt
+	}
+
+	switch {
+	case !isInChat(event.NewChatMember):
+		chat.LeftAt = at
+		log.Printf("Bot left chat %d (%s)", chat.ChatID, chat.BotStatus)
+	case !isInChat(event.OldChatMember) || chat.JoinedAt.IsZero():
+		chat.JoinedAt = at
+		log.Printf("Bot joined chat %d as %s", chat.ChatID, chat.BotStatus)
+	}
+
+	if event.NewChatMember.IsAdministrator() || event.NewChatMember.IsCreator() {
+		permissions, err := json.Marshal(botPermissionsFromMember(event.NewChatMember))
+		if err != nil {
+			log.Printf("Failed to encode bot permissions of chat %d: %v", chat.ChatID, err)
+		}
+		chat.BotPermissions = string(permissions)
+	}
+
+	if err := b.db.SaveKnownChat(chat); err != nil {
+		log.Printf("Failed to store membership in chat %d: %v", chat.ChatID, err)
+	}
+
+	// Titel und Typ beim nächsten Update neu schreiben, falls sie sich geändert haben
+	b.chats.mu.Lock()
+	delete(b.chats.seen, chat.ChatID)
+	b.chats.mu.Unlock()
+}
+
+// isInChat prüft, ob ein Mitglied (noch) im Chat ist
+func isInChat(member tgbotapi.ChatMember) bool {
+	switch member.Status {
+	case "creator", "administrator", "member":
+		return true
+	case "restricted":
+		return member.IsMember
+	}
+	return false
+}
+
+// CanManageChat prüft, ob ein User in einer Gruppe irgendein Recht hat
+func (b *Bot) CanManageChat(chatID, userID int64) bool {
+	if role := b.BotRole(userID); role != "" && len(b.RolePermissions(chatID, role)) > 0 {
+		return true
+	}
+
+	roles, err := b.telegramRoles(chatID, userID)
+	if err != nil {
+		log.Printf("Failed to load member %d of chat %d: %v", userID, chatID, err)
+		return false
+	}
+	return slices.ContainsFunc(roles, func(role string) bool {
+		return len(b.RolePermissions(chatID, role)) > 0
+	})
+}
+
+// ManagedChats liefert die Gruppen, in denen der Bot ist und der User etwas verwalten darf.
+// Chats ohne Rechte werden für groupAdminTTL gemerkt, damit /groups nicht bei jedem Aufruf
+// getChatMember für jeden bekannten Chat über die Queue schickt.
+func (b *Bot) ManagedChats(userID int64) ([]database.KnownChat, error) {
+	chats, err := b.db.GetKnownChats()
+	if err != nil {
+		return nil, err
+	}
+
+	// Bot-Rollen brauchen keine Anfrage an Telegram, sie werden nicht zwischengespeichert
+	cache := b.BotRole(userID) == ""
+
+	var managed []database.KnownChat
+	for _, chat := range chats {
+		if chat.Type != "group" && chat.Type != "supergroup" {
+			continue
+		}
+		key := chatMember{chatID: chat.ChatID, userID: userID}
+		if cache && b.recentlyUnmanaged(key) && !b.isStoredGroupAdmin(key) {
+			continue
+		}
+		if b.CanManageChat(chat.ChatID, userID) {
+			managed = append(managed, chat)
+			continue
+		}
+		if !cache {
+			continue
+		}
+
+		b.chats.mu.Lock()
+		if b.chats.unmanaged == nil {
+			b.chats.unmanaged = make(map[chatMember]time.Time)
+		}
+		b.chats.unmanaged[key] = time.Now()
+		b.chats.mu.Unlock()
+	}
+	return managed, nil
+}
+
+// isStoredGroupAdmin prüft, ob der Abgleich den User inzwischen als Admin des Chats gespeichert hat
+func (b *Bot) isStoredGroupAdmin(key chatMember) bool {
+	stored, err := b.db.GetGroupAdmin(key.chatID, key.userID)
+	if err != nil {
+		log.Printf("Failed to load group admin %d of chat %d: %v", key.userID, key.chatID, err)
+		return false
+	}
+	return stored != nil
+}
+
+// recentlyUnmanaged prüft, ob der User im Chat vor weniger als groupAdminTTL keine Rechte hatte.
+// Abgelaufene Einträge werden dabei entfernt.
+func (b *Bot) recentlyUnmanaged(key chatMember) bool {
+	b.chats.mu.Lock()
+	defer b.chats.mu.Unlock()
+
+	checkedAt, ok := b.chats.unmanaged[key]
+	if !ok {
+		return false
+	}
+	if time.Since(checkedAt) >= groupAdminTTL {
+		delete(b.chats.unmanaged, key)
+		return false
+	}
+	return true
+}
+
+// SelectedChat liefert die per /groups gewählte Gruppe eines Users, ohne Auswahl 0
+func (b *Bot) SelectedChat(userID int64) int64 {
+	chatID, err := b.db.GetSelectedChat(userID)
+	if err != nil {
+		log.Printf("Failed to load selected chat of user %d: %v", userID, err)
+		return 0
+	}
+	return chatID
+}
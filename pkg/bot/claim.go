@@ -0,0 +1,130 @@
+package bot
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"telegramBot/pkg/database"
+	"telegramBot/pkg/i18n"
+	"time"
+)
+
+// claimTokenTTL bestimmt, wie lange ein Claim-Token gültig ist
+const claimTokenTTL = 30 * time.Minute
+
+// claimState hält das Token, mit dem der erste Owner den Bot übernimmt. Gespeichert
+// wird nur der Hash, das Token selbst steht einmalig im Log.
+type claimState struct {
+	mu        sync.Mutex
+	hash      []byte
+	expiresAt time.Time
+}
+
+// IssueClaimToken erzeugt ein neues Claim-Token, schreibt es ins Log und macht ein
+// vorheriges ungültig. Solange kein Bot-Admin existiert, ruft Start dies automatisch auf.
+func (b *Bot) IssueClaimToken() (string, error) {
+	b.claim.mu.Lock()
+	defer b.claim.mu.Unlock()
+	return b.issueClaimTokenLocked()
+}
+
+// issueClaimTokenLocked erwartet, dass b.claim.mu gehalten wird
+func (b *Bot) issueClaimTokenLocked() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate claim token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+	hash := sha256.Sum256([]byte(token))
+
+	b.claim.hash = hash[:]
+	b.claim.expiresAt = time.Now().Add(claimTokenTTL)
+
+	log.Printf("No bot admins yet. Send \"/claim %s\" to the bot in a private chat within %s to become owner.", token, claimTokenTTL)
+	return token, nil
+}
+
+// RenewClaimToken erzeugt ein neues Token, wenn das bisherige abgelaufen ist und der Bot
+// weiterhin keine Admins hat. So muss der Betreiber den Bot dafür nicht neu starten.
+func (b *Bot) RenewClaimToken() error {
+	b.claim.mu.Lock()
+	defer b.claim.mu.Unlock()
+
+	if b.claim.hash != nil && time.Now().Before(b.claim.expiresAt) {
+		return nil
+	}
+	return b.renewClaimTokenLocked()
+}
+
+// renewClaimTokenLocked erwartet, dass b.claim.mu gehalten wird
+func (b *Bot) renewClaimTokenLocked() error {
+	count, err := b.db.CountBotAdmins("")
+	if err != nil {
+		return fmt.Errorf("failed to count bot admins: %w", err)
+	}
+	if count > 0 {
+		b.claim.hash = nil
+		return nil
+	}
+	_, err = b.issueClaimTokenLocked()
+	return err
+}
+
+// RedeemClaimToken macht userID zum Owner, wenn das Token stimmt und noch gültig ist.
+// Das Token gilt nur einmal und wird erst nach erfolgreicher Vergabe der Rolle verworfen.
+func (b *Bot) RedeemClaimToken(token string, userID int64) error {
+	b.claim.mu.Lock()
+	defer b.claim.mu.Unlock()
+
+	if b.claim.hash == nil {
+		return i18n.NewError("claim.invalid")
+	}
+	if time.Now().After(b.claim.expiresAt) {
+		if err := b.renewClaimTokenLocked(); err != nil {
+			log.Printf("Failed to renew claim token: %v", err)
+		}
+		return i18n.NewError("claim.expired")
+	}
+	hash := sha256.Sum256([]byte(token))
+	if subtle.ConstantTimeCompare(hash[:], b.claim.hash) != 1 {
+		log.Printf("Invalid claim token from user %d", userID)
+		return i18n.NewError("claim.invalid")
+	}
+
+	// Gibt es inzwischen einen Admin (z.B. per Import), ist das Token hinfällig
+	count, err := b.db.CountBotAdmins("")
+	if err != nil {
+		return fmt.Errorf("failed to count bot admins: %w", err)
+	}
+	if count > 0 {
+		b.claim.hash = nil
+		return i18n.NewError("claim.not_needed")
+	}
+
+	// Schlägt die Vergabe fehl, bleibt das Token gültig und der Claim kann wiederholt werden
+	if err := b.GrantBotRole(userID, database.RoleOwner, userID, "claim token"); err != nil {
+		return err
+	}
+	b.claim.hash = nil
+	log.Printf("User %d claimed the bot and is now owner", userID)
+	return nil
+}
+
+// issueClaimTokenIfNeeded erzeugt beim Start ein Token, solange niemand den Bot verwaltet
+func (b *Bot) issueClaimTokenIfNeeded() {
+	count, err := b.db.CountBotAdmins("")
+	if err != nil {
+		log.Printf("Failed to count bot admins: %v", err)
+		return
+	}
+	if count > 0 {
+		return
+	}
+	if _, err := b.IssueClaimToken(); err != nil {
+		log.Printf("Failed to issue claim token: %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+package bot
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"telegramBot/config"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Standardwerte für config.DispatcherConfig
+const (
+	defaultDispatcherWorkers   = 8
+	defaultDispatcherQueueSize = 100
+
+	// backpressureLogInterval begrenzt die Warnungen bei voller Warteschlange
+	backpressureLogInterval = 30 * time.Second
+)
+
+// DispatcherStats sind Kennzahlen der Update-Verarbeitung
+type DispatcherStats struct {
+	Workers   int    // Anzahl der Worker
+	Queued    int    // aktuell wartende Updates über alle Worker
+	MaxQueued int    // höchste Anzahl wartender Updates seit dem Start
+	Processed uint64 // verarbeitete Updates
+	Dropped   uint64 // beim Beenden verworfene Updates
+	Blocked   uint64 // Updates, die auf einen freien Platz warten mussten
+}
+
+// Dispatcher verteilt Updates auf eine feste Anzahl von Workern. Alle Updates eines Chats
+// landen beim selben Worker und werden dadurch in Eingangsreihenfolge verarbeitet, z.B. der
+// Beitritt eines Users vor seiner Captcha-Antwort. Ist die Warteschlange eines Workers voll,
+// blockiert Submit; so bremst ein Spam-Schwall das Abholen neuer Updates statt beliebig viele
+// Goroutinen zu starten.
+type Dispatcher struct {
+	handle  func(tgbotapi.Update)
+	queues  []chan tgbotapi.Update
+	wg      sync.WaitGroup
+	mu      sync.RWMutex
+	closed  bool
+	quit    chan struct{}
+	stopped sync.Once
+
+	queued      atomic.Int64
+	maxQueued   atomic.Int64
+	processed   atomic.Uint64
+	dropped     atomic.Uint64
+	blocked     atomic.Uint64
+	lastWarning atomic.Int64
+}
+
+// NewDispatcher startet die Worker. Nicht gesetzte Werte in cfg bekommen die Standardwerte.
+func NewDispatcher(cfg config.DispatcherConfig, handle func(tgbotapi.Update)) *Dispatcher {
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = defaultDispatcherWorkers
+	}
+	queueSize := cfg.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultDispatcherQueueSize
+	}
+
+	d := &Dispatcher{
+		handle: handle,
+		queues: make([]chan tgbotapi.Update, workers),
+		quit:   make(chan struct{}),
+	}
+	for i := range d.queues {
+		d.queues[i] = make(chan tgbotapi.Update, queueSize)
+		d.wg.Add(1)
+		go d.work(d.queues[i])
+	}
+	return d
+}
+
+// Submit reiht ein Update beim Worker seines Chats ein und wartet, solange dessen Warteschlange
+// voll ist. Nach Stop werden Updates verworfen, das Ergebnis ist dann false.
+func (d *Dispatcher) Submit(update tgbotapi.Update) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		d.dropped.Add(1)
+		return false
+	}
+
+	queue := d.queues[shard(updateChatID(update), len(d.queues))]
+	d.trackQueued(1)
+
+	select {
+	case queue <- update:
+		return true
+	default:
+	}
+
+	// Warteschlange voll: Gegendruck bis ein Platz frei wird oder der Bot beendet wird
+	d.blocked.Add(1)
+	d.warnBackpressure(len(queue))
+	select {
+	case queue <- update:
+		return true
+	case <-d.quit:
+		d.trackQueued(-1)
+		d.dropped.Add(1)
+		return false
+	}
+}
+
+// Stop nimmt keine neuen Updates mehr an und wartet, bis alle eingereihten Updates
+// verarbeitet sind
+func (d *Dispatcher) Stop() {
+	d.stopped.Do(func() {
+		close(d.quit)
+
+		d.mu.Lock()
+		d.closed = true
+		for _, queue := range d.queues {
+			close(queue)
+		}
+		d.mu.Unlock()
+
+		d.wg.Wait()
+
+		stats := d.Stats()
+		log.Printf("Dispatcher stopped: %d updates processed, %d dropped, peak queue depth %d",
+			stats.Processed, stats.Dropped, stats.MaxQueued)
+	})
+}
+
+// Stats liefert die aktuellen Kennzahlen
+func (d *Dispatcher) Stats() DispatcherStats {
+	return DispatcherStats{
+		Workers:   len(d.queues),
+		Queued:    int(d.queued.Load()),
+		MaxQueued: int(d.maxQueued.Load()),
+		Processed: d.processed.Load(),
+		Dropped:   d.dropped.Load(),
+		Blocked:   d.blocked.Load(),
+	}
+}
+
+func (d *Dispatcher) work(queue <-chan tgbotapi.Update) {
+	defer d.wg.Done()
+
+	for update := range queue {
+		d.trackQueued(-1)
+		d.handle(update)
+		d.processed.Add(1)
+	}
+}
+
+func (d *Dispatcher) trackQueued(delta int64) {
+	queued := d.queued.Add(delta)
+	for {
+		peak := d.maxQueued.Load()
+		if queued <= peak || d.maxQueued.CompareAndSwap(peak, queued) {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) warnBackpressure(depth int) {
+	now := time.Now().UnixNano()
+	last := d.lastWarning.Load()
+	if now-last < int64(backpressureLogInterval) || !d.lastWarning.CompareAndSwap(last, now) {
+		return
+	}
+	log.Printf("Dispatcher queue full (%d updates waiting for one worker, %d in total), slowing down update intake",
+		depth, d.queued.Load())
+}
+
+// updateChatID bestimmt den Chat, nach dem Updates geordnet werden. Updates ohne Chat
+// (z.B. Inline-Buttons) werden nach dem User geordnet.
+func updateChatID(update tgbotapi.Update) int64 {
+	switch {
+	case update.Message != nil:
+		return update.Message.Chat.ID
+	case update.EditedMessage != nil:
+		return update.EditedMessage.Chat.ID
+	case update.ChannelPost != nil:
+		return update.ChannelPost.Chat.ID
+	case update.EditedChannelPost != nil:
+		return update.EditedChannelPost.Chat.ID
+	case update.CallbackQuery != nil && update.CallbackQuery.Message != nil:
+		return update.CallbackQuery.Message.Chat.ID
+	case update.MyChatMember != nil:
+		return update.MyChatMember.Chat.ID
+	case update.ChatMember != nil:
+		return update.ChatMember.Chat.ID
+	case update.ChatJoinRequest != nil:
+		return update.ChatJoinRequest.Chat.ID
+	}
+	if user := update.SentFrom(); user != nil {
+		return user.ID
+	}
+	return 0
+}
+
+func shard(chatID int64, n int) int {
+	if chatID < 0 {
+		chatID = -chatID
+	}
+	return int(chatID % int64(n))
+}
+
+// DispatcherStats liefert die Kennzahlen der Update-Verarbeitung
+func (b *Bot) DispatcherStats() DispatcherStats {
+	return b.dispatcher.Stats()
+}
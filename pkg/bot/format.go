@@ -0,0 +1,162 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"telegramBot/config"
+	"unicode/utf16"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// ParseMode liefert den Telegram-ParseMode zu einem Format aus config (plain = ohne ParseMode)
+func ParseMode(format string) string {
+	switch format {
+	case config.WelcomeFormatMarkdown:
+		return tgbotapi.ModeMarkdownV2
+	case config.WelcomeFormatHTML:
+		return tgbotapi.ModeHTML
+	}
+	return ""
+}
+
+// EscapeFormat maskiert einen Wert, damit er im jeweiligen Format als reiner Text erscheint
+func EscapeFormat(format, text string) string {
+	mode := ParseMode(format)
+	if mode == "" {
+		return text
+	}
+	return tgbotapi.EscapeText(mode, text)
+}
+
+// EscapeHTML maskiert Text für Nachrichten mit ParseMode HTML
+func EscapeHTML(text string) string {
+	return tgbotapi.EscapeText(tgbotapi.ModeHTML, text)
+}
+
+// FormatMention erzeugt eine Erwähnung im jeweiligen Format. Mit Markdown oder HTML ist sie
+// auch ohne Username anklickbar, ohne Formatierung entspricht sie GetUserMention.
+func FormatMention(user *tgbotapi.User, format string) string {
+	switch format {
+	case config.WelcomeFormatMarkdown:
+		return fmt.Sprintf("[%s](tg://user?id=%d)", EscapeFormat(format, user.FirstName), user.ID)
+	case config.WelcomeFormatHTML:
+		return fmt.Sprintf(`<a href="tg://user?id=%d">%s</a>`, user.ID, EscapeFormat(format, user.FirstName))
+	}
+	return GetUserMention(user)
+}
+
+// MessageHTML wandelt Text oder Bildunterschrift einer Nachricht samt Formatierung in HTML um
+func MessageHTML(message *tgbotapi.Message) string {
+	if message.Text != "" {
+		return EntitiesToHTML(message.Text, message.Entities)
+	}
+	return EntitiesToHTML(message.Caption, message.CaptionEntities)
+}
+
+// CommandArgumentsHTML liefert die Argumente eines Commands mit ihrer Formatierung als HTML
+func CommandArgumentsHTML(message *tgbotapi.Message) string {
+	args := message.CommandArguments()
+
+	// Entity-Offsets zählen UTF-16-Einheiten ab Textanfang
+	start := len(utf16.Encode([]rune(message.Text[:len(message.Text)-len(args)])))
+
+	var entities []tgbotapi.MessageEntity
+	for _, entity := range message.Entities {
+		if entity.Offset < start {
+			continue
+		}
+		entity.Offset -= start
+		entities = append(entities, entity)
+	}
+
+	return strings.TrimSpace(EntitiesToHTML(args, entities))
+}
+
+// EntitiesToHTML setzt Telegram-Entities (fett, kursiv, Links, ...) in HTML-Tags um.
+// Der übrige Text wird maskiert; Entities ohne Darstellung (z.B. Hashtags) bleiben reiner Text.
+func EntitiesToHTML(text string, entities []tgbotapi.MessageEntity) string {
+	units := utf16.Encode([]rune(text))
+
+	sorted := append([]tgbotapi.MessageEntity(nil), entities...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Offset != sorted[j].Offset {
+			return sorted[i].Offset < sorted[j].Offset
+		}
+		return sorted[i].Length > sorted[j].Length
+	})
+
+	var sb strings.Builder
+	var open []tgbotapi.MessageEntity
+	next := 0
+	for i := 0; ; {
+		// Innerste Entities zuerst schließen
+		for len(open) > 0 {
+			top := open[len(open)-1]
+			if top.Offset+top.Length > i {
+				break
+			}
+			_, closeTag := htmlTags(top)
+			sb.WriteString(closeTag)
+			open = open[:len(open)-1]
+		}
+
+		if i >= len(units) {
+			break
+		}
+
+		for next < len(sorted) && sorted[next].Offset <= i {
+			entity := sorted[next]
+			next++
+			openTag, _ := htmlTags(entity)
+			if openTag == "" || entity.Length <= 0 {
+				continue
+			}
+			sb.WriteString(openTag)
+			open = append(open, entity)
+		}
+
+		n := 1
+		if utf16.IsSurrogate(rune(units[i])) && i+1 < len(units) {
+			n = 2
+		}
+		sb.WriteString(EscapeHTML(string(utf16.Decode(units[i : i+n]))))
+		i += n
+	}
+
+	return sb.String()
+}
+
+// htmlTags liefert öffnenden und schließenden Tag einer Entity (leer, wenn sie nicht dargestellt wird)
+func htmlTags(entity tgbotapi.MessageEntity) (string, string) {
+	switch entity.Type {
+	case "bold":
+		return "<b>", "</b>"
+	case "italic":
+		return "<i>", "</i>"
+	case "underline":
+		return "<u>", "</u>"
+	case "strikethrough":
+		return "<s>", "</s>"
+	case "spoiler":
+		return "<tg-spoiler>", "</tg-spoiler>"
+	case "code":
+		return "<code>", "</code>"
+	case "pre":
+		if entity.Language != "" {
+			return fmt.Sprintf(`<pre><code class="language-%s">`, html.EscapeString(entity.Language)), "</code></pre>"
+		}
+		return "<pre>", "</pre>"
+	case "blockquote":
+		return "<blockquote>", "</blockquote>"
+	case "text_link":
+		return fmt.Sprintf(`<a href="%s">`, html.EscapeString(entity.URL)), "</a>"
+	case "text_mention":
+		if entity.User != nil {
+			return fmt.Sprintf(`<a href="tg://user?id=%d">`, entity.User.ID), "</a>"
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,242 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"telegramBot/pkg/database"
+	"telegramBot/pkg/i18n"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// groupAdminTTL bestimmt, wie lange gespeicherte Telegram-Rechte ohne neue Anfrage gelten
+const groupAdminTTL = 10 * time.Minute
+
+// reconcileInterval ist der Abstand zwischen zwei Läufen von JobReconcileAdmins
+const reconcileInterval = 30 * time.Minute
+
+// migrationRevokeSyncedAdmins markiert, dass revokeSyncedAdmins gelaufen ist
+const migrationRevokeSyncedAdmins = "revoke_synced_group_admins"
+
+// syncedAdminNotes sind die Notizen, mit denen frühere Versionen Gruppen-Admins
+// automatisch zu globalen Bot-Admins gemacht haben
+var syncedAdminNotes = []string{"sync: group admin", "auto admin: group admin"}
+
+// telegramRoles liefert die Telegram-Rollen eines Users in einer Gruppe. Gespeicherte Rechte
+// gelten für groupAdminTTL, danach wird Telegram gefragt und das Ergebnis gespeichert.
+func (b *Bot) telegramRoles(chatID, userID int64) ([]string, error) {
+	stored, err := b.db.GetGroupAdmin(chatID, userID)
+	if err != nil {
+		log.Printf("Failed to load group admin %d of chat %d: %v", userID, chatID, err)
+	}
+	if stored != nil && time.Since(stored.SyncedAt) < groupAdminTTL {
+		return stored.Roles, nil
+	}
+
+	member, err := b.api.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{
+			ChatID: chatID,
+			UserID: userID,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	roles := memberRoles(member)
+	if err := b.storeGroupAdmin(chatID, userID, member, roles); err != nil {
+		log.Printf("Failed to store group admin %d of chat %d: %v", userID, chatID, err)
+	}
+	return roles, nil
+}
+
+// memberRoles leitet die Telegram-Rollen aus den Admin-Rechten eines Mitglieds ab
+func memberRoles(member tgbotapi.ChatMember) []string {
+	switch member.Status {
+	case "creator":
+		return []string{TelegramCreator}
+	case "administrator":
+		var roles []string
+		if member.CanRestrictMembers {
+			roles = append(roles, TelegramRestrict)
+		}
+		if member.CanDeleteMessages {
+			roles = append(roles, TelegramDelete)
+		}
+		if member.CanChangeInfo {
+			roles = append(roles, TelegramChangeInfo)
+		}
+		if member.CanPromoteMembers {
+			roles = append(roles, TelegramPromote)
+		}
+		return roles
+	}
+	return nil
+}
+
+// storeGroupAdmin speichert Admins mit ihren Rollen und entfernt alle anderen
+func (b *Bot) storeGroupAdmin(chatID, userID int64, member tgbotapi.ChatMember, roles []string) error {
+	if !member.IsAdministrator() && !member.IsCreator() {
+		removed, err := b.db.RemoveGroupAdmin(chatID, userID)
+		if removed {
+			log.Printf("User %d is no longer an admin of chat %d, group rights removed", userID, chatID)
+		}
+		return err
+	}
+	return b.db.SetGroupAdmin(database.GroupAdmin{ChatID: chatID, UserID: userID, Roles: roles, SyncedAt: time.Now()})
+}
+
+// SyncGroupAdmins gleicht die gespeicherten Admins einer Gruppe mit Telegram ab. Die Rechte
+// gelten nur in dieser Gruppe, globale Bot-Rollen vergibt ausschließlich /add_admin.
+func (b *Bot) SyncGroupAdmins(chatID int64) (added, removed int, err error) {
+	admins, err := b.api.GetChatAdministrators(tgbotapi.ChatAdministratorsConfig{
+		ChatConfig: tgbotapi.ChatConfig{ChatID: chatID},
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get chat administrators: %w", err)
+	}
+
+	stored, err := b.db.GetGroupAdmins(chatID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to load group admins: %w", err)
+	}
+	known := make(map[int64]bool, len(stored))
+	for _, admin := range stored {
+		known[admin.UserID] = true
+	}
+
+	now := time.Now()
+	for _, admin := range admins {
+		if admin.User == nil || admin.User.IsBot {
+			continue
+		}
+		if err := b.db.SetGroupAdmin(database.GroupAdmin{
+			ChatID:   chatID,
+			UserID:   admin.User.ID,
+			Roles:    memberRoles(admin),
+			SyncedAt: now,
+		}); err != nil {
+			return added, removed, fmt.Errorf("failed to store group admin %d: %w", admin.User.ID, err)
+		}
+		if !known[admin.User.ID] {
+			added++
+		}
+		delete(known, admin.User.ID)
+	}
+
+	// Wer übrig bleibt, ist bei Telegram kein Admin mehr
+	for userID := range known {
+		if _, err := b.db.RemoveGroupAdmin(chatID, userID); err != nil {
+			return added, removed, fmt.Errorf("failed to remove group admin %d: %w", userID, err)
+		}
+		log.Printf("User %d is no longer an admin of chat %d, group rights removed", userID, chatID)
+		removed++
+	}
+
+	return added, removed, nil
+}
+
+// ReconcileGroupAdmins gleicht alle bekannten Gruppen mit Telegram ab. Globale Bot-Rollen
+// bleiben dabei unverändert.
+func (b *Bot) ReconcileGroupAdmins() {
+	chatIDs, err := b.db.GetGroupAdminChats()
+	if err != nil {
+		log.Printf("Failed to load chats with group admins: %v", err)
+		return
+	}
+
+	for _, chatID := range chatIDs {
+		added, removed, err := b.SyncGroupAdmins(chatID)
+		if err != nil {
+			log.Printf("Failed to reconcile admins of chat %d: %v", chatID, err)
+			continue
+		}
+		if added > 0 || removed > 0 {
+			log.Printf("Reconciled admins of chat %d: %d added, %d removed", chatID, added, removed)
+		}
+	}
+}
+
+// scheduleReconcile ersetzt einen eventuell noch gespeicherten Abgleich durch einen sofort fälligen
+func (b *Bot) scheduleReconcile() {
+	if err := b.db.RemoveJobs(JobReconcileAdmins, 0, 0); err != nil {
+		log.Printf("Failed to remove old reconcile job: %v", err)
+	}
+	if err := b.ScheduleJob(database.Job{Kind: JobReconcileAdmins}, 0); err != nil {
+		log.Printf("Failed to schedule admin reconciliation: %v", err)
+	}
+}
+
+func reconcileAdminsJob(b *Bot, job database.Job) error {
+	// Wie beim Purge: Fehler nur loggen, damit kein zweiter Job entsteht
+	b.ReconcileGroupAdmins()
+
+	if err := b.ScheduleJob(database.Job{Kind: JobReconcileAdmins}, reconcileInterval); err != nil {
+		log.Printf("Failed to reschedule admin reconciliation: %v", err)
+	}
+	return nil
+}
+
+// revokeSyncedAdmins entzieht einmalig die Bot-Rollen, die frühere Versionen Gruppen-Admins
+// automatisch gegeben haben. Globale Rechte brauchen eine ausdrückliche Vergabe; die Owner
+// erfahren beim Start per DM, wen das betrifft (siehe notifyRevokedAdmins).
+func (b *Bot) revokeSyncedAdmins() error {
+	applied, err := b.db.IsMigrationApplied(migrationRevokeSyncedAdmins)
+	if err != nil {
+		return fmt.Errorf("failed to check migration %s: %w", migrationRevokeSyncedAdmins, err)
+	}
+	if applied {
+		return nil
+	}
+
+	userIDs, err := b.db.GetBotAdminsGrantedWith(syncedAdminNotes...)
+	if err != nil {
+		return fmt.Errorf("failed to load synced bot admins: %w", err)
+	}
+
+	for _, userID := range userIDs {
+		if _, err := b.RevokeBotRole(userID, 0, "group admins are scoped to their group"); err != nil {
+			return err
+		}
+	}
+	if len(userIDs) > 0 {
+		log.Printf("Revoked global bot roles of %d automatically synced group admins: %v", len(userIDs), userIDs)
+		b.revokedAdmins = userIDs
+	}
+
+	if err := b.db.SetMigrationApplied(migrationRevokeSyncedAdmins, time.Now()); err != nil {
+		return fmt.Errorf("failed to mark migration %s: %w", migrationRevokeSyncedAdmins, err)
+	}
+	return nil
+}
+
+// notifyRevokedAdmins schreibt den Ownern, welchen Usern revokeSyncedAdmins die Rolle entzogen hat
+func (b *Bot) notifyRevokedAdmins() {
+	if len(b.revokedAdmins) == 0 {
+		return
+	}
+
+	ids := make([]string, len(b.revokedAdmins))
+	for i, userID := range b.revokedAdmins {
+		ids[i] = strconv.FormatInt(userID, 10)
+	}
+	b.revokedAdmins = nil
+
+	admins, err := b.db.GetBotAdmins()
+	if err != nil {
+		log.Printf("Failed to load bot owners: %v", err)
+		return
+	}
+	for _, admin := range admins {
+		if admin.Role != database.RoleOwner {
+			continue
+		}
+		text := b.ChatLocalizer(admin.UserID).T("admins.synced_revoked", i18n.Vars{"users": strings.Join(ids, ", ")})
+		if _, err := b.SendMessage(admin.UserID, text); err != nil {
+			log.Printf("Failed to notify owner %d about revoked admins: %v", admin.UserID, err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package bot
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"telegramBot/pkg/i18n"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Localizer liefert Texte in der Sprache eines Chats. Eigene Texte aus
+// message_templates haben Vorrang vor dem mitgelieferten Katalog.
+type Localizer struct {
+	bot       *Bot
+	chatID    int64
+	locale    string
+	overrides map[string]string
+}
+
+// Localizer wählt die Sprache für eine Antwort in chat: in Gruppen die Einstellung
+// "locale" der Gruppe, in DMs die Telegram-Sprache des Users.
+func (b *Bot) Localizer(chat *tgbotapi.Chat, user *tgbotapi.User) *Localizer {
+	if chat != nil && chat.Type != "private" {
+		return b.ChatLocalizer(chat.ID)
+	}
+	return b.UserLocalizer(user)
+}
+
+// ChatLocalizer liefert Texte für Nachrichten in einer Gruppe
+func (b *Bot) ChatLocalizer(chatID int64) *Localizer {
+	locale, _ := b.GetChatConfig(chatID).GetValue("locale")
+	return &Localizer{bot: b, chatID: chatID, locale: locale}
+}
+
+// UserLocalizer liefert Texte für DMs. Ohne passende Telegram-Sprache gilt die globale Einstellung.
+func (b *Bot) UserLocalizer(user *tgbotapi.User) *Localizer {
+	locale := ""
+	if user != nil {
+		locale = i18n.Match(user.LanguageCode)
+	}
+	if locale == "" {
+		locale, _ = b.GetConfig().GetValue("locale")
+	}
+	return &Localizer{bot: b, locale: locale}
+}
+
+func (l *Localizer) Locale() string {
+	return l.locale
+}
+
+// T liefert den Text zu key mit eingesetzten Platzhaltern
+func (l *Localizer) T(key string, vars ...i18n.Vars) string {
+	if l.overrides == nil {
+		overrides, err := l.bot.db.GetMessageTemplates(l.chatID, l.locale)
+		if err != nil {
+			log.Printf("Failed to load message templates for chat %d: %v", l.chatID, err)
+			overrides = map[string]string{}
+		}
+		l.overrides = overrides
+	}
+
+	if text, ok := l.overrides[key]; ok {
+		return i18n.Render(text, vars...)
+	}
+	return i18n.T(l.locale, key, vars...)
+}
+
+// Error übersetzt Fehler, die für den User bestimmt sind (i18n.Error), auch wenn sie mit
+// einem Präfix umhüllt wurden. Andere Fehler werden unverändert ausgegeben.
+func (l *Localizer) Error(err error) string {
+	var translatable *i18n.Error
+	if !errors.As(err, &translatable) {
+		return err.Error()
+	}
+	return strings.Replace(err.Error(), translatable.Error(), l.T(translatable.Key, translatable.Vars), 1)
+}
+
+// FormatTime formatiert einen Zeitpunkt im Datumsformat der Sprache (format.datetime)
+func (l *Localizer) FormatTime(t time.Time) string {
+	return t.Local().Format(l.T("format.datetime"))
+}
@@ -1,87 +1,212 @@
 package bot
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log"
-	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"telegramBot/config"
 	"time"
+	"unicode/utf8"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+// LogOptions steuern Format und Rotation einer Log-Datei
+type LogOptions struct {
+	Format      string        // config.LogFormatJSON (Standard) oder config.LogFormatText
+	MaxSize     int64         // Bytes, 0 = keine Größengrenze
+	RotateAfter time.Duration // 0 = keine Altersgrenze
+	MaxBackups  int           // 0 = alle rotierten Dateien behalten
+	HashSalt    string        // HMAC-Schlüssel für config.MessageLogHashed, leer = zufällig
+}
+
+// LogEntry ist eine Zeile im JSON-Lines-Format. Leere Felder werden weggelassen.
+type LogEntry struct {
+	Time       time.Time `json:"time"`
+	Type       string    `json:"type"`
+	ChatID     int64     `json:"chat_id"`
+	UserID     int64     `json:"user_id"`
+	Username   string    `json:"username,omitempty"`
+	Command    string    `json:"command,omitempty"`
+	Args       string    `json:"args,omitempty"`
+	Result     string    `json:"result,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS *int64    `json:"duration_ms,omitempty"`
+	Details    string    `json:"details,omitempty"`
+	MessageID  int       `json:"message_id,omitempty"`
+	Length     int       `json:"length,omitempty"`
+	TextHash   string    `json:"text_hash,omitempty"`
+	Text       string    `json:"text,omitempty"`
+}
+
+// Ergebnisse im Feld result von COMMAND-Einträgen
+const (
+	ResultSuccess = "SUCCESS"
+	ResultError   = "ERROR"
+)
+
 type CommandLogger struct {
-	logFile *os.File
+	out    *rotatingFile
+	format string
 }
 
 type EventLogger struct {
-	logFile *os.File
+	out      *rotatingFile
+	format   string
+	hashSalt []byte
 }
 
-func NewCommandLogger(filepath string) (*CommandLogger, error) {
-	file, err := os.OpenFile(filepath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+func NewCommandLogger(filepath string, opts LogOptions) (*CommandLogger, error) {
+	out, err := openRotatingFile(filepath, opts.MaxSize, opts.RotateAfter, opts.MaxBackups)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open log file: %w", err)
 	}
 
 	return &CommandLogger{
-		logFile: file,
+		out:    out,
+		format: opts.Format,
 	}, nil
 }
 
-func (cl *CommandLogger) LogCommand(chatID int64, userID int64, username, command, args, result string) {
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
+// LogCommand protokolliert einen ausgeführten Command mit Laufzeit und Ergebnis
+func (cl *CommandLogger) LogCommand(chatID int64, userID int64, username, command, args string, elapsed time.Duration, cmdErr error) {
+	result := ResultSuccess
+	var errText string
+	if cmdErr != nil {
+		result = ResultError
+		errText = cmdErr.Error()
+	}
 
-	logEntry := fmt.Sprintf("[%s] Chat: %d | User: %d (%s) | Command: %s %s | Result: %s\n",
-		timestamp, chatID, userID, username, command, args, result)
+	var line string
+	if cl.format == config.LogFormatText {
+		textResult := result
+		if cmdErr != nil {
+			textResult = ResultError + ": " + errText
+		}
+		line = fmt.Sprintf("[%s] Chat: %d | User: %d (%s) | Command: %s %s | Result: %s\n",
+			time.Now().Format("2006-01-02 15:04:05"), chatID, userID, textEscaper.Replace(username), command,
+			textEscaper.Replace(args), textEscaper.Replace(textResult))
+	} else {
+		ms := elapsed.Milliseconds()
+		line = jsonLine(LogEntry{
+			Time:       time.Now(),
+			Type:       "COMMAND",
+			ChatID:     chatID,
+			UserID:     userID,
+			Username:   username,
+			Command:    command,
+			Args:       args,
+			Result:     result,
+			Error:      errText,
+			DurationMS: &ms,
+		})
+	}
 
-	if _, err := cl.logFile.WriteString(logEntry); err != nil {
+	if err := cl.out.WriteLine(line); err != nil {
 		log.Printf("Failed to write to command log: %v", err)
-	} else {
-		cl.logFile.Sync()
 	}
 }
 
 func (cl *CommandLogger) Close() error {
-	if cl.logFile != nil {
-		return cl.logFile.Close()
+	if cl.out != nil {
+		return cl.out.Close()
 	}
 	return nil
 }
 
-func NewEventLogger(filepath string) (*EventLogger, error) {
-	file, err := os.OpenFile(filepath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+func NewEventLogger(filepath string, opts LogOptions) (*EventLogger, error) {
+	out, err := openRotatingFile(filepath, opts.MaxSize, opts.RotateAfter, opts.MaxBackups)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open event log file: %w", err)
 	}
 
+	salt := []byte(opts.HashSalt)
+	if len(salt) == 0 {
+		// Ohne festen Schlüssel sind Hashes nur innerhalb einer Laufzeit vergleichbar
+		salt = make([]byte, 32)
+		if _, err := rand.Read(salt); err != nil {
+			out.Close()
+			return nil, fmt.Errorf("failed to generate hash salt: %w", err)
+		}
+	}
+
 	return &EventLogger{
-		logFile: file,
+		out:      out,
+		format:   opts.Format,
+		hashSalt: salt,
 	}, nil
 }
 
 func (el *EventLogger) LogEvent(eventType string, chatID int64, userID int64, username string, details string) {
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	el.write(LogEntry{
+		Type:     eventType,
+		ChatID:   chatID,
+		UserID:   userID,
+		Username: username,
+		Details:  details,
+	})
+}
 
-	logEntry := fmt.Sprintf("[%s] %s | Chat: %d | User: %d (%s) | Details: %s\n",
-		timestamp, eventType, chatID, userID, username, details)
+// LogMessage protokolliert eine Nachricht je nach mode (config.MessageLog*).
+// Bei metadata und hashed wird der Text selbst nicht gespeichert.
+func (el *EventLogger) LogMessage(mode string, chatID int64, userID int64, username string, messageID int, messageText string) {
+	entry := LogEntry{
+		Type:      "MESSAGE",
+		ChatID:    chatID,
+		UserID:    userID,
+		Username:  username,
+		MessageID: messageID,
+		Length:    utf8.RuneCountInString(messageText),
+	}
 
-	if _, err := el.logFile.WriteString(logEntry); err != nil {
-		log.Printf("Failed to write to event log: %v", err)
-	} else {
-		el.logFile.Sync()
+	switch mode {
+	case config.MessageLogOff:
+		return
+	case config.MessageLogFull:
+		entry.Text = messageText
+	case config.MessageLogHashed:
+		if messageText != "" {
+			mac := hmac.New(sha256.New, el.hashSalt)
+			mac.Write([]byte(messageText))
+			entry.TextHash = hex.EncodeToString(mac.Sum(nil))
+		}
 	}
+
+	el.write(entry)
 }
 
-func (el *EventLogger) LogMessage(chatID int64, userID int64, username string, messageText string) {
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
+func (el *EventLogger) write(entry LogEntry) {
+	entry.Time = time.Now()
 
-	logEntry := fmt.Sprintf("[%s] MESSAGE | Chat: %d | User: %d (%s) | Text: %s\n",
-		timestamp, chatID, userID, username, messageText)
+	var line string
+	if el.format == config.LogFormatText {
+		label, value := "Details", entry.Details
+		if entry.Type == "MESSAGE" {
+			label, value = "Text", entry.Text
+			if entry.Text == "" {
+				label = "Message"
+				value = fmt.Sprintf("%d | Length: %d", entry.MessageID, entry.Length)
+				if entry.TextHash != "" {
+					value += " | Hash: " + entry.TextHash
+				}
+			}
+		}
+		line = fmt.Sprintf("[%s] %s | Chat: %d | User: %d (%s) | %s: %s\n",
+			entry.Time.Format("2006-01-02 15:04:05"), entry.Type, entry.ChatID, entry.UserID,
+			textEscaper.Replace(entry.Username), label, textEscaper.Replace(value))
+	} else {
+		line = jsonLine(entry)
+	}
 
-	if _, err := el.logFile.WriteString(logEntry); err != nil {
+	if err := el.out.WriteLine(line); err != nil {
 		log.Printf("Failed to write to event log: %v", err)
-	} else {
-		el.logFile.Sync()
 	}
 }
 
@@ -102,17 +227,101 @@ func (el *EventLogger) LogCaptchaFail(chatID int64, userID int64, username strin
 	el.LogEvent("CAPTCHA_FAIL", chatID, userID, username, reason)
 }
 
+func (el *EventLogger) LogRulesAccepted(chatID int64, userID int64, username string) {
+	el.LogEvent("RULES_ACCEPTED", chatID, userID, username, "User accepted the group rules")
+}
+
 func (el *EventLogger) LogKick(chatID int64, userID int64, username string, reason string) {
 	el.LogEvent("USER_KICKED", chatID, userID, username, reason)
 }
 
+func (el *EventLogger) LogBan(chatID int64, userID int64, username string, reason string) {
+	el.LogEvent("USER_BANNED", chatID, userID, username, reason)
+}
+
 func (el *EventLogger) Close() error {
-	if el.logFile != nil {
-		return el.logFile.Close()
+	if el.out != nil {
+		return el.out.Close()
 	}
 	return nil
 }
 
+// Purge entfernt alle Einträge, für die drop true liefert, auch aus rotierten Dateien
+func (el *EventLogger) Purge(drop func(LogEntry) bool) (int, error) {
+	return purgeLog(el.out, drop)
+}
+
+// Purge entfernt alle Einträge, für die drop true liefert, auch aus rotierten Dateien
+func (cl *CommandLogger) Purge(drop func(LogEntry) bool) (int, error) {
+	return purgeLog(cl.out, drop)
+}
+
+// purgeLog filtert eine Log-Datei und liefert die Anzahl entfernter Einträge.
+// Zeilen, die sich nicht lesen lassen, bleiben erhalten.
+func purgeLog(out *rotatingFile, drop func(LogEntry) bool) (int, error) {
+	// Ältere Klartext-Logs enthalten mehrzeilige Nachrichten unmaskiert. Folgezeilen ohne
+	// eigenen Kopf gehören zum Eintrag davor und werden mit ihm entfernt.
+	removed := 0
+	dropping := false
+	_, err := out.Filter(func(line string) bool {
+		entry, ok := parseLogLine(line)
+		if !ok {
+			return !dropping
+		}
+		dropping = drop(entry)
+		if dropping {
+			removed++
+		}
+		return !dropping
+	})
+	return removed, err
+}
+
+// textEscaper hält Klartext-Einträge auf einer Zeile, damit parseLogLine sie vollständig erkennt
+var textEscaper = strings.NewReplacer("\\", `\\`, "\n", `\n`, "\r", `\r`)
+
+// textLinePattern erkennt die Klartext-Zeilen beider Logs, Commands haben keinen Typ
+var textLinePattern = regexp.MustCompile(`^\[(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})\] (?:([A-Z_]+) \| )?Chat: (-?\d+) \| User: (-?\d+) `)
+
+// parseLogLine liest eine Zeile im JSON- oder Klartext-Format. Beide Formate können
+// in einer Datei vorkommen, wenn logging.format geändert wurde.
+func parseLogLine(line string) (LogEntry, bool) {
+	var entry LogEntry
+	if strings.HasPrefix(line, "{") {
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return entry, false
+		}
+		return entry, true
+	}
+
+	match := textLinePattern.FindStringSubmatch(line)
+	if match == nil {
+		return entry, false
+	}
+
+	timestamp, err := time.ParseInLocation("2006-01-02 15:04:05", match[1], time.Local)
+	if err != nil {
+		return entry, false
+	}
+	entry.Time = timestamp
+	entry.Type = match[2]
+	if entry.Type == "" {
+		entry.Type = "COMMAND"
+	}
+	entry.ChatID, _ = strconv.ParseInt(match[3], 10, 64)
+	entry.UserID, _ = strconv.ParseInt(match[4], 10, 64)
+	return entry, true
+}
+
+func jsonLine(entry LogEntry) string {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		// Kann bei diesem Struct nicht passieren, die Zeile soll trotzdem nicht verloren gehen
+		return fmt.Sprintf("{\"type\":%q,\"error\":%q}\n", entry.Type, err.Error())
+	}
+	return string(data) + "\n"
+}
+
 func GetUserIdentifier(user *tgbotapi.User) string {
 	if user.UserName != "" {
 		return "@" + user.UserName
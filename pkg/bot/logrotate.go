@@ -0,0 +1,405 @@
+package bot
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxLogLineSize begrenzt eine Zeile beim Filtern; Telegram-Nachrichten sind deutlich kürzer
+const maxLogLineSize = 1024 * 1024
+
+// rotatingFile ist eine Log-Datei, die ab einer Größe oder nach einer Zeitspanne
+// umbenannt und gzip-komprimiert wird. Danach wird eine neue Datei begonnen.
+type rotatingFile struct {
+	mu          sync.Mutex
+	path        string
+	file        *os.File
+	size        int64
+	openedAt    time.Time
+	maxSize     int64
+	rotateAfter time.Duration
+	maxBackups  int
+	closed      bool
+	filtering   bool       // Filter läuft, nicht rotieren
+	filterMu    sync.Mutex // ein Filter zur Zeit
+	compressing sync.WaitGroup
+}
+
+func openRotatingFile(path string, maxSize int64, rotateAfter time.Duration, maxBackups int) (*rotatingFile, error) {
+	rf := &rotatingFile{
+		path:        path,
+		maxSize:     maxSize,
+		rotateAfter: rotateAfter,
+		maxBackups:  maxBackups,
+	}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *rotatingFile) open() error {
+	file, err := os.OpenFile(rf.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	rf.file = file
+	rf.size = info.Size()
+	// Das Alter zählt ab dem Öffnen; eine bestehende Datei wird spätestens nach rotateAfter rotiert
+	rf.openedAt = time.Now()
+	return nil
+}
+
+// WriteLine schreibt einen Eintrag und rotiert vorher, falls nötig
+func (rf *rotatingFile) WriteLine(line string) error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.closed {
+		return fmt.Errorf("log file %s is closed", rf.path)
+	}
+	// Ist das Öffnen nach einer Rotation oder einem Filterlauf fehlgeschlagen, bei jedem Eintrag erneut versuchen
+	if rf.file == nil {
+		if err := rf.open(); err != nil {
+			return err
+		}
+	}
+
+	if rf.needsRotation(int64(len(line))) {
+		if err := rf.rotate(); err != nil {
+			log.Printf("Failed to rotate %s: %v", rf.path, err)
+		}
+		if rf.file == nil {
+			return fmt.Errorf("failed to reopen %s after rotation", rf.path)
+		}
+	}
+
+	n, err := rf.file.WriteString(line)
+	rf.size += int64(n)
+	if err != nil {
+		return err
+	}
+	return rf.file.Sync()
+}
+
+func (rf *rotatingFile) needsRotation(next int64) bool {
+	if rf.size == 0 || rf.filtering {
+		return false
+	}
+	if rf.maxSize > 0 && rf.size+next > rf.maxSize {
+		return true
+	}
+	return rf.rotateAfter > 0 && time.Since(rf.openedAt) >= rf.rotateAfter
+}
+
+func (rf *rotatingFile) rotate() error {
+	if err := rf.file.Close(); err != nil {
+		return err
+	}
+	rf.file = nil
+
+	rotated := fmt.Sprintf("%s.%s", rf.path, time.Now().Format("20060102-150405.000"))
+	if err := os.Rename(rf.path, rotated); err != nil {
+		// Umbenennen fehlgeschlagen: in die bisherige Datei weiterschreiben
+		if openErr := rf.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+
+	rf.compressRotated(rotated)
+
+	// Schlägt das Öffnen fehl, bleibt rf.file nil und WriteLine versucht es beim nächsten Eintrag erneut
+	return rf.open()
+}
+
+// compressRotated komprimiert eine rotierte Datei im Hintergrund und räumt danach alte Backups auf
+func (rf *rotatingFile) compressRotated(rotated string) {
+	rf.compressing.Add(1)
+	go func() {
+		defer rf.compressing.Done()
+		if err := compressFile(rotated); err != nil {
+			log.Printf("Failed to compress %s: %v", rotated, err)
+			return
+		}
+		rf.removeOldBackups()
+	}()
+}
+
+// compressFile schreibt path als path.gz und entfernt das Original
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		gz.Close()
+		dst.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	src.Close()
+	return os.Remove(path)
+}
+
+// removeOldBackups löscht die ältesten .gz-Dateien, wenn mehr als maxBackups vorhanden sind
+func (rf *rotatingFile) removeOldBackups() {
+	if rf.maxBackups <= 0 {
+		return
+	}
+
+	backups, err := filepath.Glob(rf.path + ".*.gz")
+	if err != nil {
+		return
+	}
+
+	// Der Zeitstempel im Namen sortiert chronologisch
+	sort.Strings(backups)
+	for len(backups) > rf.maxBackups {
+		if err := os.Remove(backups[0]); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to remove old log %s: %v", backups[0], err)
+		}
+		backups = backups[1:]
+	}
+}
+
+func (rf *rotatingFile) Close() error {
+	rf.mu.Lock()
+	rf.closed = true
+	var err error
+	if rf.file != nil {
+		err = rf.file.Close()
+		rf.file = nil
+	}
+	rf.mu.Unlock()
+
+	// Laufende Komprimierungen abschließen, damit keine halbfertigen .gz-Dateien bleiben
+	rf.compressing.Wait()
+	return err
+}
+
+// Filter entfernt alle Zeilen, für die keep false liefert, aus der aktuellen Datei und den
+// rotierten Dateien. Dateien ohne Treffer bleiben unverändert. rf.mu wird nur für den Austausch
+// der aktuellen Datei gehalten, damit WriteLine währenddessen nicht blockiert.
+func (rf *rotatingFile) Filter(keep func(line string) bool) (int, error) {
+	rf.filterMu.Lock()
+	defer rf.filterMu.Unlock()
+
+	// Während des Filterns nicht rotieren, sonst verschieben sich die Dateien unter dem Filter
+	rf.mu.Lock()
+	closed := rf.closed
+	rf.filtering = true
+	var offset int64
+	if info, err := os.Stat(rf.path); err == nil {
+		offset = info.Size()
+	}
+	rf.mu.Unlock()
+	defer func() {
+		rf.mu.Lock()
+		rf.filtering = false
+		rf.mu.Unlock()
+	}()
+
+	// Laufende Komprimierungen abwarten, sonst fehlt die gerade entstehende .gz-Datei
+	rf.compressing.Wait()
+
+	removed := 0
+	if !closed {
+		n, err := rf.filterCurrent(offset, keep)
+		removed += n
+		if err != nil {
+			return removed, err
+		}
+	}
+
+	// Auch Backups, deren Komprimierung fehlgeschlagen ist, liegen noch unkomprimiert vor
+	backups, err := filepath.Glob(rf.path + ".*")
+	if err != nil {
+		return removed, err
+	}
+	for _, backup := range backups {
+		if strings.HasSuffix(backup, ".tmp") {
+			continue
+		}
+		n, err := filterFile(backup, strings.HasSuffix(backup, ".gz"), keep)
+		removed += n
+		if err != nil {
+			return removed, fmt.Errorf("failed to filter %s: %w", backup, err)
+		}
+	}
+
+	return removed, nil
+}
+
+// filterCurrent filtert die ersten offset Bytes der aktuellen Datei ohne Sperre in eine
+// temporäre Datei. Unter rf.mu werden nur die seitdem angehängten Zeilen übernommen und
+// die Dateien getauscht.
+func (rf *rotatingFile) filterCurrent(offset int64, keep func(line string) bool) (int, error) {
+	src, err := os.Open(rf.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer src.Close()
+
+	tmpPath := rf.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+	discard := func(err error) (int, error) {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return 0, err
+	}
+
+	removed, err := filterLines(io.LimitReader(src, offset), tmp, keep)
+	if err != nil || removed == 0 {
+		return discard(err)
+	}
+
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	// Zeilen, die WriteLine während des Filterns geschrieben hat
+	if _, err := src.Seek(offset, io.SeekStart); err != nil {
+		return discard(err)
+	}
+	appended, err := filterLines(src, tmp, keep)
+	if err != nil {
+		return discard(err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return 0, err
+	}
+
+	if rf.file != nil {
+		if err := rf.file.Close(); err != nil {
+			os.Remove(tmpPath)
+			return 0, err
+		}
+		rf.file = nil
+	}
+	if err := os.Rename(tmpPath, rf.path); err != nil {
+		os.Remove(tmpPath)
+		return 0, err
+	}
+
+	// Schlägt das Öffnen fehl, versucht WriteLine es beim nächsten Eintrag erneut
+	openedAt := rf.openedAt
+	if err := rf.open(); err != nil {
+		return removed + appended, err
+	}
+	rf.openedAt = openedAt
+	return removed + appended, nil
+}
+
+// filterLines schreibt die behaltenen Zeilen von r nach w und zählt die entfernten
+func filterLines(r io.Reader, w io.Writer, keep func(line string) bool) (int, error) {
+	buffered := bufio.NewWriter(w)
+	removed := 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLogLineSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !keep(line) {
+			removed++
+			continue
+		}
+		if _, err := buffered.WriteString(line + "\n"); err != nil {
+			return 0, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return removed, buffered.Flush()
+}
+
+// filterFile schreibt die behaltenen Zeilen in eine temporäre Datei und ersetzt damit das Original
+func filterFile(path string, gzipped bool, keep func(line string) bool) (int, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer src.Close()
+
+	var reader io.Reader = src
+	if gzipped {
+		gz, err := gzip.NewReader(src)
+		if err != nil {
+			return 0, err
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	tmpPath := path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+
+	var writer io.Writer = tmp
+	var gzWriter *gzip.Writer
+	if gzipped {
+		gzWriter = gzip.NewWriter(tmp)
+		writer = gzWriter
+	}
+
+	removed, err := filterLines(reader, writer, keep)
+	if err == nil && gzWriter != nil {
+		err = gzWriter.Close()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil || removed == 0 {
+		os.Remove(tmpPath)
+		return 0, err
+	}
+
+	src.Close()
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return 0, err
+	}
+	return removed, nil
+}
@@ -0,0 +1,123 @@
+package bot
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// messageIndexSize begrenzt die gemerkten Nachrichten pro Chat
+	messageIndexSize = 2000
+	// messageIndexMaxAge entspricht der Grenze von Telegram: ältere Nachrichten kann ein Bot nicht mehr löschen
+	messageIndexMaxAge = 48 * time.Hour
+)
+
+// IndexedMessage ist eine Nachricht, die der Bot in einem Update gesehen hat
+type IndexedMessage struct {
+	ID       int
+	UserID   int64
+	Username string
+	Date     time.Time
+}
+
+// MessageIndex merkt sich pro Chat die zuletzt gesehenen Nachrichten samt Autor, damit /del und
+// /purge genau diese Nachrichten löschen können. Er liegt nur im Speicher, nach einem Neustart
+// kennt er nur die seitdem geschriebenen Nachrichten.
+type MessageIndex struct {
+	mu    sync.Mutex
+	chats map[int64][]IndexedMessage
+}
+
+func NewMessageIndex() *MessageIndex {
+	return &MessageIndex{
+		chats: make(map[int64][]IndexedMessage),
+	}
+}
+
+// Add nimmt eine Nachricht auf. Nachrichten bleiben nach ID sortiert, zu alte fallen heraus.
+func (idx *MessageIndex) Add(chatID int64, message IndexedMessage) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	messages := idx.chats[chatID]
+	i := sort.Search(len(messages), func(i int) bool { return messages[i].ID >= message.ID })
+	if i < len(messages) && messages[i].ID == message.ID {
+		return
+	}
+	messages = append(messages, IndexedMessage{})
+	copy(messages[i+1:], messages[i:])
+	messages[i] = message
+
+	cutoff := time.Now().Add(-messageIndexMaxAge)
+	start := 0
+	for start < len(messages) && messages[start].Date.Before(cutoff) {
+		start++
+	}
+	if len(messages)-start > messageIndexSize {
+		start = len(messages) - messageIndexSize
+	}
+	idx.chats[chatID] = messages[start:]
+}
+
+// Messages liefert eine Kopie der gemerkten Nachrichten eines Chats, die älteste zuerst
+func (idx *MessageIndex) Messages(chatID int64) []IndexedMessage {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return append([]IndexedMessage(nil), idx.chats[chatID]...)
+}
+
+// Remove vergisst gelöschte Nachrichten
+func (idx *MessageIndex) Remove(chatID int64, messageIDs []int) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	removed := make(map[int]bool, len(messageIDs))
+	for _, id := range messageIDs {
+		removed[id] = true
+	}
+
+	messages := idx.chats[chatID]
+	kept := messages[:0]
+	for _, message := range messages {
+		if !removed[message.ID] {
+			kept = append(kept, message)
+		}
+	}
+	idx.chats[chatID] = kept
+}
+
+// FindUser sucht die User-ID zu einem Username unter den Autoren der gemerkten Nachrichten
+func (idx *MessageIndex) FindUser(chatID int64, username string) (int64, bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	messages := idx.chats[chatID]
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Username != "" && strings.EqualFold(messages[i].Username, username) {
+			return messages[i].UserID, true
+		}
+	}
+	return 0, false
+}
+
+// indexMessage nimmt eine Gruppen-Nachricht aus einem Update in den Index auf
+func (b *Bot) indexMessage(message *tgbotapi.Message) {
+	if message.Chat.Type == "private" || message.From == nil {
+		return
+	}
+	b.messages.Add(message.Chat.ID, IndexedMessage{
+		ID:       message.MessageID,
+		UserID:   message.From.ID,
+		Username: message.From.UserName,
+		Date:     message.Time(),
+	})
+}
+
+// MessageIndex liefert den Index der zuletzt gesehenen Nachrichten
+func (b *Bot) MessageIndex() *MessageIndex {
+	return b.messages
+}
@@ -1,8 +1,13 @@
 package bot
 
 import (
+	"encoding/json"
 	"fmt"
+	"log"
 	"strings"
+	"telegramBot/pkg/database"
+	"telegramBot/pkg/i18n"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -27,6 +32,11 @@ func (b *Bot) GetBotPermissions(chatID int64) (*BotPermissions, error) {
 		return nil, fmt.Errorf("failed to get bot member info: %w", err)
 	}
 
+	return botPermissionsFromMember(botMember), nil
+}
+
+// botPermissionsFromMember liest die Rechte des Bots aus seinem Mitgliedseintrag
+func botPermissionsFromMember(botMember tgbotapi.ChatMember) *BotPermissions {
 	permissions := &BotPermissions{}
 
 	if botMember.Status == "administrator" {
@@ -45,10 +55,11 @@ func (b *Bot) GetBotPermissions(chatID int64) (*BotPermissions, error) {
 		permissions.CanPinMessages = true
 	}
 
-	return permissions, nil
+	return permissions
 }
 
-func (b *Bot) CheckRequiredPermissions(chatID int64) (bool, string, error) {
+// CheckRequiredPermissions prüft die Rechte des Bots in chatID und liefert einen Statustext in der Sprache von l
+func (b *Bot) CheckRequiredPermissions(chatID int64, l *Localizer) (bool, string, error) {
 	permissions, err := b.GetBotPermissions(chatID)
 	if err != nil {
 		return false, "", err
@@ -59,42 +70,178 @@ func (b *Bot) CheckRequiredPermissions(chatID int64) (bool, string, error) {
 
 	// Required permissions for basic functionality
 	if !permissions.CanDeleteMessages {
-		missing = append(missing, "Nachrichten loeschen")
+		missing = append(missing, l.T("permissions.delete_messages"))
 	} else {
-		current = append(current, "Nachrichten loeschen")
+		current = append(current, l.T("permissions.delete_messages"))
 	}
 
 	if !permissions.CanRestrictMembers {
-		missing = append(missing, "Mitglieder einschraenken")
+		missing = append(missing, l.T("permissions.restrict_members"))
 	} else {
-		current = append(current, "Mitglieder einschraenken")
+		current = append(current, l.T("permissions.restrict_members"))
 	}
 
 	// Optional but recommended permissions
 	if permissions.CanInviteUsers {
-		current = append(current, "Nutzer einladen")
+		current = append(current, l.T("permissions.invite_users"))
 	}
 
 	if permissions.CanPinMessages {
-		current = append(current, "Nachrichten anheften")
+		current = append(current, l.T("permissions.pin_messages"))
 	}
 
 	if permissions.CanChangeInfo {
-		current = append(current, "Gruppeninfo aendern")
+		current = append(current, l.T("permissions.change_info"))
 	}
 
 	allPermissions := len(missing) == 0
 
-	status := fmt.Sprintf("Bot-Berechtigung Status:\n\nVorhanden: %s\n", strings.Join(current, ", "))
+	status := l.T("permissions.status", i18n.Vars{"current": strings.Join(current, ", ")})
 	if len(missing) > 0 {
-		status += fmt.Sprintf("Fehlend: %s\n", strings.Join(missing, ", "))
-		status += "\nBitte gebe dem Bot folgende Admin-Rechte:\n"
-		status += "- Nachrichten loeschen\n"
-		status += "- Mitglieder bannen\n"
-		status += "- Mitglieder einschraenken"
+		status += l.T("permissions.status_missing", i18n.Vars{"missing": strings.Join(missing, ", ")})
 	} else {
-		status += "\nAlle erforderlichen Berechtigungen sind vorhanden."
+		status += l.T("permissions.status_complete")
 	}
 
 	return allPermissions, status, nil
 }
+
+// fallbackGroupPermissions gilt, wenn Telegram keine Standardrechte der Gruppe liefert
+var fallbackGroupPermissions = tgbotapi.ChatPermissions{
+	CanSendMessages:       true,
+	CanSendMediaMessages:  true,
+	CanSendPolls:          true,
+	CanSendOtherMessages:  true,
+	CanAddWebPagePreviews: true,
+}
+
+// GroupPermissions liest die Standardrechte der Mitglieder einer Gruppe per getChat
+func (b *Bot) GroupPermissions(chatID int64) (tgbotapi.ChatPermissions, error) {
+	chat, err := b.api.GetChat(tgbotapi.ChatInfoConfig{ChatConfig: tgbotapi.ChatConfig{ChatID: chatID}})
+	if err != nil {
+		return tgbotapi.ChatPermissions{}, fmt.Errorf("failed to get chat: %w", err)
+	}
+	if chat.Permissions == nil {
+		return tgbotapi.ChatPermissions{}, fmt.Errorf("chat %d has no default permissions", chatID)
+	}
+	return *chat.Permissions, nil
+}
+
+// RestrictMember schränkt einen User ein und merkt sich vorher seine bisherigen Rechte,
+// damit RestoreMember sie beim Aufheben exakt wiederherstellen kann
+func (b *Bot) RestrictMember(chatID, userID int64, permissions tgbotapi.ChatPermissions) error {
+	if err := b.snapshotPermissions(chatID, userID); err != nil {
+		log.Printf("Failed to snapshot permissions of user %d in chat %d: %v", userID, chatID, err)
+	}
+	return b.RestrictChatMember(chatID, userID, permissions)
+}
+
+// RestoreMember hebt eine Einschränkung des Bots auf. War der User vorher selbst eingeschränkt,
+// bekommt er genau diese Rechte zurück, sonst gelten wieder die Standardrechte der Gruppe.
+// limit begrenzt die Rechte zusätzlich (nil = keine Begrenzung).
+func (b *Bot) RestoreMember(chatID, userID int64, limit *tgbotapi.ChatPermissions) error {
+	permissions, err := b.previousPermissions(chatID, userID)
+	if err != nil {
+		return err
+	}
+	if limit != nil {
+		permissions = intersectPermissions(permissions, *limit)
+	}
+
+	if err := b.RestrictChatMember(chatID, userID, permissions); err != nil {
+		return err
+	}
+	return b.DiscardPermissionSnapshot(chatID, userID)
+}
+
+// DiscardPermissionSnapshot verwirft gemerkte Rechte, z.B. wenn der User die Gruppe verlassen musste
+func (b *Bot) DiscardPermissionSnapshot(chatID, userID int64) error {
+	if err := b.db.RemovePermissionSnapshot(chatID, userID); err != nil {
+		return fmt.Errorf("failed to remove permission snapshot: %w", err)
+	}
+	return nil
+}
+
+// snapshotPermissions speichert die aktuellen Rechte eines Users, sofern noch kein Snapshot existiert
+func (b *Bot) snapshotPermissions(chatID, userID int64) error {
+	existing, err := b.db.GetPermissionSnapshot(chatID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to load permission snapshot: %w", err)
+	}
+	if existing != nil {
+		return nil // Der Ursprungszustand ist schon gesichert
+	}
+
+	member, err := b.api.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: chatID, UserID: userID},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get chat member: %w", err)
+	}
+
+	snapshot := database.PermissionSnapshot{
+		ChatID:     chatID,
+		UserID:     userID,
+		Restricted: member.Status == "restricted",
+		CreatedAt:  time.Now(),
+	}
+	if snapshot.Restricted {
+		raw, err := json.Marshal(memberPermissions(member))
+		if err != nil {
+			return fmt.Errorf("failed to encode permissions: %w", err)
+		}
+		snapshot.Permissions = string(raw)
+	}
+
+	return b.db.SavePermissionSnapshot(snapshot)
+}
+
+// previousPermissions liefert die Rechte, die ein User vor der Einschränkung durch den Bot hatte
+func (b *Bot) previousPermissions(chatID, userID int64) (tgbotapi.ChatPermissions, error) {
+	snapshot, err := b.db.GetPermissionSnapshot(chatID, userID)
+	if err != nil {
+		return tgbotapi.ChatPermissions{}, fmt.Errorf("failed to load permission snapshot: %w", err)
+	}
+
+	if snapshot != nil && snapshot.Restricted {
+		var permissions tgbotapi.ChatPermissions
+		if err := json.Unmarshal([]byte(snapshot.Permissions), &permissions); err == nil {
+			return permissions, nil
+		}
+		log.Printf("Invalid permission snapshot of user %d in chat %d, using group defaults", userID, chatID)
+	}
+
+	permissions, err := b.GroupPermissions(chatID)
+	if err != nil {
+		log.Printf("Failed to get default permissions of chat %d, using fallback: %v", chatID, err)
+		return fallbackGroupPermissions, nil
+	}
+	return permissions, nil
+}
+
+// memberPermissions übernimmt die Rechte eines eingeschränkten Mitglieds
+func memberPermissions(member tgbotapi.ChatMember) tgbotapi.ChatPermissions {
+	return tgbotapi.ChatPermissions{
+		CanSendMessages:       member.CanSendMessages,
+		CanSendMediaMessages:  member.CanSendMediaMessages,
+		CanSendPolls:          member.CanSendPolls,
+		CanSendOtherMessages:  member.CanSendOtherMessages,
+		CanAddWebPagePreviews: member.CanAddWebPagePreviews,
+		CanChangeInfo:         member.CanChangeInfo,
+		CanInviteUsers:        member.CanInviteUsers,
+		CanPinMessages:        member.CanPinMessages,
+	}
+}
+
+func intersectPermissions(a, b tgbotapi.ChatPermissions) tgbotapi.ChatPermissions {
+	return tgbotapi.ChatPermissions{
+		CanSendMessages:       a.CanSendMessages && b.CanSendMessages,
+		CanSendMediaMessages:  a.CanSendMediaMessages && b.CanSendMediaMessages,
+		CanSendPolls:          a.CanSendPolls && b.CanSendPolls,
+		CanSendOtherMessages:  a.CanSendOtherMessages && b.CanSendOtherMessages,
+		CanAddWebPagePreviews: a.CanAddWebPagePreviews && b.CanAddWebPagePreviews,
+		CanChangeInfo:         a.CanChangeInfo && b.CanChangeInfo,
+		CanInviteUsers:        a.CanInviteUsers && b.CanInviteUsers,
+		CanPinMessages:        a.CanPinMessages && b.CanPinMessages,
+	}
+}
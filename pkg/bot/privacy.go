@@ -0,0 +1,109 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"telegramBot/config"
+	"telegramBot/pkg/database"
+	"telegramBot/pkg/duration"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// purgeInterval ist der Abstand zwischen zwei Läufen von JobPurgeMessages
+const purgeInterval = time.Hour
+
+// logMessage schreibt eine Nachricht mit dem message_logging-Modus des Chats
+func (b *Bot) logMessage(message *tgbotapi.Message) {
+	if message.From == nil {
+		return
+	}
+
+	mode, _ := b.GetChatConfig(message.Chat.ID).GetValue("message_logging")
+	if mode == config.MessageLogOff {
+		return
+	}
+
+	username := GetUserIdentifier(message.From)
+	b.eventLogger.LogMessage(mode, message.Chat.ID, message.From.ID, username, message.MessageID, message.Text)
+}
+
+// schedulePurge ersetzt einen eventuell noch gespeicherten Purge-Job durch einen sofort fälligen
+func (b *Bot) schedulePurge() {
+	if err := b.db.RemoveJobs(JobPurgeMessages, 0, 0); err != nil {
+		log.Printf("Failed to remove old purge job: %v", err)
+	}
+	if err := b.ScheduleJob(database.Job{Kind: JobPurgeMessages}, 0); err != nil {
+		log.Printf("Failed to schedule message purge: %v", err)
+	}
+}
+
+func purgeMessagesJob(b *Bot, job database.Job) error {
+	// Fehler nur loggen: ein Retry würde neben dem neu geplanten Job einen zweiten erzeugen
+	removed, err := b.PurgeExpiredMessages(time.Now())
+	if err != nil {
+		log.Printf("Failed to purge expired messages: %v", err)
+	} else if removed > 0 {
+		log.Printf("Purged %d expired message log entries", removed)
+	}
+
+	if err := b.ScheduleJob(database.Job{Kind: JobPurgeMessages}, purgeInterval); err != nil {
+		log.Printf("Failed to reschedule message purge: %v", err)
+	}
+	return nil
+}
+
+// PurgeExpiredMessages entfernt MESSAGE-Einträge, die älter als message_retention_days
+// ihres Chats sind. Andere Events bleiben erhalten.
+func (b *Bot) PurgeExpiredMessages(now time.Time) (int, error) {
+	retention := make(map[int64]time.Duration)
+
+	return b.eventLogger.Purge(func(entry LogEntry) bool {
+		if entry.Type != "MESSAGE" {
+			return false
+		}
+
+		keep, ok := retention[entry.ChatID]
+		if !ok {
+			keep = time.Duration(b.GetChatConfig(entry.ChatID).Logging.MessageRetentionDays) * duration.Day
+			retention[entry.ChatID] = keep
+		}
+		return keep > 0 && now.Sub(entry.Time) > keep
+	})
+}
+
+// sanctionEvents bleiben bei /forgetme in events.log, wie Verwarnungen, Banns, Mutes und der
+// Moderationsverlauf in der Datenbank (siehe forgetUserTables)
+var sanctionEvents = map[string]bool{
+	"USER_KICKED":  true,
+	"USER_BANNED":  true,
+	"CAPTCHA_FAIL": true,
+	"FLOOD":        true,
+}
+
+// ForgetUser entfernt die Einträge eines Users aus commands.log und events.log (inklusive
+// rotierter Dateien) außer Sanktions-Events sowie seine Datenbankzeilen außer Sanktionen.
+// Zurückgegeben werden die Anzahl gelöschter Log-Einträge und Datenbankzeilen.
+func (b *Bot) ForgetUser(userID int64) (int, int64, error) {
+	events, err := b.eventLogger.Purge(func(entry LogEntry) bool {
+		return entry.UserID == userID && !sanctionEvents[entry.Type]
+	})
+	if err != nil {
+		return events, 0, fmt.Errorf("failed to purge event log: %w", err)
+	}
+
+	commands, err := b.logger.Purge(func(entry LogEntry) bool {
+		return entry.UserID == userID
+	})
+	if err != nil {
+		return events + commands, 0, fmt.Errorf("failed to purge command log: %w", err)
+	}
+
+	rows, err := b.db.ForgetUser(userID)
+	if err != nil {
+		return events + commands, rows, fmt.Errorf("failed to delete user data: %w", err)
+	}
+
+	return events + commands, rows, nil
+}
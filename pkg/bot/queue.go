@@ -0,0 +1,337 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"telegramBot/config"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Standardwerte für config.RateLimitConfig, angelehnt an die Grenzen der Bot API
+const (
+	defaultGlobalPerSecond  = 30
+	defaultGroupPerMinute   = 20
+	defaultPrivatePerSecond = 1
+	defaultMaxRetries       = 3
+
+	// privateBurst erlaubt in Privatchats kurze Folgen wie Hilfe-Seiten ohne Wartezeit
+	privateBurst = 3
+	// retryBaseDelay ist die erste Wartezeit nach einem vorübergehenden Fehler, sie verdoppelt sich pro Versuch
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 10 * time.Second
+)
+
+// errQueueClosed wird an wartende Anfragen geliefert, wenn der Bot beendet wird
+var errQueueClosed = errors.New("request queue closed")
+
+// RequestQueue ist die zentrale Warteschlange für alle ausgehenden Anfragen an die Bot API.
+// Sie sitzt als HTTP-Client unter tgbotapi und erfasst damit jeden Aufruf, egal ob über die
+// Helfer von Bot oder direkt über GetAPI():
+//
+//   - global höchstens global_per_second Anfragen pro Sekunde
+//   - pro Chat höchstens group_per_minute Nachrichten pro Minute in Gruppen bzw.
+//     private_per_second pro Sekunde in Privatchats (nur sendende Methoden)
+//   - bei 429 wird retry_after abgewartet und die Anfrage erneut gestellt
+//   - Netzwerkfehler und 5xx-Antworten werden mit Backoff wiederholt
+//
+// Anfragen warten in der Reihenfolge ihres Eintreffens auf einen freien Platz.
+type RequestQueue struct {
+	client     tgbotapi.HTTPClient
+	cfg        config.RateLimitConfig
+	mu         sync.Mutex
+	global     *tokenBucket
+	chats      map[string]*tokenBucket
+	done       chan struct{}
+	closeOnce  sync.Once
+	retryDelay time.Duration
+}
+
+// NewRequestQueue legt die Warteschlange vor einen HTTP-Client. Nicht gesetzte Werte in cfg
+// bekommen die Standardwerte.
+func NewRequestQueue(client tgbotapi.HTTPClient, cfg config.RateLimitConfig) *RequestQueue {
+	if cfg.GlobalPerSecond <= 0 {
+		cfg.GlobalPerSecond = defaultGlobalPerSecond
+	}
+	if cfg.GroupPerMinute <= 0 {
+		cfg.GroupPerMinute = defaultGroupPerMinute
+	}
+	if cfg.PrivatePerSecond <= 0 {
+		cfg.PrivatePerSecond = defaultPrivatePerSecond
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = defaultMaxRetries
+	}
+
+	return &RequestQueue{
+		client:     client,
+		cfg:        cfg,
+		global:     newTokenBucket(float64(cfg.GlobalPerSecond), float64(cfg.GlobalPerSecond)),
+		chats:      make(map[string]*tokenBucket),
+		done:       make(chan struct{}),
+		retryDelay: retryBaseDelay,
+	}
+}
+
+// Close bricht alle wartenden Anfragen ab, neue Anfragen schlagen sofort fehl
+func (q *RequestQueue) Close() {
+	q.closeOnce.Do(func() { close(q.done) })
+}
+
+// Do implementiert tgbotapi.HTTPClient
+func (q *RequestQueue) Do(req *http.Request) (*http.Response, error) {
+	method := path.Base(req.URL.Path)
+	// Long Polling hält die Verbindung offen und zählt nicht gegen die Grenzen
+	if method == "getUpdates" {
+		return q.client.Do(req)
+	}
+
+	// Der Body wird für Wiederholungen gepuffert
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+	chatID := requestChatID(req.Header.Get("Content-Type"), body)
+
+	for attempt := 0; ; attempt++ {
+		if err := q.wait(method, chatID); err != nil {
+			return nil, err
+		}
+
+		retry := req.Clone(req.Context())
+		retry.Body = io.NopCloser(bytes.NewReader(body))
+		retry.ContentLength = int64(len(body))
+
+		resp, err := q.client.Do(retry)
+		if attempt >= q.cfg.MaxRetries {
+			return resp, err
+		}
+
+		delay, retryable := q.retryDelayFor(method, resp, err, attempt)
+		if !retryable {
+			return resp, err
+		}
+		if resp != nil {
+			if retryAfter := readRetryAfter(resp); retryAfter > 0 {
+				delay = retryAfter
+				q.block(chatID, retryAfter)
+				log.Printf("Rate limited on %s (chat %s), retrying in %s", method, chatOrGlobal(chatID), delay)
+			} else {
+				log.Printf("%s failed with HTTP %d, retrying in %s", method, resp.StatusCode, delay)
+			}
+		} else {
+			log.Printf("%s failed: %v, retrying in %s", method, err, delay)
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-q.done:
+			return nil, errQueueClosed
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// retryDelayFor entscheidet, ob eine Antwort wiederholt wird, und liefert die Backoff-Wartezeit.
+// Sendende Methoden werden nur nach 429 oder einem Verbindungsfehler wiederholt: bei einem
+// Timeout oder 5xx kann Telegram die Nachricht bereits zugestellt haben.
+func (q *RequestQueue) retryDelayFor(method string, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	backoff := q.retryDelay << attempt
+	if backoff > retryMaxDelay {
+		backoff = retryMaxDelay
+	}
+
+	send := isSendMethod(method)
+	if err != nil {
+		return backoff, !send || neverSent(err)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || (!send && resp.StatusCode >= 500) {
+		return backoff, true
+	}
+	return 0, false
+}
+
+// neverSent meldet Fehler, bei denen die Anfrage den Server sicher nicht erreicht hat
+func neverSent(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// wait reserviert einen Platz im globalen Limit und, bei sendenden Methoden, im Limit des Chats
+func (q *RequestQueue) wait(method, chatID string) error {
+	now := time.Now()
+
+	q.mu.Lock()
+	delay := q.global.reserve(now)
+	if chatID != "" {
+		if chatDelay := q.chatBucket(chatID, isSendMethod(method)).reserve(now); chatDelay > delay {
+			delay = chatDelay
+		}
+	}
+	q.mu.Unlock()
+
+	if delay <= 0 {
+		select {
+		case <-q.done:
+			return errQueueClosed
+		default:
+			return nil
+		}
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-q.done:
+		return errQueueClosed
+	}
+}
+
+// chatBucket liefert das Limit eines Chats. Nicht sendende Methoden beachten nur eine Sperre
+// aus retry_after, verbrauchen aber kein Kontingent.
+func (q *RequestQueue) chatBucket(chatID string, send bool) *tokenBucket {
+	bucket, ok := q.chats[chatID]
+	if !ok {
+		if strings.HasPrefix(chatID, "-") {
+			bucket = newTokenBucket(float64(q.cfg.GroupPerMinute)/60, float64(q.cfg.GroupPerMinute))
+		} else {
+			bucket = newTokenBucket(float64(q.cfg.PrivatePerSecond), privateBurst)
+		}
+		q.chats[chatID] = bucket
+	}
+	if !send {
+		return &tokenBucket{blockedUntil: bucket.blockedUntil}
+	}
+	return bucket
+}
+
+// block sperrt nach einer 429-Antwort den Chat bzw. ohne Chat alle Anfragen
+func (q *RequestQueue) block(chatID string, retryAfter time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	until := time.Now().Add(retryAfter)
+	bucket := q.global
+	if chatID != "" {
+		bucket = q.chatBucket(chatID, true)
+	}
+	if until.After(bucket.blockedUntil) {
+		bucket.blockedUntil = until
+	}
+}
+
+// tokenBucket ist ein einfacher Token-Bucket mit Reservierung: jede Anfrage nimmt sofort ein
+// Token und wartet, bis es nachgefüllt ist. So bleibt die Reihenfolge der Anfragen erhalten.
+type tokenBucket struct {
+	tokens       float64
+	rate         float64 // Tokens pro Sekunde
+	burst        float64
+	last         time.Time
+	blockedUntil time.Time
+}
+
+func newTokenBucket(rate, burst float64) *tokenBucket {
+	return &tokenBucket{tokens: burst, rate: rate, burst: burst, last: time.Now()}
+}
+
+// reserve verbraucht ein Token und liefert die Wartezeit bis zur Ausführung
+func (bk *tokenBucket) reserve(now time.Time) time.Duration {
+	var delay time.Duration
+	if bk.rate > 0 {
+		bk.tokens += now.Sub(bk.last).Seconds() * bk.rate
+		if bk.tokens > bk.burst {
+			bk.tokens = bk.burst
+		}
+		bk.last = now
+		bk.tokens--
+		if bk.tokens < 0 {
+			delay = time.Duration(-bk.tokens / bk.rate * float64(time.Second))
+		}
+	}
+	if blocked := bk.blockedUntil.Sub(now); blocked > delay {
+		delay = blocked
+	}
+	return delay
+}
+
+// isSendMethod erkennt Methoden, die eine Nachricht im Chat erzeugen oder ändern
+func isSendMethod(method string) bool {
+	return strings.HasPrefix(method, "send") ||
+		strings.HasPrefix(method, "editMessage") ||
+		method == "copyMessage" ||
+		method == "forwardMessage"
+}
+
+// requestChatID liest chat_id aus einem Formular- oder Multipart-Body
+func requestChatID(contentType string, body []byte) string {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return ""
+		}
+		return values.Get("chat_id")
+
+	case "multipart/form-data":
+		reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				return ""
+			}
+			if part.FormName() == "chat_id" {
+				value, _ := io.ReadAll(part)
+				return string(value)
+			}
+		}
+	}
+	return ""
+}
+
+// readRetryAfter liest parameters.retry_after aus einer Fehlerantwort und schließt den Body
+func readRetryAfter(resp *http.Response) time.Duration {
+	defer resp.Body.Close()
+
+	var apiResp tgbotapi.APIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil || apiResp.Parameters == nil {
+		return 0
+	}
+	return time.Duration(apiResp.Parameters.RetryAfter) * time.Second
+}
+
+func chatOrGlobal(chatID string) string {
+	if chatID == "" {
+		return "global"
+	}
+	return chatID
+}
@@ -0,0 +1,173 @@
+package bot
+
+import (
+	"log"
+	"sync"
+	"telegramBot/pkg/database"
+	"time"
+)
+
+// Job-Arten, die der Scheduler kennt
+const (
+	JobDeleteMessage   = "delete_message"
+	JobUnmute          = "unmute"
+	JobKickPending     = "kick_pending"
+	JobUnban           = "unban"
+	JobPurgeMessages   = "purge_messages"
+	JobReconcileAdmins = "reconcile_admins"
+)
+
+const (
+	schedulerInterval = time.Second
+	jobMaxAttempts    = 3
+	jobRetryDelay     = time.Minute
+)
+
+// JobHandler führt einen fälligen Job aus
+type JobHandler func(b *Bot, job database.Job) error
+
+// Scheduler führt in der Datenbank gespeicherte Jobs aus. Da die Jobs persistiert
+// werden, gehen sie bei einem Neustart nicht verloren; überfällige Jobs werden beim
+// nächsten Durchlauf sofort nachgeholt.
+type Scheduler struct {
+	bot      *Bot
+	mu       sync.RWMutex
+	handlers map[string]JobHandler
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func NewScheduler(b *Bot) *Scheduler {
+	s := &Scheduler{
+		bot:      b,
+		handlers: make(map[string]JobHandler),
+	}
+
+	s.RegisterHandler(JobDeleteMessage, func(b *Bot, job database.Job) error {
+		return b.DeleteMessage(job.ChatID, job.MessageID)
+	})
+	s.RegisterHandler(JobUnban, func(b *Bot, job database.Job) error {
+		return b.UnbanChatMember(job.ChatID, job.UserID)
+	})
+	s.RegisterHandler(JobPurgeMessages, purgeMessagesJob)
+	s.RegisterHandler(JobReconcileAdmins, reconcileAdminsJob)
+
+	return s
+}
+
+func (s *Scheduler) RegisterHandler(kind string, handler JobHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[kind] = handler
+}
+
+// Schedule speichert einen Job, der nach delay ausgeführt wird
+func (s *Scheduler) Schedule(job database.Job, delay time.Duration) error {
+	job.RunAt = time.Now().Add(delay)
+	_, err := s.bot.db.AddJob(job)
+	return err
+}
+
+func (s *Scheduler) Start() {
+	if s.stop != nil {
+		return
+	}
+
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go s.run()
+}
+
+func (s *Scheduler) Stop() {
+	if s.stop == nil {
+		return
+	}
+
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+}
+
+func (s *Scheduler) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+
+	// Beim Start sofort überfällige Jobs nachholen
+	s.runDueJobs()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.runDueJobs()
+		}
+	}
+}
+
+func (s *Scheduler) runDueJobs() {
+	jobs, err := s.bot.db.GetDueJobs(time.Now())
+	if err != nil {
+		log.Printf("Scheduler: failed to load due jobs: %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		s.runJob(job)
+	}
+}
+
+func (s *Scheduler) runJob(job database.Job) {
+	s.mu.RLock()
+	handler, exists := s.handlers[job.Kind]
+	s.mu.RUnlock()
+
+	if !exists {
+		log.Printf("Scheduler: no handler for job %d (%s), dropping it", job.ID, job.Kind)
+		s.bot.db.RemoveJob(job.ID)
+		return
+	}
+
+	if err := handler(s.bot, job); err != nil {
+		if job.Attempts+1 < jobMaxAttempts {
+			log.Printf("Scheduler: job %d (%s) failed, retrying: %v", job.ID, job.Kind, err)
+			s.bot.db.RescheduleJob(job.ID, time.Now().Add(jobRetryDelay))
+			return
+		}
+		log.Printf("Scheduler: job %d (%s) failed permanently: %v", job.ID, job.Kind, err)
+	}
+
+	if err := s.bot.db.RemoveJob(job.ID); err != nil {
+		log.Printf("Scheduler: failed to remove job %d: %v", job.ID, err)
+	}
+}
+
+// RegisterJobHandler registriert die Ausführung für eine Job-Art
+func (b *Bot) RegisterJobHandler(kind string, handler JobHandler) {
+	b.scheduler.RegisterHandler(kind, handler)
+}
+
+// ScheduleJob plant einen Job, der nach delay ausgeführt wird
+func (b *Bot) ScheduleJob(job database.Job, delay time.Duration) error {
+	return b.scheduler.Schedule(job, delay)
+}
+
+// ScheduleMessageDeletion löscht eine Nachricht nach delay, auch über Neustarts hinweg
+func (b *Bot) ScheduleMessageDeletion(chatID int64, messageID int, delay time.Duration) {
+	job := database.Job{
+		Kind:      JobDeleteMessage,
+		ChatID:    chatID,
+		MessageID: messageID,
+	}
+	if err := b.scheduler.Schedule(job, delay); err != nil {
+		log.Printf("Failed to schedule deletion of message %d: %v", messageID, err)
+	}
+}
+
+// CancelJobs entfernt alle geplanten Jobs einer Art für einen User in einem Chat
+func (b *Bot) CancelJobs(kind string, chatID, userID int64) error {
+	return b.db.RemoveJobs(kind, chatID, userID)
+}
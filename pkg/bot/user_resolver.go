@@ -1,9 +1,9 @@
 package bot
 
 import (
-	"fmt"
 	"strconv"
 	"strings"
+	"telegramBot/pkg/i18n"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -23,7 +23,7 @@ func (b *Bot) ResolveUser(chatID int64, target string) (int64, error) {
 		return b.findUserByUsername(chatID, username)
 	}
 
-	return 0, fmt.Errorf("Ungültiges Format: %s (verwende @username oder User-ID)", target)
+	return 0, i18n.NewError("user.invalid_format", i18n.Vars{"input": target})
 }
 
 // findUserByUsername tries to find a user ID by username using Telegram API
@@ -42,13 +42,13 @@ func (b *Bot) findUserByUsername(chatID int64, username string) (int64, error) {
 
 	// Fallback: Username resolution not directly possible with Bot API
 	// User must use reply-to-message or provide user ID
-	return 0, fmt.Errorf("Username @%s konnte nicht aufgelöst werden. Verwende 'Auf Nachricht antworten' oder User-ID", username)
+	return 0, i18n.NewError("user.unresolved", i18n.Vars{"username": username})
 }
 
 // ValidateUserID checks if a user ID is valid and not a bot
 func (b *Bot) ValidateUserID(userID int64) error {
 	if userID <= 0 {
-		return fmt.Errorf("Ungültige User-ID: %d", userID)
+		return i18n.NewError("user.invalid_id", i18n.Vars{"id": userID})
 	}
 
 	// Additional validation could be added here
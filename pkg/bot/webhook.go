@@ -0,0 +1,165 @@
+package bot
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// webhookServer nimmt Updates per HTTP entgegen und reicht sie an denselben
+// Channel weiter, den sonst das Long Polling befüllt
+type webhookServer struct {
+	server  *http.Server
+	updates chan tgbotapi.Update
+	done    chan struct{} // wird bei stopWebhook geschlossen, wartende Handler geben auf
+
+	// Handler senden nur unter RLock; closed wird erst unter Lock gesetzt,
+	// damit updates nie geschlossen wird, während noch jemand sendet
+	mu     sync.RWMutex
+	closed bool
+}
+
+// startWebhook öffnet den HTTP-Listener und registriert den Webhook bei Telegram
+func (b *Bot) startWebhook() (tgbotapi.UpdatesChannel, error) {
+	cfg := b.GetConfig().Webhook
+
+	path := cfg.Path
+	if path == "" {
+		publicURL, err := url.Parse(cfg.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook url: %w", err)
+		}
+		path = publicURL.Path
+	}
+	if path == "" {
+		path = "/"
+	}
+
+	listener, err := net.Listen("tcp", cfg.ListenAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", cfg.ListenAddr, err)
+	}
+
+	wh := &webhookServer{
+		updates: make(chan tgbotapi.Update, b.api.Buffer),
+		done:    make(chan struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, b.handleWebhookRequest(wh))
+	wh.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		var err error
+		if cfg.CertFile != "" {
+			err = wh.server.ServeTLS(listener, cfg.CertFile, cfg.KeyFile)
+		} else {
+			err = wh.server.Serve(listener)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			log.Printf("Webhook server error: %v", err)
+		}
+	}()
+
+	params := tgbotapi.Params{"url": cfg.URL}
+	params.AddNonEmpty("secret_token", cfg.SecretToken)
+	if _, err := b.api.MakeRequest("setWebhook", params); err != nil {
+		wh.server.Close()
+		return nil, fmt.Errorf("failed to register webhook: %w", err)
+	}
+
+	b.webhookMu.Lock()
+	b.webhook = wh
+	b.webhookMu.Unlock()
+
+	log.Printf("Webhook registered: %s (listening on %s%s)", cfg.URL, listener.Addr(), path)
+
+	return wh.updates, nil
+}
+
+func (b *Bot) handleWebhookRequest(wh *webhookServer) http.HandlerFunc {
+	secret := []byte(b.GetConfig().Webhook.SecretToken)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if len(secret) > 0 {
+			header := []byte(r.Header.Get(secretTokenHeader))
+			if subtle.ConstantTimeCompare(header, secret) != 1 {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+		}
+
+		update, err := b.api.HandleUpdate(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		wh.mu.RLock()
+		defer wh.mu.RUnlock()
+		if wh.closed {
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+			return
+		}
+
+		// Ohne OK stellt Telegram das Update später erneut zu
+		select {
+		case wh.updates <- *update:
+			w.WriteHeader(http.StatusOK)
+		case <-wh.done:
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		case <-r.Context().Done():
+		}
+	}
+}
+
+// stopWebhook beendet den HTTP-Server und meldet den Webhook bei Telegram ab
+func (b *Bot) stopWebhook() {
+	b.webhookMu.Lock()
+	wh := b.webhook
+	b.webhook = nil
+	b.webhookMu.Unlock()
+
+	if wh == nil {
+		return
+	}
+
+	// Handler, die auf einen Platz im Channel warten, sofort freigeben
+	close(wh.done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := wh.server.Shutdown(ctx); err != nil {
+		log.Printf("Failed to shut down webhook server: %v", err)
+	}
+
+	if _, err := b.api.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		log.Printf("Failed to delete webhook: %v", err)
+	}
+
+	// Auch nach einem Timeout von Shutdown können noch Handler laufen. Der Lock wartet, bis
+	// keiner mehr sendet, danach lehnen sie ab, ohne den Channel anzufassen.
+	wh.mu.Lock()
+	wh.closed = true
+	close(wh.updates)
+	wh.mu.Unlock()
+}
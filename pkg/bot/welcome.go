@@ -0,0 +1,180 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"telegramBot/config"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// WelcomePlaceholders sind die Platzhalter, die in welcome_message ersetzt werden
+var WelcomePlaceholders = []string{"first_name", "last_name", "username", "mention", "chat_title", "member_count", "rules_link"}
+
+// welcomeButtonPattern erkennt URL-Buttons im Format [Text](buttonurl:https://...) und
+// [Text](buttonurl:https://...:same) für einen Button in derselben Zeile wie der vorherige
+var welcomeButtonPattern = regexp.MustCompile(`\[([^\[\]\n]+)\]\(buttonurl:([^\s()]+?)(:same)?\)`)
+
+// SendWelcome sendet die Willkommensnachricht eines Chats für einen neuen User.
+// Lehnt Telegram die Formatierung ab, wird sie ohne ParseMode erneut gesendet.
+func (b *Bot) SendWelcome(chat *tgbotapi.Chat, user *tgbotapi.User) (tgbotapi.Message, error) {
+	captchaCfg := b.GetChatConfig(chat.ID).Captcha
+	msg := b.RenderWelcome(chat, user, captchaCfg.WelcomeMessage, captchaCfg.WelcomeFormat)
+
+	sent, err := b.api.Send(msg)
+	if err != nil && msg.ParseMode != "" {
+		log.Printf("Failed to send formatted welcome message in chat %d, retrying without formatting: %v", chat.ID, err)
+		msg.ParseMode = ""
+		return b.api.Send(msg)
+	}
+	return sent, err
+}
+
+// RenderWelcome setzt eine Willkommens-Vorlage für einen User zusammen. Platzhalter werden
+// passend zum Format maskiert, Button-Zeilen werden zum Inline-Keyboard.
+func (b *Bot) RenderWelcome(chat *tgbotapi.Chat, user *tgbotapi.User, template, format string) tgbotapi.MessageConfig {
+	if strings.TrimSpace(template) == "" {
+		template = b.ChatLocalizer(chat.ID).T("welcome.default")
+		format = config.WelcomeFormatPlain
+	} else if !hasWelcomePlaceholder(template) {
+		// Alte Vorlagen ohne Platzhalter wurden bisher um die Erwähnung ergänzt
+		template += " {mention}"
+	}
+
+	vars := b.welcomeVars(chat, user, template)
+	text, keyboard := parseWelcomeButtons(template, format, vars)
+
+	msg := tgbotapi.NewMessage(chat.ID, renderWelcomeText(text, format, user, vars))
+	msg.ParseMode = ParseMode(format)
+	if keyboard != nil {
+		msg.ReplyMarkup = *keyboard
+	}
+	return msg
+}
+
+func hasWelcomePlaceholder(template string) bool {
+	for _, name := range WelcomePlaceholders {
+		if strings.Contains(template, "{"+name+"}") {
+			return true
+		}
+	}
+	return false
+}
+
+// welcomeVars liefert die ungemaskierten Werte der Platzhalter. Die Mitgliederzahl
+// kostet einen API-Aufruf und wird nur geholt, wenn die Vorlage sie verwendet.
+func (b *Bot) welcomeVars(chat *tgbotapi.Chat, user *tgbotapi.User, template string) map[string]string {
+	vars := map[string]string{
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+		"username":   user.UserName,
+		"mention":    GetUserMention(user),
+		"chat_title": chat.Title,
+		"rules_link": RulesLink(b.api.Self.UserName, chat.ID),
+	}
+	if user.UserName != "" {
+		vars["username"] = "@" + user.UserName
+	}
+
+	if strings.Contains(template, "{member_count}") {
+		count, err := b.api.GetChatMembersCount(tgbotapi.ChatMemberCountConfig{
+			ChatConfig: tgbotapi.ChatConfig{ChatID: chat.ID},
+		})
+		if err != nil {
+			log.Printf("Failed to get member count for chat %d: %v", chat.ID, err)
+			vars["member_count"] = "?"
+		} else {
+			vars["member_count"] = strconv.Itoa(count)
+		}
+	}
+
+	return vars
+}
+
+// RulesStartPrefix leitet den Start-Parameter von RulesLink ein, dahinter steht die Chat-ID
+const RulesStartPrefix = "rules_"
+
+// RulesLink ist ein Deep-Link, der die Regeln eines Chats im privaten Chat mit dem Bot öffnet
+func RulesLink(botUserName string, chatID int64) string {
+	return fmt.Sprintf("https://t.me/%s?start=%s%d", botUserName, RulesStartPrefix, chatID)
+}
+
+// renderWelcomeText ersetzt die Platzhalter; die Erwähnung wird als Link im Format erzeugt
+func renderWelcomeText(text, format string, user *tgbotapi.User, vars map[string]string) string {
+	var pairs []string
+	for _, name := range WelcomePlaceholders {
+		value := EscapeFormat(format, vars[name])
+		if name == "mention" {
+			value = FormatMention(user, format)
+		}
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// parseWelcomeButtons entfernt die Button-Angaben aus der Vorlage und baut daraus das
+// Inline-Keyboard. Buttons ohne http(s)- oder tg-Link werden verworfen.
+func parseWelcomeButtons(template, format string, vars map[string]string) (string, *tgbotapi.InlineKeyboardMarkup) {
+	var pairs []string
+	for _, name := range WelcomePlaceholders {
+		pairs = append(pairs, "{"+name+"}", vars[name])
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	text := welcomeButtonPattern.ReplaceAllStringFunc(template, func(match string) string {
+		parts := welcomeButtonPattern.FindStringSubmatch(match)
+		label, link := parts[1], parts[2]
+		if format == config.WelcomeFormatHTML {
+			// Aus /setwelcome stammende Vorlagen sind bereits HTML-maskiert
+			label, link = html.UnescapeString(label), html.UnescapeString(link)
+		}
+		label, link = replacer.Replace(label), replacer.Replace(link)
+
+		if !strings.HasPrefix(link, "https://") && !strings.HasPrefix(link, "http://") && !strings.HasPrefix(link, "tg://") {
+			log.Printf("Ignoring welcome button %q with invalid link %q", label, link)
+			return ""
+		}
+
+		button := tgbotapi.NewInlineKeyboardButtonURL(label, link)
+		if parts[3] != "" && len(rows) > 0 {
+			rows[len(rows)-1] = append(rows[len(rows)-1], button)
+		} else {
+			rows = append(rows, []tgbotapi.InlineKeyboardButton{button})
+		}
+		return ""
+	})
+
+	if len(rows) == 0 {
+		return strings.TrimSpace(text), nil
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return strings.TrimSpace(text), &keyboard
+}
+
+// WelcomeButtonLines wandelt die URL-Buttons einer Nachricht in Button-Zeilen für eine HTML-Vorlage um
+func WelcomeButtonLines(markup *tgbotapi.InlineKeyboardMarkup) string {
+	if markup == nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	for _, row := range markup.InlineKeyboard {
+		for i, button := range row {
+			if button.URL == nil {
+				continue
+			}
+			same := ""
+			if i > 0 {
+				same = ":same"
+			}
+			sb.WriteString(fmt.Sprintf("\n[%s](buttonurl:%s%s)",
+				EscapeFormat(config.WelcomeFormatHTML, button.Text), EscapeFormat(config.WelcomeFormatHTML, *button.URL), same))
+		}
+	}
+	return sb.String()
+}
@@ -1,12 +1,15 @@
 package captcha
 
 import (
+	"encoding/json"
 	"fmt"
 	"math/rand"
 	"strconv"
 	"strings"
 	"telegramBot/pkg/bot"
 	"telegramBot/pkg/database"
+	"telegramBot/pkg/duration"
+	"telegramBot/pkg/i18n"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -53,128 +56,125 @@ func (h *Handler) handleNewMember(b *bot.Bot, chatID int64, user *tgbotapi.User)
 		CanPinMessages:        false,
 	}
 
-	if err := b.RestrictChatMember(chatID, user.ID, permissions); err != nil {
+	// Bisherige Rechte merken, damit sie nach dem Captcha wiederhergestellt werden
+	if err := b.RestrictMember(chatID, user.ID, permissions); err != nil {
 		return fmt.Errorf("failed to restrict user: %w", err)
 	}
 
-	captchaKey := generateCaptcha()
-	solution, _ := solveCaptcha(captchaKey)
+	challengeType, challenge := getChallenge(b.GetChatConfig(chatID).Captcha.ChallengeType)
+	state, err := challenge.Generate()
+	if err != nil {
+		return fmt.Errorf("failed to generate captcha: %w", err)
+	}
+
+	tr := b.ChatLocalizer(chatID)
+	prompt, err := challenge.Render(tr, state)
+	if err != nil {
+		return fmt.Errorf("failed to render captcha: %w", err)
+	}
 
 	pendingUser := database.PendingUser{
-		UserID:     user.ID,
-		ChatID:     chatID,
-		CaptchaKey: captchaKey,
-		ExpiresAt:  time.Now().Add(time.Duration(b.GetConfig().Captcha.MessageDeleteDelayMinutes) * time.Minute),
-		Attempts:   0,
+		UserID:         user.ID,
+		ChatID:         chatID,
+		ChallengeType:  challengeType,
+		ChallengeState: state,
+		ExpiresAt:      time.Now().Add(b.GetChatConfig(chatID).Captcha.Timeout()),
+		Attempts:       0,
 	}
 
 	if err := b.GetDB().AddPendingUser(pendingUser); err != nil {
 		return fmt.Errorf("failed to add pending user: %w", err)
 	}
 
-	return h.sendCaptchaToGroup(b, user, captchaKey, solution, chatID)
+	return h.sendCaptchaToGroup(b, tr, user, prompt, chatID)
 }
 
-func (h *Handler) sendCaptchaToGroup(b *bot.Bot, user *tgbotapi.User, captchaKey string, solution int, chatID int64) error {
-	text := fmt.Sprintf(
-		"%s %s!\n\n"+
-			"Um in der Gruppe schreiben zu können, löse bitte das folgende Captcha:\n\n"+
-			"Berechne: %s = ?\n\n"+
-			"Antworte einfach mit der Zahl. Du hast %d Minuten Zeit.",
-		b.GetConfig().Captcha.WelcomeMessage,
-		bot.GetUserMention(user),
-		captchaKey,
-		b.GetConfig().Captcha.MessageDeleteDelayMinutes,
-	)
+func (h *Handler) sendCaptchaToGroup(b *bot.Bot, tr *bot.Localizer, user *tgbotapi.User, prompt *Prompt, chatID int64) error {
+	cfg := b.GetChatConfig(chatID).Captcha
+	timeout := cfg.Timeout()
+	text := tr.T("captcha.prompt", i18n.Vars{
+		"user":    bot.GetUserMention(user),
+		"task":    prompt.Text,
+		"timeout": duration.Format(timeout),
+	})
 
 	// Willkommensnachricht mit Captcha senden
-	welcomeMsg, err := b.SendMessage(chatID, text)
+	var welcomeMsg tgbotapi.Message
+	var err error
+	switch {
+	case prompt.Image != nil:
+		welcomeMsg, err = b.SendPhoto(chatID, prompt.Image, text, prompt.Keyboard)
+	case prompt.Keyboard != nil:
+		welcomeMsg, err = b.SendMessageWithKeyboard(chatID, text, *prompt.Keyboard)
+	default:
+		welcomeMsg, err = b.SendMessage(chatID, text)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to send welcome message: %w", err)
 	}
 
 	// Willkommensnachricht-ID in der DB speichern für spätere Löschung
 	if err := b.GetDB().SetWelcomeMessage(user.ID, chatID, welcomeMsg.MessageID); err != nil {
-		// Fallback: Direkt Timer für Willkommensnachricht starten
-		go func() {
-			delay := time.Duration(b.GetConfig().Captcha.MessageDeleteDelayMinutes) * time.Minute
-			time.Sleep(delay)
-			b.DeleteMessage(chatID, welcomeMsg.MessageID)
-		}()
-	}
-
-	// Auto-Kick Timer starten
-	go func() {
-		delay := time.Duration(b.GetConfig().Captcha.MessageDeleteDelayMinutes) * time.Minute
-		time.Sleep(delay)
-
-		// Prüfen ob User noch pending ist
-		pendingUser, err := b.GetDB().GetPendingUser(user.ID, chatID)
-		if err == nil && pendingUser != nil {
-			// User hat Captcha nicht gelöst - kicken
-			username := bot.GetUserIdentifier(user)
-			b.GetEventLogger().LogCaptchaFail(chatID, user.ID, username, "Timeout - captcha not solved in time")
-			b.GetEventLogger().LogKick(chatID, user.ID, username, "Captcha timeout")
-
-			b.KickChatMember(chatID, user.ID)
-			b.UnbanChatMember(chatID, user.ID)
-			b.GetDB().RemovePendingUser(user.ID, chatID)
-
-			// Willkommensnachricht löschen
-			b.DeleteMessage(chatID, welcomeMsg.MessageID)
-
-			// Timeout-Nachricht senden
-			timeoutMsg, _ := b.SendMessage(chatID, fmt.Sprintf(
-				"%s wurde wegen Captcha-Timeout aus der Gruppe entfernt.",
-				bot.GetUserMention(user),
-			))
-
-			// Timeout-Nachricht nach 5 Sekunden löschen
-			go func() {
-				time.Sleep(5 * time.Second)
-				b.DeleteMessage(chatID, timeoutMsg.MessageID)
-			}()
-		}
-	}()
+		// Fallback: Löschung direkt einplanen
+		b.ScheduleMessageDeletion(chatID, welcomeMsg.MessageID, time.Duration(cfg.MessageDeleteDelayMinutes)*time.Minute)
+	}
 
-	return nil
+	// Auto-Kick nach timeout_minutes einplanen (ersetzt einen evtl. noch offenen Kick von einem früheren Beitritt)
+	if err := scheduleKick(b, chatID, user, timeout); err != nil {
+		return fmt.Errorf("failed to schedule captcha timeout: %w", err)
+	}
 
+	return nil
 }
 
-func generateCaptcha() string {
-	rand.Seed(time.Now().UnixNano())
-	a := rand.Intn(20) + 1
-	b := rand.Intn(20) + 1
-	return fmt.Sprintf("%d+%d", a, b)
+// scheduleKick plant den Kick eines Users, der das Captcha nicht rechtzeitig abschließt.
+// Ein bereits geplanter Kick wird ersetzt.
+func scheduleKick(b *bot.Bot, chatID int64, user *tgbotapi.User, delay time.Duration) error {
+	payload, _ := json.Marshal(kickPayload{
+		Mention:  bot.GetUserMention(user),
+		Username: bot.GetUserIdentifier(user),
+	})
+
+	b.CancelJobs(bot.JobKickPending, chatID, user.ID)
+	job := database.Job{
+		Kind:    bot.JobKickPending,
+		ChatID:  chatID,
+		UserID:  user.ID,
+		Payload: string(payload),
+	}
+	return b.ScheduleJob(job, delay)
 }
 
-func generateMathProblem(captcha string) string {
-	return captcha + " = ?"
+// kickPayload enthält die User-Daten, die beim Captcha-Timeout noch gebraucht werden
+type kickPayload struct {
+	Mention  string `json:"mention"`
+	Username string `json:"username"`
 }
 
-func solveCaptcha(captcha string) (int, error) {
-	parts := strings.Split(captcha, "+")
-	if len(parts) != 2 {
-		return 0, fmt.Errorf("invalid captcha format")
-	}
-
-	a, err := strconv.Atoi(strings.TrimSpace(parts[0]))
-	if err != nil {
-		return 0, err
+// KickPendingJob entfernt einen User, der sein Captcha nicht rechtzeitig gelöst hat
+func KickPendingJob(b *bot.Bot, job database.Job) error {
+	// Prüfen ob User noch pending ist
+	pendingUser, err := b.GetDB().GetPendingUser(job.UserID, job.ChatID)
+	if err != nil || pendingUser == nil {
+		return nil
 	}
 
-	b, err := strconv.Atoi(strings.TrimSpace(parts[1]))
-	if err != nil {
-		return 0, err
+	var payload kickPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		payload.Mention = b.ChatLocalizer(job.ChatID).T("captcha.user_fallback", i18n.Vars{"id": job.UserID})
+		payload.Username = fmt.Sprintf("ID:%d", job.UserID)
 	}
 
-	return a + b, nil
+	return NewVerifier().Fail(b, job.ChatID, job.UserID, payload.Mention, payload.Username, failTimeout)
 }
 
-type CallbackHandler struct{}
+// CallbackHandler nimmt Captcha-Antworten per Button entgegen und gibt sie an den Verifier weiter
+type CallbackHandler struct {
+	verifier *Verifier
+}
 
 func NewCallbackHandler() *CallbackHandler {
-	return &CallbackHandler{}
+	return &CallbackHandler{verifier: NewVerifier()}
 }
 
 func (h *CallbackHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
@@ -193,6 +193,10 @@ func (h *CallbackHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
 		return h.handleCaptchaAnswer(b, callback)
 	}
 
+	if strings.HasPrefix(data, pickCallbackPrefix) {
+		return h.handleCaptchaPick(b, callback)
+	}
+
 	return nil
 }
 
@@ -208,29 +212,34 @@ func (h *CallbackHandler) handleCaptchaSolve(b *bot.Bot, callback *tgbotapi.Call
 	}
 
 	captchaKey := parts[2]
+	tr := b.ChatLocalizer(groupChatID)
 
 	pendingUser, err := b.GetDB().GetPendingUser(callback.From.ID, groupChatID)
 	if err != nil {
-		b.GetAPI().Send(tgbotapi.NewCallback(callback.ID, "Captcha nicht gefunden oder abgelaufen!"))
+		b.GetAPI().Send(tgbotapi.NewCallback(callback.ID, tr.T("captcha.not_found_or_expired")))
 		return nil
 	}
 
-	if pendingUser.CaptchaKey != captchaKey {
-		b.GetAPI().Send(tgbotapi.NewCallback(callback.ID, "Ungültiges Captcha!"))
+	if pendingUser.ChallengeType != ChallengeMath || pendingUser.ChallengeState != captchaKey {
+		b.GetAPI().Send(tgbotapi.NewCallback(callback.ID, tr.T("captcha.invalid")))
 		return nil
 	}
 
 	if time.Now().After(pendingUser.ExpiresAt) {
-		b.GetDB().RemovePendingUser(callback.From.ID, groupChatID)
-		b.GetAPI().Send(tgbotapi.NewCallback(callback.ID, "Captcha abgelaufen!"))
+		b.GetAPI().Send(tgbotapi.NewCallback(callback.ID, tr.T("captcha.expired")))
 		return nil
 	}
 
-	solution, err := solveCaptcha(captchaKey)
+	solution, err := solveMath(captchaKey)
 	if err != nil {
 		return fmt.Errorf("failed to solve captcha: %w", err)
 	}
 
+	prompt, err := challenges[ChallengeMath].Render(tr, captchaKey)
+	if err != nil {
+		return fmt.Errorf("failed to render captcha: %w", err)
+	}
+
 	keyboard := tgbotapi.NewInlineKeyboardMarkup()
 	row := make([]tgbotapi.InlineKeyboardButton, 0)
 
@@ -250,12 +259,7 @@ func (h *CallbackHandler) handleCaptchaSolve(b *bot.Bot, callback *tgbotapi.Call
 
 	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, row)
 
-	text := fmt.Sprintf(
-		"Captcha-Loesung\n\n"+
-			"Berechne: %s\n\n"+
-			"Wähle die richtige Antwort:",
-		generateMathProblem(captchaKey),
-	)
+	text := tr.T("captcha.choose_answer", i18n.Vars{"task": strings.SplitN(prompt.Text, "\n", 2)[0]})
 
 	edit := tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, text)
 	edit.ReplyMarkup = &keyboard
@@ -281,139 +285,57 @@ func (h *CallbackHandler) handleCaptchaAnswer(b *bot.Bot, callback *tgbotapi.Cal
 		return fmt.Errorf("invalid group chat ID")
 	}
 
-	captchaKey := parts[2]
-	userAnswer, err := strconv.Atoi(parts[3])
-	if err != nil {
+	if _, err := strconv.Atoi(parts[3]); err != nil {
 		return fmt.Errorf("invalid answer format")
 	}
 
-	pendingUser, err := b.GetDB().GetPendingUser(callback.From.ID, groupChatID)
-	if err != nil {
-		b.GetAPI().Send(tgbotapi.NewCallback(callback.ID, "Captcha nicht gefunden!"))
-		return nil
-	}
-
-	if time.Now().After(pendingUser.ExpiresAt) {
-		b.GetDB().RemovePendingUser(callback.From.ID, groupChatID)
-		b.GetAPI().Send(tgbotapi.NewCallback(callback.ID, "Captcha abgelaufen!"))
-		return nil
-	}
-
-	correctAnswer, err := solveCaptcha(captchaKey)
-	if err != nil {
-		return fmt.Errorf("failed to solve captcha: %w", err)
-	}
-
-	if userAnswer == correctAnswer {
-		return h.handleCorrectAnswer(b, callback, groupChatID)
-	} else {
-		return h.handleWrongAnswer(b, callback, pendingUser, groupChatID)
-	}
-}
-
-func (h *CallbackHandler) handleCorrectAnswer(b *bot.Bot, callback *tgbotapi.CallbackQuery, groupChatID int64) error {
-	permissions := tgbotapi.ChatPermissions{
-		CanSendMessages:       true,
-		CanSendMediaMessages:  true,
-		CanSendPolls:          true,
-		CanSendOtherMessages:  true,
-		CanAddWebPagePreviews: true,
-		CanChangeInfo:         false,
-		CanInviteUsers:        false,
-		CanPinMessages:        false,
-	}
-
-	if err := b.RestrictChatMember(groupChatID, callback.From.ID, permissions); err != nil {
-		return fmt.Errorf("failed to unrestrict user: %w", err)
-	}
-
-	if err := b.GetDB().RemovePendingUser(callback.From.ID, groupChatID); err != nil {
-		return fmt.Errorf("failed to remove pending user: %w", err)
+	// Die Buttons können auch außerhalb der Gruppe stehen, der Titel wird für die Begrüßung nachgeladen
+	chat := &tgbotapi.Chat{ID: groupChatID, Type: "supergroup"}
+	if callback.Message != nil && callback.Message.Chat.ID == groupChatID {
+		chat = callback.Message.Chat
+	} else if info, err := b.GetAPI().GetChat(tgbotapi.ChatInfoConfig{ChatConfig: tgbotapi.ChatConfig{ChatID: groupChatID}}); err == nil {
+		chat = &info
 	}
 
-	successText := "Glückwunsch!\n\nDu hast das Captcha erfolgreich gelöst und wurdest zur Gruppe hinzugefügt!"
-	edit := tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, successText)
-	edit.ParseMode = "Markdown"
-	b.GetAPI().Send(edit)
-
-	welcomeText := fmt.Sprintf(
-		"%s %s",
-		b.GetConfig().Captcha.WelcomeMessage,
-		bot.GetUserMention(callback.From),
-	)
-
-	msg, err := b.SendMessage(groupChatID, welcomeText)
-	if err == nil {
-		// Nach messageDeleteDelayMinutes löschen
-		go func() {
-			delay := time.Duration(b.GetConfig().Captcha.MessageDeleteDelayMinutes) * time.Minute
-			time.Sleep(delay)
-			b.DeleteMessage(groupChatID, msg.MessageID)
-		}()
-	}
-
-	// Auch die Captcha-Nachricht nach dem konfigurierten Zeitraum löschen
-	go func() {
-		delay := time.Duration(b.GetConfig().Captcha.MessageDeleteDelayMinutes) * time.Minute
-		time.Sleep(delay)
-		b.DeleteMessage(callback.Message.Chat.ID, callback.Message.MessageID)
-	}()
-
-	b.GetAPI().Send(tgbotapi.NewCallback(callback.ID, "✅ Captcha gelöst!"))
-	return nil
+	return h.verify(b, callback, chat, parts[3])
 }
 
-func (h *CallbackHandler) handleWrongAnswer(b *bot.Bot, callback *tgbotapi.CallbackQuery, pendingUser *database.PendingUser, groupChatID int64) error {
-	if err := b.GetDB().IncrementAttempts(callback.From.ID, groupChatID); err != nil {
-		return fmt.Errorf("failed to increment attempts: %w", err)
-	}
-
-	attempts := pendingUser.Attempts + 1
-	maxAttempts := b.GetConfig().Captcha.MaxAttempts
-
-	if attempts >= maxAttempts {
-		if err := b.GetDB().RemovePendingUser(callback.From.ID, groupChatID); err != nil {
-			return fmt.Errorf("failed to remove pending user: %w", err)
-		}
-
-		failText := "Captcha fehlgeschlagen!\n\nDu hast zu viele falsche Versuche gemacht und wurdest aus der Gruppe entfernt."
-		edit := tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, failText)
-		edit.ParseMode = "Markdown"
-		b.GetAPI().Send(edit)
-
-		// User aus Gruppe kicken
-		b.KickChatMember(groupChatID, callback.From.ID)
-		b.UnbanChatMember(groupChatID, callback.From.ID)
-
-		// Captcha-Nachricht sofort nach Kick löschen
-		go func() {
-			time.Sleep(2 * time.Second) // Kurz warten, damit User die Nachricht sehen kann
-			b.DeleteMessage(callback.Message.Chat.ID, callback.Message.MessageID)
-		}()
-
-		b.GetAPI().Send(tgbotapi.NewCallback(callback.ID, "Zu viele Fehlversuche!"))
+// handleCaptchaPick verarbeitet Antwort-Buttons von emoji-/word-Challenges direkt in der Gruppe
+func (h *CallbackHandler) handleCaptchaPick(b *bot.Bot, callback *tgbotapi.CallbackQuery) error {
+	if callback.Message == nil {
 		return nil
 	}
 
-	remainingAttempts := maxAttempts - attempts
-	retryText := fmt.Sprintf(
-		"Falsche Antwort!\n\n"+
-			"Du hast noch %d Versuche uebrig.\n\n"+
-			"Klicke erneut auf 'Antwort eingeben' um es nochmal zu versuchen.",
-		remainingAttempts,
-	)
-
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Erneut versuchen", fmt.Sprintf("captcha_solve:%d:%s", groupChatID, pendingUser.CaptchaKey)),
-		),
-	)
-
-	edit := tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, retryText)
-	edit.ReplyMarkup = &keyboard
-	edit.ParseMode = "Markdown"
-	b.GetAPI().Send(edit)
+	return h.verify(b, callback, callback.Message.Chat, strings.TrimPrefix(callback.Data, pickCallbackPrefix))
+}
 
-	b.GetAPI().Send(tgbotapi.NewCallback(callback.ID, fmt.Sprintf("Falsch! Noch %d Versuche", remainingAttempts)))
+// verify prüft eine Button-Antwort und beantwortet den Callback passend zum Ergebnis
+func (h *CallbackHandler) verify(b *bot.Bot, callback *tgbotapi.CallbackQuery, chat *tgbotapi.Chat, answer string) error {
+	outcome, err := h.verifier.Verify(b, chat, callback.From, answer)
+	if err != nil {
+		return err
+	}
+
+	tr := b.ChatLocalizer(chat.ID)
+	var text string
+	switch outcome.Result {
+	case ResultNoCaptcha:
+		// Nur der neue User selbst darf sein Captcha lösen
+		text = tr.T("captcha.not_yours")
+	case ResultExpired:
+		text = tr.T("captcha.expired")
+	case ResultRulesPending:
+		text = tr.T("captcha.rules_pending")
+	case ResultWrong:
+		text = tr.T("captcha.wrong_short", i18n.Vars{"remaining": outcome.Remaining})
+	case ResultFailed:
+		text = tr.T("captcha.too_many_short")
+	case ResultRulesRequired:
+		text = tr.T("captcha.solved_rules_short")
+	case ResultSolved:
+		text = tr.T("captcha.solved_short")
+	}
+
+	b.GetAPI().Request(tgbotapi.NewCallback(callback.ID, text))
 	return nil
 }
@@ -0,0 +1,207 @@
+package captcha
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"telegramBot/pkg/bot"
+	"telegramBot/pkg/i18n"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Challenge-Typen, auswählbar pro Gruppe über captcha.challenge_type
+const (
+	ChallengeMath  = "math"
+	ChallengeEmoji = "emoji"
+	ChallengeWord  = "word"
+	ChallengeImage = "image"
+)
+
+// pickCallbackPrefix markiert Antwort-Buttons von Auswahl-Challenges
+const pickCallbackPrefix = "captcha_pick:"
+
+// Challenge ist eine Captcha-Aufgabe. Der Zustand (state) wird als String in
+// pending_users gespeichert, damit die Aufgabe auch nach einem Neustart prüfbar bleibt.
+type Challenge interface {
+	// Generate erzeugt eine neue zufällige Aufgabe
+	Generate() (string, error)
+	// Render baut aus dem Zustand die Nachricht für den User in der Sprache der Gruppe
+	Render(tr *bot.Localizer, state string) (*Prompt, error)
+	// Verify prüft eine Antwort (getippter Text oder Button-Wert)
+	Verify(state, answer string) bool
+	// TextAnswer gibt an, ob die Antwort als Nachricht getippt wird (sonst per Button)
+	TextAnswer() bool
+}
+
+// Prompt ist die gerenderte Aufgabe
+type Prompt struct {
+	Text     string
+	Keyboard *tgbotapi.InlineKeyboardMarkup
+	Image    []byte // PNG, optional
+}
+
+var challenges = map[string]Challenge{
+	ChallengeMath: mathChallenge{},
+	ChallengeEmoji: pickChallenge{
+		pool:   []string{"🍎", "🐶", "🚗", "🌵", "⚽", "🎸", "🌙", "🐟", "🍕", "🔑", "🎈", "🐝"},
+		prompt: "captcha.pick_emoji",
+	},
+	ChallengeWord: pickChallenge{
+		// Die Wörter werden über captcha.word.<id> übersetzt, gespeichert wird nur die ID
+		pool:   []string{"cat", "dog", "tree", "sun", "apple", "house", "car", "flower", "bird", "fish", "bread", "star"},
+		prompt: "captcha.pick_word",
+		labels: "captcha.word.",
+	},
+	ChallengeImage: imageChallenge{},
+}
+
+// getChallenge liefert die Challenge zu einem Typ; unbekannte Typen fallen auf math zurück
+func getChallenge(challengeType string) (string, Challenge) {
+	if challenge, ok := challenges[challengeType]; ok {
+		return challengeType, challenge
+	}
+	return ChallengeMath, challenges[ChallengeMath]
+}
+
+// mathChallenge: Rechenaufgabe mit +, - oder ×, Zustand z.B. "12-5"
+type mathChallenge struct{}
+
+var mathPattern = regexp.MustCompile(`^(\d+)([+\-*])(\d+)$`)
+
+func (mathChallenge) Generate() (string, error) {
+	switch rand.Intn(3) {
+	case 0:
+		return fmt.Sprintf("%d+%d", rand.Intn(20)+1, rand.Intn(20)+1), nil
+	case 1:
+		a := rand.Intn(20) + 10
+		return fmt.Sprintf("%d-%d", a, rand.Intn(a)+1), nil
+	default:
+		return fmt.Sprintf("%d*%d", rand.Intn(8)+2, rand.Intn(8)+2), nil
+	}
+}
+
+func (mathChallenge) Render(tr *bot.Localizer, state string) (*Prompt, error) {
+	m := mathPattern.FindStringSubmatch(state)
+	if m == nil {
+		return nil, fmt.Errorf("invalid math captcha: %s", state)
+	}
+
+	op := m[2]
+	if op == "*" {
+		op = "×"
+	}
+
+	return &Prompt{
+		Text: tr.T("captcha.math_prompt", i18n.Vars{"task": m[1] + " " + op + " " + m[3]}),
+	}, nil
+}
+
+func (mathChallenge) Verify(state, answer string) bool {
+	solution, err := solveMath(state)
+	if err != nil {
+		return false
+	}
+	value, err := strconv.Atoi(answer)
+	return err == nil && value == solution
+}
+
+func (mathChallenge) TextAnswer() bool {
+	return true
+}
+
+func solveMath(state string) (int, error) {
+	m := mathPattern.FindStringSubmatch(state)
+	if m == nil {
+		return 0, fmt.Errorf("invalid captcha format")
+	}
+
+	a, _ := strconv.Atoi(m[1])
+	b, _ := strconv.Atoi(m[3])
+
+	switch m[2] {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	default:
+		return a * b, nil
+	}
+}
+
+// pickChallenge: User muss den passenden Button aus mehreren Optionen wählen
+type pickChallenge struct {
+	pool   []string
+	prompt string // Katalog-Schlüssel der Aufgabe
+	labels string // Präfix für übersetzte Button-Texte, leer = Option direkt anzeigen
+}
+
+type pickState struct {
+	Target  string   `json:"target"`
+	Options []string `json:"options"`
+}
+
+const pickOptions = 6
+
+func (c pickChallenge) Generate() (string, error) {
+	options := make([]string, len(c.pool))
+	copy(options, c.pool)
+	rand.Shuffle(len(options), func(i, j int) { options[i], options[j] = options[j], options[i] })
+	options = options[:pickOptions]
+
+	state := pickState{
+		Target:  options[rand.Intn(len(options))],
+		Options: options,
+	}
+
+	data, err := json.Marshal(state)
+	return string(data), err
+}
+
+func (c pickChallenge) Render(tr *bot.Localizer, state string) (*Prompt, error) {
+	var s pickState
+	if err := json.Unmarshal([]byte(state), &s); err != nil {
+		return nil, fmt.Errorf("invalid pick captcha: %w", err)
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup()
+	var row []tgbotapi.InlineKeyboardButton
+	for i, option := range s.Options {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(c.label(tr, option), pickCallbackPrefix+option))
+		if len(row) == 3 || i == len(s.Options)-1 {
+			keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, row)
+			row = nil
+		}
+	}
+
+	return &Prompt{
+		Text:     tr.T(c.prompt, i18n.Vars{"target": c.label(tr, s.Target)}),
+		Keyboard: &keyboard,
+	}, nil
+}
+
+// label liefert den angezeigten Text einer Option. Ohne Übersetzung (z.B. Zustände aus
+// älteren Versionen) wird die Option selbst angezeigt.
+func (c pickChallenge) label(tr *bot.Localizer, option string) string {
+	if c.labels == "" {
+		return option
+	}
+	if text := tr.T(c.labels + option); text != c.labels+option {
+		return text
+	}
+	return option
+}
+
+func (c pickChallenge) Verify(state, answer string) bool {
+	var s pickState
+	if err := json.Unmarshal([]byte(state), &s); err != nil {
+		return false
+	}
+	return answer == s.Target
+}
+
+func (pickChallenge) TextAnswer() bool {
+	return false
+}
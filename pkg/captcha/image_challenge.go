@@ -0,0 +1,164 @@
+package captcha
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math/rand"
+	"strings"
+	"telegramBot/pkg/bot"
+)
+
+// imageChallenge: Zahl als verzerrtes Bild, Antwort wird getippt. Zustand sind die Ziffern.
+type imageChallenge struct{}
+
+const (
+	imageDigits = 5
+	imageWidth  = 220
+	imageHeight = 80
+	glyphScale  = 6
+)
+
+// 5x7-Pixelfont für die Ziffern 0-9
+var digitGlyphs = [10][7]string{
+	{"01110", "10001", "10011", "10101", "11001", "10001", "01110"},
+	{"00100", "01100", "00100", "00100", "00100", "00100", "01110"},
+	{"01110", "10001", "00001", "00010", "00100", "01000", "11111"},
+	{"11110", "00001", "00001", "01110", "00001", "00001", "11110"},
+	{"00010", "00110", "01010", "10010", "11111", "00010", "00010"},
+	{"11111", "10000", "11110", "00001", "00001", "10001", "01110"},
+	{"00110", "01000", "10000", "11110", "10001", "10001", "01110"},
+	{"11111", "00001", "00010", "00100", "01000", "01000", "01000"},
+	{"01110", "10001", "10001", "01110", "10001", "10001", "01110"},
+	{"01110", "10001", "10001", "01111", "00001", "00010", "01100"},
+}
+
+func (imageChallenge) Generate() (string, error) {
+	var sb strings.Builder
+	for i := 0; i < imageDigits; i++ {
+		sb.WriteByte(byte('0' + rand.Intn(10)))
+	}
+	return sb.String(), nil
+}
+
+func (imageChallenge) Render(tr *bot.Localizer, state string) (*Prompt, error) {
+	img, err := renderDigits(state)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode captcha image: %w", err)
+	}
+
+	return &Prompt{
+		Text:  tr.T("captcha.image_prompt"),
+		Image: buf.Bytes(),
+	}, nil
+}
+
+func (imageChallenge) Verify(state, answer string) bool {
+	return strings.TrimSpace(answer) == state
+}
+
+func (imageChallenge) TextAnswer() bool {
+	return true
+}
+
+func renderDigits(digits string) (*image.RGBA, error) {
+	img := image.NewRGBA(image.Rect(0, 0, imageWidth, imageHeight))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.RGBA{240, 240, 235, 255}}, image.Point{}, draw.Src)
+
+	// Hintergrundrauschen
+	for i := 0; i < imageWidth*imageHeight/8; i++ {
+		img.Set(rand.Intn(imageWidth), rand.Intn(imageHeight), randomColor(150, 230))
+	}
+
+	glyphWidth := 5 * glyphScale
+	step := (imageWidth - 20) / len(digits)
+
+	for i, r := range digits {
+		if r < '0' || r > '9' {
+			return nil, fmt.Errorf("invalid image captcha: %s", digits)
+		}
+
+		glyph := digitGlyphs[r-'0']
+		col := randomColor(20, 110)
+		originX := 10 + i*step + rand.Intn(step-glyphWidth+1)
+		originY := 10 + rand.Intn(imageHeight-7*glyphScale-20+1)
+		shear := rand.Float64()*0.6 - 0.3 // leichte Schrägstellung pro Ziffer
+
+		for row, line := range glyph {
+			offset := int(shear * float64(row*glyphScale))
+			for colIdx, bit := range line {
+				if bit != '1' {
+					continue
+				}
+				x := originX + colIdx*glyphScale + offset
+				y := originY + row*glyphScale
+				fillRect(img, x+rand.Intn(2), y+rand.Intn(2), glyphScale-1, glyphScale-1, col)
+			}
+		}
+	}
+
+	// Störlinien quer durch das Bild
+	for i := 0; i < 4; i++ {
+		drawLine(img,
+			rand.Intn(imageWidth/4), rand.Intn(imageHeight),
+			imageWidth-rand.Intn(imageWidth/4), rand.Intn(imageHeight),
+			randomColor(60, 160))
+	}
+
+	return img, nil
+}
+
+func randomColor(min, max int) color.RGBA {
+	c := func() uint8 { return uint8(min + rand.Intn(max-min)) }
+	return color.RGBA{c(), c(), c(), 255}
+}
+
+func fillRect(img *image.RGBA, x, y, w, h int, c color.Color) {
+	draw.Draw(img, image.Rect(x, y, x+w, y+h), &image.Uniform{c}, image.Point{}, draw.Src)
+}
+
+// drawLine zeichnet eine Linie nach Bresenham
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.Color) {
+	dx := abs(x1 - x0)
+	dy := -abs(y1 - y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+
+	for {
+		img.Set(x0, y0, c)
+		img.Set(x0, y0+1, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
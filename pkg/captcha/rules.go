@@ -0,0 +1,119 @@
+package captcha
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"telegramBot/config"
+	"telegramBot/pkg/bot"
+	"telegramBot/pkg/database"
+	"telegramBot/pkg/i18n"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// rulesCallbackPrefix markiert den Button zum Akzeptieren der Regeln ("rules:<user_id>").
+// Solche Callbacks gehen an den Handler "callback_rules".
+const rulesCallbackPrefix = "rules:"
+
+// requestRulesAcceptance startet nach gelöster Aufgabe die Regel-Stufe, sofern die Gruppe
+// rules_acceptance aktiviert und Regeln hinterlegt hat. Bei false wird der User direkt freigeschaltet.
+func requestRulesAcceptance(b *bot.Bot, chat *tgbotapi.Chat, user *tgbotapi.User) bool {
+	if b.GetChatConfig(chat.ID).Captcha.RulesAcceptance != config.RulesAcceptanceOn {
+		return false
+	}
+
+	rules, err := b.GetDB().GetGroupRules(chat.ID)
+	if err != nil {
+		log.Printf("Failed to load rules for chat %d, skipping rules acceptance: %v", chat.ID, err)
+		return false
+	}
+	if rules == "" {
+		return false // Ohne Regeln gibt es nichts zu akzeptieren
+	}
+
+	pendingUser, err := b.GetDB().GetPendingUser(user.ID, chat.ID)
+	if err != nil {
+		log.Printf("Failed to load pending user %d in chat %d, skipping rules acceptance: %v", user.ID, chat.ID, err)
+		return false
+	}
+
+	tr := b.ChatLocalizer(chat.ID)
+	text := bot.EscapeHTML(tr.T("captcha.rules_prompt", i18n.Vars{"user": bot.GetUserMention(user)})) + "\n\n" + rules
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(tr.T("captcha.rules_accept_button"), rulesCallbackPrefix+strconv.FormatInt(user.ID, 10)),
+	))
+
+	msg, err := b.SendHTMLMessage(chat.ID, text, &keyboard)
+	if err != nil {
+		log.Printf("Failed to send rules to user %d in chat %d, skipping rules acceptance: %v", user.ID, chat.ID, err)
+		return false
+	}
+
+	if err := b.GetDB().SetPendingStage(user.ID, chat.ID, database.StageRules); err != nil {
+		log.Printf("Failed to store rules stage for user %d in chat %d: %v", user.ID, chat.ID, err)
+		b.DeleteMessage(chat.ID, msg.MessageID)
+		return false
+	}
+
+	// Die Regel-Nachricht ersetzt die Captcha-Nachricht und wird wie diese beim Abschluss gelöscht
+	if err := b.GetDB().SetWelcomeMessage(user.ID, chat.ID, msg.MessageID); err != nil {
+		log.Printf("Failed to store rules message for user %d in chat %d: %v", user.ID, chat.ID, err)
+	}
+
+	// Für die Regeln gilt die restliche Zeit des Captchas
+	remaining := time.Until(pendingUser.ExpiresAt)
+	if remaining < time.Minute {
+		remaining = time.Minute
+	}
+	if err := scheduleKick(b, chat.ID, user, remaining); err != nil {
+		log.Printf("Failed to reschedule captcha timeout for user %d in chat %d: %v", user.ID, chat.ID, err)
+	}
+
+	return true
+}
+
+// RulesCallbackHandler schaltet einen User frei, der nach dem Captcha die Regeln akzeptiert
+type RulesCallbackHandler struct {
+	verifier *Verifier
+}
+
+func NewRulesCallbackHandler() *RulesCallbackHandler {
+	return &RulesCallbackHandler{verifier: NewVerifier()}
+}
+
+func (h *RulesCallbackHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
+	callback := update.CallbackQuery
+	if callback == nil || callback.Message == nil {
+		return nil
+	}
+
+	chatID := callback.Message.Chat.ID
+	tr := b.ChatLocalizer(chatID)
+
+	// Nur der neue User selbst darf die Regeln akzeptieren
+	userID, err := strconv.ParseInt(strings.TrimPrefix(callback.Data, rulesCallbackPrefix), 10, 64)
+	if err != nil || userID != callback.From.ID {
+		b.GetAPI().Request(tgbotapi.NewCallback(callback.ID, tr.T("captcha.not_yours")))
+		return nil
+	}
+
+	pendingUser, err := b.GetDB().GetPendingUser(userID, chatID)
+	if err != nil || pendingUser.Stage != database.StageRules {
+		b.GetAPI().Request(tgbotapi.NewCallback(callback.ID, tr.T("captcha.expired")))
+		return nil
+	}
+
+	if err := b.GetDB().RecordRulesAcceptance(chatID, userID, time.Now()); err != nil {
+		log.Printf("Failed to record rules acceptance of user %d in chat %d: %v", userID, chatID, err)
+	}
+	b.GetEventLogger().LogRulesAccepted(chatID, userID, bot.GetUserIdentifier(callback.From))
+
+	if err := h.verifier.Admit(b, callback.Message.Chat, callback.From); err != nil {
+		return err
+	}
+
+	b.GetAPI().Request(tgbotapi.NewCallback(callback.ID, tr.T("captcha.rules_accepted_short")))
+	return nil
+}
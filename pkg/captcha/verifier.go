@@ -0,0 +1,231 @@
+package captcha
+
+import (
+	"fmt"
+	"log"
+	"telegramBot/config"
+	"telegramBot/pkg/bot"
+	"telegramBot/pkg/database"
+	"telegramBot/pkg/i18n"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Result beschreibt, was eine Captcha-Antwort bewirkt hat
+type Result int
+
+const (
+	ResultNoCaptcha     Result = iota // kein offenes Captcha für diesen User
+	ResultExpired                     // Zeit abgelaufen, der User wurde entfernt
+	ResultRulesPending                // Aufgabe schon gelöst, die Regeln stehen noch aus
+	ResultWrong                       // falsche Antwort, es sind noch Versuche übrig
+	ResultFailed                      // zu viele falsche Antworten, der User wurde entfernt
+	ResultRulesRequired               // gelöst, jetzt müssen noch die Regeln akzeptiert werden
+	ResultSolved                      // gelöst und freigeschaltet
+)
+
+// Outcome ist das Ergebnis von Verifier.Verify
+type Outcome struct {
+	Result    Result
+	Remaining int // verbleibende Versuche bei ResultWrong
+}
+
+// failReason unterscheidet, warum ein User das Captcha nicht bestanden hat
+type failReason int
+
+const (
+	failTooManyAttempts failReason = iota
+	failTimeout
+)
+
+func (r failReason) logText() string {
+	if r == failTimeout {
+		return "Timeout - captcha not solved in time"
+	}
+	return "Too many wrong attempts"
+}
+
+func (r failReason) messageKey() string {
+	if r == failTimeout {
+		return "captcha.timeout_kick"
+	}
+	return "captcha.failed_kick"
+}
+
+// Verifier prüft Captcha-Antworten für alle Eingabewege (getippte Antwort, Buttons) und
+// übernimmt Freischaltung, Logging, Aufräumen und Kick einheitlich. Die Handler kümmern
+// sich nur noch um ihre eigene Rückmeldung an den User.
+type Verifier struct{}
+
+func NewVerifier() *Verifier {
+	return &Verifier{}
+}
+
+// Verify prüft die Antwort eines Users auf sein offenes Captcha in chat
+func (v *Verifier) Verify(b *bot.Bot, chat *tgbotapi.Chat, user *tgbotapi.User, answer string) (Outcome, error) {
+	pendingUser, err := b.GetDB().GetPendingUser(user.ID, chat.ID)
+	if err != nil || pendingUser == nil {
+		return Outcome{Result: ResultNoCaptcha}, nil
+	}
+
+	if time.Now().After(pendingUser.ExpiresAt) {
+		// Der Kick-Job kann sich verspäten, abgelaufene Captchas werden sofort abgeschlossen
+		if err := v.Fail(b, chat.ID, user.ID, bot.GetUserMention(user), bot.GetUserIdentifier(user), failTimeout); err != nil {
+			return Outcome{}, err
+		}
+		return Outcome{Result: ResultExpired}, nil
+	}
+
+	if pendingUser.Stage == database.StageRules {
+		return Outcome{Result: ResultRulesPending}, nil
+	}
+
+	_, challenge := getChallenge(pendingUser.ChallengeType)
+	if challenge.Verify(pendingUser.ChallengeState, answer) {
+		b.GetEventLogger().LogCaptchaSuccess(chat.ID, user.ID, bot.GetUserIdentifier(user), pendingUser.Attempts+1)
+		removePrompt(b, chat.ID, user.ID)
+
+		// Verlangt die Gruppe das Akzeptieren der Regeln, bleibt der User bis dahin eingeschränkt
+		if requestRulesAcceptance(b, chat, user) {
+			return Outcome{Result: ResultRulesRequired}, nil
+		}
+
+		if err := v.Admit(b, chat, user); err != nil {
+			return Outcome{}, err
+		}
+		return Outcome{Result: ResultSolved}, nil
+	}
+
+	attempts := pendingUser.Attempts + 1
+	maxAttempts := b.GetChatConfig(chat.ID).Captcha.MaxAttempts
+	if attempts >= maxAttempts {
+		if err := v.Fail(b, chat.ID, user.ID, bot.GetUserMention(user), bot.GetUserIdentifier(user), failTooManyAttempts); err != nil {
+			return Outcome{}, err
+		}
+		return Outcome{Result: ResultFailed}, nil
+	}
+
+	if err := b.GetDB().IncrementAttempts(user.ID, chat.ID); err != nil {
+		return Outcome{}, fmt.Errorf("failed to increment attempts: %w", err)
+	}
+
+	return Outcome{Result: ResultWrong, Remaining: maxAttempts - attempts}, nil
+}
+
+// Admit schaltet einen User nach bestandenem Captcha (und ggf. akzeptierten Regeln) frei,
+// meldet den Erfolg und schickt die Willkommensnachricht der Gruppe
+func (v *Verifier) Admit(b *bot.Bot, chat *tgbotapi.Chat, user *tgbotapi.User) error {
+	cfg := b.GetChatConfig(chat.ID).Captcha
+
+	// Vorherige Rechte bzw. Standardrechte der Gruppe, ggf. durch grant_permissions begrenzt
+	if err := b.RestoreMember(chat.ID, user.ID, grantLimit(chat.ID, cfg.GrantPermissions)); err != nil {
+		return fmt.Errorf("failed to unrestrict user: %w", err)
+	}
+
+	if err := b.GetDB().RemovePendingUser(user.ID, chat.ID); err != nil {
+		return fmt.Errorf("failed to remove pending user: %w", err)
+	}
+	b.CancelJobs(bot.JobKickPending, chat.ID, user.ID)
+	removePrompt(b, chat.ID, user.ID)
+
+	// Erfolgs-Nachricht nach separatem konfigurierten Delay löschen
+	tr := b.ChatLocalizer(chat.ID)
+	successMsg, err := b.SendMessage(chat.ID, tr.T("captcha.solved", i18n.Vars{"user": bot.GetUserMention(user)}))
+	if err == nil {
+		delay := time.Duration(cfg.SuccessMessageDeleteDelayMinutes) * time.Minute
+		b.ScheduleMessageDeletion(chat.ID, successMsg.MessageID, delay)
+	}
+
+	// Willkommensnachricht nach messageDeleteDelayMinutes löschen
+	welcomeMsg, err := b.SendWelcome(chat, user)
+	if err != nil {
+		log.Printf("Failed to send welcome message in chat %d: %v", chat.ID, err)
+	} else {
+		delay := time.Duration(cfg.MessageDeleteDelayMinutes) * time.Minute
+		b.ScheduleMessageDeletion(chat.ID, welcomeMsg.MessageID, delay)
+	}
+
+	return nil
+}
+
+// Fail entfernt einen User, der das Captcha nicht bestanden hat, je nach fail_action per Kick oder Ban
+func (v *Verifier) Fail(b *bot.Bot, chatID, userID int64, mention, username string, reason failReason) error {
+	cfg := b.GetChatConfig(chatID).Captcha
+	b.GetEventLogger().LogCaptchaFail(chatID, userID, username, reason.logText())
+
+	if err := b.GetDB().RemovePendingUser(userID, chatID); err != nil {
+		return fmt.Errorf("failed to remove pending user: %w", err)
+	}
+	b.CancelJobs(bot.JobKickPending, chatID, userID)
+	removePrompt(b, chatID, userID)
+
+	// Kick und Bann heben die Einschränkung ohnehin auf
+	if err := b.DiscardPermissionSnapshot(chatID, userID); err != nil {
+		log.Printf("Failed to discard permission snapshot of user %d in chat %d: %v", userID, chatID, err)
+	}
+
+	if cfg.FailAction == config.CaptchaFailBan {
+		b.GetEventLogger().LogBan(chatID, userID, username, "Captcha failed: "+reason.logText())
+		if err := b.BanChatMember(chatID, userID); err != nil {
+			log.Printf("Failed to ban user %d in chat %d after captcha: %v", userID, chatID, err)
+		}
+	} else {
+		b.GetEventLogger().LogKick(chatID, userID, username, "Captcha failed: "+reason.logText())
+		if err := b.KickChatMember(chatID, userID); err != nil {
+			log.Printf("Failed to kick user %d in chat %d after captcha: %v", userID, chatID, err)
+		}
+		b.UnbanChatMember(chatID, userID)
+	}
+
+	// Hinweis senden und nach 5 Sekunden löschen
+	tr := b.ChatLocalizer(chatID)
+	b.SendTemporaryMessage(chatID, tr.T(reason.messageKey(), i18n.Vars{"user": mention}), 5)
+	return nil
+}
+
+// removePrompt löscht die offene Captcha- bzw. Regel-Nachricht eines Users
+func removePrompt(b *bot.Bot, chatID, userID int64) {
+	messageID, err := b.GetDB().GetWelcomeMessage(userID, chatID)
+	if err != nil || messageID <= 0 {
+		return
+	}
+	b.DeleteMessage(chatID, messageID)
+	b.GetDB().RemoveWelcomeMessage(userID, chatID)
+}
+
+// grantLimit setzt grant_permissions in eine Obergrenze für Telegram-Rechte um (nil = restore).
+// Ein ungültiger Wert (z.B. von Hand in config.json eingetragen) gilt wie restore.
+func grantLimit(chatID int64, value string) *tgbotapi.ChatPermissions {
+	names, err := config.ParseGrantPermissions(value)
+	if err != nil {
+		log.Printf("Invalid grant_permissions for chat %d, restoring previous permissions: %v", chatID, err)
+		return nil
+	}
+	if names == nil {
+		return nil
+	}
+
+	var permissions tgbotapi.ChatPermissions
+	for _, name := range names {
+		switch name {
+		case config.PermissionMessages:
+			permissions.CanSendMessages = true
+		case config.PermissionMedia:
+			permissions.CanSendMediaMessages = true
+		case config.PermissionPolls:
+			permissions.CanSendPolls = true
+		case config.PermissionOther:
+			permissions.CanSendOtherMessages = true
+		case config.PermissionWebPreviews:
+			permissions.CanAddWebPagePreviews = true
+		case config.PermissionChangeInfo:
+			permissions.CanChangeInfo = true
+		case config.PermissionInviteUsers:
+			permissions.CanInviteUsers = true
+		case config.PermissionPinMessages:
+			permissions.CanPinMessages = true
+		}
+	}
+	return &permissions
+}
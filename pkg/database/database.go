@@ -2,7 +2,10 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -13,33 +16,179 @@ type DB struct {
 }
 
 type PendingUser struct {
-	UserID     int64
-	ChatID     int64
-	CaptchaKey string
-	ExpiresAt  time.Time
-	Attempts   int
+	UserID         int64
+	ChatID         int64
+	ChallengeType  string
+	ChallengeState string
+	ExpiresAt      time.Time
+	Attempts       int
+	Stage          string
 }
 
+// Stufen eines PendingUser: erst die Aufgabe, danach ggf. das Akzeptieren der Regeln
+const (
+	StageChallenge = ""
+	StageRules     = "rules"
+)
+
 type MutedUser struct {
 	UserID int64
 	ChatID int64
 	Until  time.Time
 }
 
+// BannedUser ist ein vom Bot ausgesprochener Bann. Until ist bei permanenten Banns leer.
+type BannedUser struct {
+	UserID    int64
+	ChatID    int64
+	AdminID   int64
+	Reason    string
+	Until     time.Time
+	CreatedAt time.Time
+}
+
+// PermissionSnapshot hält die Rechte eines Users fest, bevor der Bot ihn einschränkt
+type PermissionSnapshot struct {
+	ChatID      int64
+	UserID      int64
+	Restricted  bool   // war der User vorher schon eingeschränkt?
+	Permissions string // JSON seiner damaligen Rechte, nur bei Restricted
+	CreatedAt   time.Time
+}
+
+// Rollen der Bot-Admins (bot_admins.role), absteigend nach Rechten
+const (
+	RoleOwner     = "owner"
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+)
+
+// BotAdmin ist ein User mit globalen Bot-Rechten
+type BotAdmin struct {
+	UserID    int64
+	Role      string
+	GrantedBy int64 // 0 = vom Bot selbst, z.B. bei der Übernahme aus der Config
+	GrantedAt time.Time
+}
+
+// Aktionen im Audit-Trail der Bot-Admins (admin_audit.action)
+const (
+	AuditGrant  = "grant"
+	AuditRevoke = "revoke"
+)
+
+// AdminAuditEntry ist ein Eintrag im Audit-Trail der Rechtevergaben
+type AdminAuditEntry struct {
+	ID        int64
+	UserID    int64
+	Action    string
+	Role      string
+	ActorID   int64
+	Note      string
+	CreatedAt time.Time
+}
+
+// GroupAdmin ist ein Telegram-Admin einer Gruppe mit den daraus abgeleiteten Rollen.
+// Die Rollen gelten nur in dieser Gruppe.
+type GroupAdmin struct {
+	ChatID   int64
+	UserID   int64
+	Roles    []string
+	SyncedAt time.Time
+}
+
+// KnownChat ist eine Gruppe oder ein Kanal, in dem der Bot Updates gesehen hat
+type KnownChat struct {
+	ChatID         int64
+	Title          string
+	Type           string
+	BotStatus      string // Status des Bots laut my_chat_member, "" = unbekannt
+	BotPermissions string // JSON der Rechte des Bots, nur als Administrator
+	JoinedAt       time.Time
+	LeftAt         time.Time // leer, solange der Bot im Chat ist
+	UpdatedAt      time.Time
+}
+
+// MessageTemplate ist ein von Admins überschriebener Text aus dem Katalog.
+// ChatID 0 gilt für alle Gruppen.
+type MessageTemplate struct {
+	ChatID    int64
+	Locale    string
+	Key       string
+	Text      string
+	UpdatedBy int64
+	UpdatedAt time.Time
+}
+
+// Aktionstypen in moderation_actions
+const (
+	ActionBan  = "ban"
+	ActionMute = "mute"
+	ActionKick = "kick"
+	ActionWarn = "warn"
+)
+
+// ModerationAction ist ein Eintrag im Moderationsprotokoll (Bann, Mute, Kick, Verwarnung).
+// ExpiresAt ist bei unbefristeten Aktionen leer, RevokedBy/RevokedAt erst nach Aufhebung gesetzt.
+type ModerationAction struct {
+	ID        int64
+	ChatID    int64
+	UserID    int64
+	AdminID   int64
+	Action    string
+	Reason    string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	RevokedBy int64
+	RevokedAt time.Time
+}
+
+// Warning ist eine Verwarnung eines Users in einem Chat
+type Warning struct {
+	ID        int64
+	ChatID    int64
+	UserID    int64
+	AdminID   int64
+	Reason    string
+	CreatedAt time.Time
+}
+
+// Job ist eine geplante Aktion, die auch einen Neustart des Bots überlebt
+type Job struct {
+	ID        int64
+	Kind      string
+	ChatID    int64
+	UserID    int64
+	MessageID int
+	Payload   string
+	RunAt     time.Time
+	Attempts  int
+}
+
 func NewDB(filepath string) (*DB, error) {
-	conn, err := sql.Open("sqlite3", filepath)
+	conn, err := sql.Open("sqlite3", dataSourceName(filepath))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
 	db := &DB{conn: conn}
 	if err := db.createTables(); err != nil {
+		conn.Close()
 		return nil, fmt.Errorf("failed to create tables: %w", err)
 	}
 
 	return db, nil
 }
 
+// dataSourceName baut eine file:-URI, damit Zeichen wie ? oder # im Pfad die Parameter nicht
+// verfälschen. Scheduler und Handler schreiben gleichzeitig, bei einer Sperre wird gewartet
+// statt abgebrochen.
+func dataSourceName(path string) string {
+	params := url.Values{}
+	params.Set("_busy_timeout", "5000")
+	return "file:" + (&url.URL{Path: path}).EscapedPath() + "?" + params.Encode()
+}
+
 func (db *DB) createTables() error {
 	queries := []string{
 		`CREATE TABLE IF NOT EXISTS pending_users (
@@ -67,6 +216,125 @@ func (db *DB) createTables() error {
 			message_id INTEGER,
 			PRIMARY KEY (user_id, chat_id)
 		)`,
+		`CREATE TABLE IF NOT EXISTS scheduled_jobs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			kind TEXT NOT NULL,
+			chat_id INTEGER,
+			user_id INTEGER,
+			message_id INTEGER,
+			payload TEXT,
+			run_at DATETIME,
+			attempts INTEGER DEFAULT 0
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_scheduled_jobs_run_at ON scheduled_jobs (run_at)`,
+		`CREATE TABLE IF NOT EXISTS warnings (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			chat_id INTEGER,
+			user_id INTEGER,
+			admin_id INTEGER,
+			reason TEXT,
+			created_at DATETIME
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_warnings_chat_user ON warnings (chat_id, user_id)`,
+		`CREATE TABLE IF NOT EXISTS moderation_actions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			chat_id INTEGER,
+			user_id INTEGER,
+			admin_id INTEGER,
+			action TEXT NOT NULL,
+			reason TEXT,
+			created_at DATETIME,
+			expires_at DATETIME,
+			revoked_by INTEGER,
+			revoked_at DATETIME
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_moderation_actions_chat_user ON moderation_actions (chat_id, user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_moderation_actions_chat_action ON moderation_actions (chat_id, action)`,
+		`CREATE TABLE IF NOT EXISTS banned_users (
+			user_id INTEGER,
+			chat_id INTEGER,
+			admin_id INTEGER,
+			reason TEXT,
+			until DATETIME,
+			created_at DATETIME,
+			PRIMARY KEY (user_id, chat_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS message_templates (
+			chat_id INTEGER,
+			locale TEXT,
+			key TEXT,
+			text TEXT NOT NULL,
+			updated_by INTEGER,
+			updated_at DATETIME,
+			PRIMARY KEY (chat_id, locale, key)
+		)`,
+		`CREATE TABLE IF NOT EXISTS group_rules (
+			chat_id INTEGER PRIMARY KEY,
+			text TEXT NOT NULL,
+			updated_by INTEGER,
+			updated_at DATETIME
+		)`,
+		`CREATE TABLE IF NOT EXISTS rules_acceptances (
+			chat_id INTEGER,
+			user_id INTEGER,
+			accepted_at DATETIME,
+			PRIMARY KEY (chat_id, user_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS permission_snapshots (
+			chat_id INTEGER,
+			user_id INTEGER,
+			restricted BOOLEAN,
+			permissions TEXT,
+			created_at DATETIME,
+			PRIMARY KEY (chat_id, user_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS bot_admins (
+			user_id INTEGER PRIMARY KEY,
+			role TEXT NOT NULL,
+			granted_by INTEGER,
+			granted_at DATETIME
+		)`,
+		`CREATE TABLE IF NOT EXISTS admin_audit (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER,
+			action TEXT NOT NULL,
+			role TEXT,
+			actor_id INTEGER,
+			note TEXT,
+			created_at DATETIME
+		)`,
+		`CREATE TABLE IF NOT EXISTS group_admins (
+			chat_id INTEGER,
+			user_id INTEGER,
+			roles TEXT,
+			synced_at DATETIME,
+			PRIMARY KEY (chat_id, user_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS chats (
+			chat_id INTEGER PRIMARY KEY,
+			title TEXT,
+			type TEXT,
+			bot_status TEXT,
+			bot_permissions TEXT,
+			joined_at DATETIME,
+			left_at DATETIME,
+			updated_at DATETIME
+		)`,
+		`CREATE TABLE IF NOT EXISTS selected_chats (
+			user_id INTEGER PRIMARY KEY,
+			chat_id INTEGER,
+			selected_at DATETIME
+		)`,
+		`CREATE TABLE IF NOT EXISTS role_permissions (
+			chat_id INTEGER,
+			role TEXT,
+			permissions TEXT,
+			PRIMARY KEY (chat_id, role)
+		)`,
+		`CREATE TABLE IF NOT EXISTS migrations (
+			name TEXT PRIMARY KEY,
+			applied_at DATETIME
+		)`,
 	}
 
 	for _, query := range queries {
@@ -75,23 +343,56 @@ func (db *DB) createTables() error {
 		}
 	}
 
+	return db.migrate()
+}
+
+// IsMigrationApplied prüft, ob eine einmalige Datenmigration bereits gelaufen ist
+func (db *DB) IsMigrationApplied(name string) (bool, error) {
+	var exists bool
+	err := db.conn.QueryRow(`SELECT EXISTS(SELECT 1 FROM migrations WHERE name = ?)`, name).Scan(&exists)
+	return exists, err
+}
+
+// SetMigrationApplied markiert eine einmalige Datenmigration als erledigt
+func (db *DB) SetMigrationApplied(name string, at time.Time) error {
+	_, err := db.conn.Exec(`INSERT OR IGNORE INTO migrations (name, applied_at) VALUES (?, ?)`, name, at)
+	return err
+}
+
+// migrate ergänzt Spalten, die in älteren Datenbanken noch fehlen
+func (db *DB) migrate() error {
+	migrations := []string{
+		`ALTER TABLE group_settings ADD COLUMN settings TEXT`,
+		`ALTER TABLE pending_users ADD COLUMN challenge_type TEXT`,
+		`ALTER TABLE pending_users ADD COLUMN challenge_state TEXT`,
+		`ALTER TABLE pending_users ADD COLUMN stage TEXT`,
+	}
+
+	for _, query := range migrations {
+		if _, err := db.conn.Exec(query); err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+			return err
+		}
+	}
+
 	return nil
 }
 
 func (db *DB) AddPendingUser(user PendingUser) error {
-	query := `INSERT OR REPLACE INTO pending_users (user_id, chat_id, captcha_key, expires_at, attempts) 
-			  VALUES (?, ?, ?, ?, ?)`
-	_, err := db.conn.Exec(query, user.UserID, user.ChatID, user.CaptchaKey, user.ExpiresAt, user.Attempts)
+	query := `INSERT OR REPLACE INTO pending_users (user_id, chat_id, challenge_type, challenge_state, expires_at, attempts, stage) 
+			  VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err := db.conn.Exec(query, user.UserID, user.ChatID, user.ChallengeType, user.ChallengeState, user.ExpiresAt, user.Attempts, user.Stage)
 	return err
 }
 
 func (db *DB) GetPendingUser(userID, chatID int64) (*PendingUser, error) {
-	query := `SELECT user_id, chat_id, captcha_key, expires_at, attempts FROM pending_users 
+	// Alte Einträge haben nur captcha_key ("a+b"), das entspricht einer math-Challenge
+	query := `SELECT user_id, chat_id, COALESCE(challenge_type, 'math'), COALESCE(challenge_state, captcha_key, ''),
+			  expires_at, attempts, COALESCE(stage, '') FROM pending_users 
 			  WHERE user_id = ? AND chat_id = ?`
 
 	var user PendingUser
 	err := db.conn.QueryRow(query, userID, chatID).Scan(
-		&user.UserID, &user.ChatID, &user.CaptchaKey, &user.ExpiresAt, &user.Attempts,
+		&user.UserID, &user.ChatID, &user.ChallengeType, &user.ChallengeState, &user.ExpiresAt, &user.Attempts, &user.Stage,
 	)
 	if err != nil {
 		return nil, err
@@ -99,6 +400,13 @@ func (db *DB) GetPendingUser(userID, chatID int64) (*PendingUser, error) {
 	return &user, nil
 }
 
+// SetPendingStage setzt die Stufe eines PendingUser, z.B. StageRules nach gelöster Aufgabe
+func (db *DB) SetPendingStage(userID, chatID int64, stage string) error {
+	query := `UPDATE pending_users SET stage = ? WHERE user_id = ? AND chat_id = ?`
+	_, err := db.conn.Exec(query, stage, userID, chatID)
+	return err
+}
+
 func (db *DB) RemovePendingUser(userID, chatID int64) error {
 	query := `DELETE FROM pending_users WHERE user_id = ? AND chat_id = ?`
 	_, err := db.conn.Exec(query, userID, chatID)
@@ -137,6 +445,18 @@ func (db *DB) IsUserMuted(userID, chatID int64) (bool, error) {
 	return true, nil
 }
 
+// GetMutedUser liefert den Mute-Eintrag eines Users, auch wenn er bereits abgelaufen ist
+func (db *DB) GetMutedUser(userID, chatID int64) (*MutedUser, error) {
+	query := `SELECT user_id, chat_id, until FROM muted_users WHERE user_id = ? AND chat_id = ?`
+
+	var muted MutedUser
+	err := db.conn.QueryRow(query, userID, chatID).Scan(&muted.UserID, &muted.ChatID, &muted.Until)
+	if err != nil {
+		return nil, err
+	}
+	return &muted, nil
+}
+
 func (db *DB) RemoveMutedUser(userID, chatID int64) error {
 	query := `DELETE FROM muted_users WHERE user_id = ? AND chat_id = ?`
 	_, err := db.conn.Exec(query, userID, chatID)
@@ -172,6 +492,894 @@ func (db *DB) RemoveWelcomeMessage(userID, chatID int64) error {
 	return err
 }
 
+func (db *DB) AddJob(job Job) (int64, error) {
+	query := `INSERT INTO scheduled_jobs (kind, chat_id, user_id, message_id, payload, run_at, attempts)
+			  VALUES (?, ?, ?, ?, ?, ?, ?)`
+	result, err := db.conn.Exec(query, job.Kind, job.ChatID, job.UserID, job.MessageID, job.Payload, job.RunAt.UTC(), job.Attempts)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// GetDueJobs liefert alle Jobs, deren Ausführungszeit erreicht oder überschritten ist
+func (db *DB) GetDueJobs(now time.Time) ([]Job, error) {
+	query := `SELECT id, kind, chat_id, user_id, message_id, payload, run_at, attempts FROM scheduled_jobs
+			  WHERE run_at <= ? ORDER BY run_at`
+
+	rows, err := db.conn.Query(query, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []Job
+	for rows.Next() {
+		var job Job
+		var payload sql.NullString
+		if err := rows.Scan(&job.ID, &job.Kind, &job.ChatID, &job.UserID, &job.MessageID, &payload, &job.RunAt, &job.Attempts); err != nil {
+			return nil, err
+		}
+		job.Payload = payload.String
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// RescheduleJob verschiebt einen fehlgeschlagenen Job und erhöht den Versuchszähler
+func (db *DB) RescheduleJob(id int64, runAt time.Time) error {
+	query := `UPDATE scheduled_jobs SET run_at = ?, attempts = attempts + 1 WHERE id = ?`
+	_, err := db.conn.Exec(query, runAt.UTC(), id)
+	return err
+}
+
+func (db *DB) RemoveJob(id int64) error {
+	query := `DELETE FROM scheduled_jobs WHERE id = ?`
+	_, err := db.conn.Exec(query, id)
+	return err
+}
+
+// RemoveJobs entfernt alle Jobs einer Art für einen User in einem Chat
+func (db *DB) RemoveJobs(kind string, chatID, userID int64) error {
+	query := `DELETE FROM scheduled_jobs WHERE kind = ? AND chat_id = ? AND user_id = ?`
+	_, err := db.conn.Exec(query, kind, chatID, userID)
+	return err
+}
+
+func (db *DB) AddBannedUser(banned BannedUser) error {
+	query := `INSERT OR REPLACE INTO banned_users (user_id, chat_id, admin_id, reason, until, created_at)
+			  VALUES (?, ?, ?, ?, ?, ?)`
+	_, err := db.conn.Exec(query, banned.UserID, banned.ChatID, banned.AdminID, banned.Reason, nullableTime(banned.Until), banned.CreatedAt.UTC())
+	return err
+}
+
+func (db *DB) GetBannedUser(userID, chatID int64) (*BannedUser, error) {
+	query := `SELECT user_id, chat_id, admin_id, reason, until, created_at FROM banned_users
+			  WHERE user_id = ? AND chat_id = ?`
+
+	rows, err := db.conn.Query(query, userID, chatID)
+	if err != nil {
+		return nil, err
+	}
+	banned, err := scanBannedUsers(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(banned) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &banned[0], nil
+}
+
+// GetTempBans liefert alle noch laufenden zeitlich begrenzten Banns eines Chats
+func (db *DB) GetTempBans(chatID int64, now time.Time) ([]BannedUser, error) {
+	query := `SELECT user_id, chat_id, admin_id, reason, until, created_at FROM banned_users
+			  WHERE chat_id = ? AND until IS NOT NULL AND until > ? ORDER BY until`
+
+	rows, err := db.conn.Query(query, chatID, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	return scanBannedUsers(rows)
+}
+
+func (db *DB) RemoveBannedUser(userID, chatID int64) error {
+	query := `DELETE FROM banned_users WHERE user_id = ? AND chat_id = ?`
+	_, err := db.conn.Exec(query, userID, chatID)
+	return err
+}
+
+func scanBannedUsers(rows *sql.Rows) ([]BannedUser, error) {
+	defer rows.Close()
+
+	var banned []BannedUser
+	for rows.Next() {
+		var user BannedUser
+		var reason sql.NullString
+		var until sql.NullTime
+		if err := rows.Scan(&user.UserID, &user.ChatID, &user.AdminID, &reason, &until, &user.CreatedAt); err != nil {
+			return nil, err
+		}
+		user.Reason = reason.String
+		if until.Valid {
+			user.Until = until.Time
+		}
+		banned = append(banned, user)
+	}
+	return banned, rows.Err()
+}
+
+func (db *DB) AddModerationAction(action ModerationAction) (int64, error) {
+	query := `INSERT INTO moderation_actions (chat_id, user_id, admin_id, action, reason, created_at, expires_at)
+			  VALUES (?, ?, ?, ?, ?, ?, ?)`
+	result, err := db.conn.Exec(query, action.ChatID, action.UserID, action.AdminID, action.Action, action.Reason,
+		action.CreatedAt.UTC(), nullableTime(action.ExpiresAt))
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// RevokeModerationActions markiert alle noch nicht aufgehobenen Aktionen eines Typs als aufgehoben
+func (db *DB) RevokeModerationActions(chatID, userID int64, action string, revokedBy int64, at time.Time) error {
+	query := `UPDATE moderation_actions SET revoked_by = ?, revoked_at = ?
+			  WHERE chat_id = ? AND user_id = ? AND action = ? AND revoked_at IS NULL`
+	_, err := db.conn.Exec(query, revokedBy, at.UTC(), chatID, userID, action)
+	return err
+}
+
+// RevokeLatestModerationAction hebt nur die jüngste offene Aktion eines Typs auf (z.B. bei /unwarn)
+func (db *DB) RevokeLatestModerationAction(chatID, userID int64, action string, revokedBy int64, at time.Time) error {
+	query := `UPDATE moderation_actions SET revoked_by = ?, revoked_at = ?
+			  WHERE id = (SELECT id FROM moderation_actions
+			              WHERE chat_id = ? AND user_id = ? AND action = ? AND revoked_at IS NULL
+			              ORDER BY created_at DESC, id DESC LIMIT 1)`
+	_, err := db.conn.Exec(query, revokedBy, at.UTC(), chatID, userID, action)
+	return err
+}
+
+// GetActiveModerationActions liefert eine Seite der aktiven (weder abgelaufenen noch aufgehobenen)
+// Aktionen eines Typs sowie deren Gesamtzahl
+func (db *DB) GetActiveModerationActions(chatID int64, action string, now time.Time, limit, offset int) ([]ModerationAction, int, error) {
+	where := `WHERE chat_id = ? AND action = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)`
+	args := []interface{}{chatID, action, now.UTC()}
+	return db.queryModerationActions(where, args, limit, offset)
+}
+
+// GetModerationHistory liefert eine Seite aller Aktionen gegen einen User (neueste zuerst) sowie deren Gesamtzahl
+func (db *DB) GetModerationHistory(chatID, userID int64, limit, offset int) ([]ModerationAction, int, error) {
+	where := `WHERE chat_id = ? AND user_id = ?`
+	args := []interface{}{chatID, userID}
+	return db.queryModerationActions(where, args, limit, offset)
+}
+
+func (db *DB) queryModerationActions(where string, args []interface{}, limit, offset int) ([]ModerationAction, int, error) {
+	var total int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM moderation_actions `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT id, chat_id, user_id, admin_id, action, reason, created_at, expires_at, revoked_by, revoked_at
+			  FROM moderation_actions ` + where + ` ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`
+
+	rows, err := db.conn.Query(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var actions []ModerationAction
+	for rows.Next() {
+		var action ModerationAction
+		var reason sql.NullString
+		var expiresAt, revokedAt sql.NullTime
+		var revokedBy sql.NullInt64
+		if err := rows.Scan(&action.ID, &action.ChatID, &action.UserID, &action.AdminID, &action.Action, &reason,
+			&action.CreatedAt, &expiresAt, &revokedBy, &revokedAt); err != nil {
+			return nil, 0, err
+		}
+		action.Reason = reason.String
+		action.ExpiresAt = expiresAt.Time
+		action.RevokedBy = revokedBy.Int64
+		action.RevokedAt = revokedAt.Time
+		actions = append(actions, action)
+	}
+	return actions, total, rows.Err()
+}
+
+// nullableTime speichert leere Zeitpunkte als NULL
+func nullableTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC()
+}
+
+func (db *DB) AddWarning(warning Warning) (int64, error) {
+	query := `INSERT INTO warnings (chat_id, user_id, admin_id, reason, created_at) VALUES (?, ?, ?, ?, ?)`
+	result, err := db.conn.Exec(query, warning.ChatID, warning.UserID, warning.AdminID, warning.Reason, warning.CreatedAt.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// GetWarnings liefert die Verwarnungen eines Users, die nach since ausgesprochen wurden (älteste zuerst)
+func (db *DB) GetWarnings(chatID, userID int64, since time.Time) ([]Warning, error) {
+	query := `SELECT id, chat_id, user_id, admin_id, reason, created_at FROM warnings
+			  WHERE chat_id = ? AND user_id = ? AND created_at >= ? ORDER BY created_at, id`
+
+	rows, err := db.conn.Query(query, chatID, userID, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var warnings []Warning
+	for rows.Next() {
+		var warning Warning
+		var reason sql.NullString
+		if err := rows.Scan(&warning.ID, &warning.ChatID, &warning.UserID, &warning.AdminID, &reason, &warning.CreatedAt); err != nil {
+			return nil, err
+		}
+		warning.Reason = reason.String
+		warnings = append(warnings, warning)
+	}
+	return warnings, rows.Err()
+}
+
+func (db *DB) RemoveWarning(id int64) error {
+	query := `DELETE FROM warnings WHERE id = ?`
+	_, err := db.conn.Exec(query, id)
+	return err
+}
+
+// RemoveWarnings löscht alle Verwarnungen eines Users in einem Chat und gibt die Anzahl zurück
+func (db *DB) RemoveWarnings(chatID, userID int64) (int64, error) {
+	query := `DELETE FROM warnings WHERE chat_id = ? AND user_id = ?`
+	result, err := db.conn.Exec(query, chatID, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetGroupSettings liefert alle gruppenspezifischen Config-Overrides eines Chats
+func (db *DB) GetGroupSettings(chatID int64) (map[string]string, error) {
+	query := `SELECT settings FROM group_settings WHERE chat_id = ?`
+
+	var raw sql.NullString
+	err := db.conn.QueryRow(query, chatID).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeGroupSettings(raw)
+}
+
+// SetGroupSetting speichert einen Override für einen einzelnen Config-Schlüssel
+func (db *DB) SetGroupSetting(chatID int64, key, value string) error {
+	return db.updateGroupSettings(chatID, func(settings map[string]string) {
+		settings[key] = value
+	})
+}
+
+// RemoveGroupSetting entfernt einen Override, danach gilt wieder der globale Wert
+func (db *DB) RemoveGroupSetting(chatID int64, key string) error {
+	return db.updateGroupSettings(chatID, func(settings map[string]string) {
+		delete(settings, key)
+	})
+}
+
+func (db *DB) updateGroupSettings(chatID int64, update func(map[string]string)) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var raw sql.NullString
+	err = tx.QueryRow(`SELECT settings FROM group_settings WHERE chat_id = ?`, chatID).Scan(&raw)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	settings, err := decodeGroupSettings(raw)
+	if err != nil {
+		return err
+	}
+
+	update(settings)
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO group_settings (chat_id, settings) VALUES (?, ?)
+			  ON CONFLICT(chat_id) DO UPDATE SET settings = excluded.settings`
+	if _, err := tx.Exec(query, chatID, string(data)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func decodeGroupSettings(raw sql.NullString) (map[string]string, error) {
+	settings := map[string]string{}
+	if !raw.Valid || raw.String == "" {
+		return settings, nil
+	}
+
+	if err := json.Unmarshal([]byte(raw.String), &settings); err != nil {
+		return nil, fmt.Errorf("invalid group settings: %w", err)
+	}
+	return settings, nil
+}
+
+// SetMessageTemplate speichert einen eigenen Text. chatID 0 gilt für alle Gruppen.
+func (db *DB) SetMessageTemplate(tmpl MessageTemplate) error {
+	query := `INSERT OR REPLACE INTO message_templates (chat_id, locale, key, text, updated_by, updated_at)
+			  VALUES (?, ?, ?, ?, ?, ?)`
+	_, err := db.conn.Exec(query, tmpl.ChatID, tmpl.Locale, tmpl.Key, tmpl.Text, tmpl.UpdatedBy, tmpl.UpdatedAt.UTC())
+	return err
+}
+
+func (db *DB) RemoveMessageTemplate(chatID int64, locale, key string) (bool, error) {
+	query := `DELETE FROM message_templates WHERE chat_id = ? AND locale = ? AND key = ?`
+	result, err := db.conn.Exec(query, chatID, locale, key)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// GetMessageTemplates liefert die eigenen Texte für einen Chat in einer Sprache.
+// Globale Texte (chat_id 0) werden von Texten der Gruppe überschrieben.
+func (db *DB) GetMessageTemplates(chatID int64, locale string) (map[string]string, error) {
+	query := `SELECT key, text FROM message_templates
+			  WHERE locale = ? AND chat_id IN (0, ?)
+			  ORDER BY chat_id = 0 DESC`
+	rows, err := db.conn.Query(query, locale, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := make(map[string]string)
+	for rows.Next() {
+		var key, text string
+		if err := rows.Scan(&key, &text); err != nil {
+			return nil, err
+		}
+		templates[key] = text
+	}
+	return templates, rows.Err()
+}
+
+// ListMessageTemplates liefert alle eigenen Texte eines Chats (ohne globale)
+func (db *DB) ListMessageTemplates(chatID int64) ([]MessageTemplate, error) {
+	query := `SELECT chat_id, locale, key, text, updated_by, updated_at FROM message_templates
+			  WHERE chat_id = ? ORDER BY locale, key`
+	rows, err := db.conn.Query(query, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []MessageTemplate
+	for rows.Next() {
+		var tmpl MessageTemplate
+		var updatedAt sql.NullTime
+		if err := rows.Scan(&tmpl.ChatID, &tmpl.Locale, &tmpl.Key, &tmpl.Text, &tmpl.UpdatedBy, &updatedAt); err != nil {
+			return nil, err
+		}
+		tmpl.UpdatedAt = updatedAt.Time
+		templates = append(templates, tmpl)
+	}
+	return templates, rows.Err()
+}
+
+// SetGroupRules speichert die Regeln einer Gruppe (HTML)
+func (db *DB) SetGroupRules(chatID int64, text string, updatedBy int64) error {
+	query := `INSERT OR REPLACE INTO group_rules (chat_id, text, updated_by, updated_at) VALUES (?, ?, ?, ?)`
+	_, err := db.conn.Exec(query, chatID, text, updatedBy, time.Now())
+	return err
+}
+
+// GetGroupRules liefert die Regeln einer Gruppe, ohne Regeln einen leeren String
+func (db *DB) GetGroupRules(chatID int64) (string, error) {
+	var text string
+	err := db.conn.QueryRow(`SELECT text FROM group_rules WHERE chat_id = ?`, chatID).Scan(&text)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return text, err
+}
+
+// RemoveGroupRules löscht die Regeln einer Gruppe und meldet, ob es welche gab
+func (db *DB) RemoveGroupRules(chatID int64) (bool, error) {
+	result, err := db.conn.Exec(`DELETE FROM group_rules WHERE chat_id = ?`, chatID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// RecordRulesAcceptance speichert, wann ein User die Regeln einer Gruppe akzeptiert hat
+func (db *DB) RecordRulesAcceptance(chatID, userID int64, acceptedAt time.Time) error {
+	query := `INSERT OR REPLACE INTO rules_acceptances (chat_id, user_id, accepted_at) VALUES (?, ?, ?)`
+	_, err := db.conn.Exec(query, chatID, userID, acceptedAt)
+	return err
+}
+
+// GetRulesAcceptance liefert den Zeitpunkt der letzten Zustimmung, ohne Zustimmung die Nullzeit
+func (db *DB) GetRulesAcceptance(chatID, userID int64) (time.Time, error) {
+	var acceptedAt time.Time
+	err := db.conn.QueryRow(`SELECT accepted_at FROM rules_acceptances WHERE chat_id = ? AND user_id = ?`, chatID, userID).Scan(&acceptedAt)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	return acceptedAt, err
+}
+
+// SavePermissionSnapshot merkt sich die Rechte eines Users vor einer Einschränkung durch den Bot.
+// Ein vorhandener Snapshot bleibt erhalten, damit verschachtelte Einschränkungen den Ursprungszustand nicht überschreiben.
+func (db *DB) SavePermissionSnapshot(snapshot PermissionSnapshot) error {
+	query := `INSERT OR IGNORE INTO permission_snapshots (chat_id, user_id, restricted, permissions, created_at) VALUES (?, ?, ?, ?, ?)`
+	_, err := db.conn.Exec(query, snapshot.ChatID, snapshot.UserID, snapshot.Restricted, snapshot.Permissions, snapshot.CreatedAt)
+	return err
+}
+
+// GetPermissionSnapshot liefert den gespeicherten Snapshot eines Users, ohne Snapshot nil
+func (db *DB) GetPermissionSnapshot(chatID, userID int64) (*PermissionSnapshot, error) {
+	snapshot := PermissionSnapshot{ChatID: chatID, UserID: userID}
+	query := `SELECT restricted, permissions, created_at FROM permission_snapshots WHERE chat_id = ? AND user_id = ?`
+	err := db.conn.QueryRow(query, chatID, userID).Scan(&snapshot.Restricted, &snapshot.Permissions, &snapshot.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// RemovePermissionSnapshot verwirft den Snapshot eines Users, z.B. nachdem er wiederhergestellt wurde
+func (db *DB) RemovePermissionSnapshot(chatID, userID int64) error {
+	_, err := db.conn.Exec(`DELETE FROM permission_snapshots WHERE chat_id = ? AND user_id = ?`, chatID, userID)
+	return err
+}
+
+// GetBotAdmin liefert die Rolle eines Users, ohne Rolle nil
+func (db *DB) GetBotAdmin(userID int64) (*BotAdmin, error) {
+	admin := BotAdmin{UserID: userID}
+	query := `SELECT role, granted_by, granted_at FROM bot_admins WHERE user_id = ?`
+	err := db.conn.QueryRow(query, userID).Scan(&admin.Role, &admin.GrantedBy, &admin.GrantedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &admin, nil
+}
+
+// GetBotAdmins liefert alle Bot-Admins, sortiert nach Vergabe
+func (db *DB) GetBotAdmins() ([]BotAdmin, error) {
+	rows, err := db.conn.Query(`SELECT user_id, role, granted_by, granted_at FROM bot_admins ORDER BY granted_at, user_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var admins []BotAdmin
+	for rows.Next() {
+		var admin BotAdmin
+		if err := rows.Scan(&admin.UserID, &admin.Role, &admin.GrantedBy, &admin.GrantedAt); err != nil {
+			return nil, err
+		}
+		admins = append(admins, admin)
+	}
+	return admins, rows.Err()
+}
+
+// CountBotAdmins zählt die Bot-Admins mit einer Rolle, "" zählt alle
+func (db *DB) CountBotAdmins(role string) (int, error) {
+	query := `SELECT COUNT(*) FROM bot_admins WHERE ? = '' OR role = ?`
+	var count int
+	err := db.conn.QueryRow(query, role, role).Scan(&count)
+	return count, err
+}
+
+// SetBotAdmin vergibt oder ändert die Rolle eines Users und schreibt den Audit-Trail
+func (db *DB) SetBotAdmin(admin BotAdmin, note string) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setBotAdmin(tx, admin, note); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveBotAdmin entzieht einem User seine Rolle und meldet, ob er eine hatte
+func (db *DB) RemoveBotAdmin(userID, actorID int64, note string) (bool, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var role string
+	err = tx.QueryRow(`SELECT role FROM bot_admins WHERE user_id = ?`, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM bot_admins WHERE user_id = ?`, userID); err != nil {
+		return false, err
+	}
+	if err := addAdminAudit(tx, AdminAuditEntry{
+		UserID: userID, Action: AuditRevoke, Role: role, ActorID: actorID, Note: note, CreatedAt: time.Now(),
+	}); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// ImportBotAdmins übernimmt Bot-Admins einmalig, z.B. aus admin_user_ids der Config.
+// Gibt es bereits Einträge im Audit-Trail, wurde schon übernommen oder vergeben und es passiert nichts.
+func (db *DB) ImportBotAdmins(admins []BotAdmin, note string) (int, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var entries int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM admin_audit`).Scan(&entries); err != nil {
+		return 0, err
+	}
+	if entries > 0 {
+		return 0, nil
+	}
+
+	for _, admin := range admins {
+		if err := setBotAdmin(tx, admin, note); err != nil {
+			return 0, err
+		}
+	}
+	return len(admins), tx.Commit()
+}
+
+// GetAdminAudit liefert die neuesten Einträge des Audit-Trails, der neueste zuerst
+func (db *DB) GetAdminAudit(limit int) ([]AdminAuditEntry, error) {
+	query := `SELECT id, user_id, action, role, actor_id, note, created_at FROM admin_audit ORDER BY id DESC LIMIT ?`
+	rows, err := db.conn.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []AdminAuditEntry
+	for rows.Next() {
+		var entry AdminAuditEntry
+		if err := rows.Scan(&entry.ID, &entry.UserID, &entry.Action, &entry.Role, &entry.ActorID, &entry.Note, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func setBotAdmin(tx *sql.Tx, admin BotAdmin, note string) error {
+	query := `INSERT OR REPLACE INTO bot_admins (user_id, role, granted_by, granted_at) VALUES (?, ?, ?, ?)`
+	if _, err := tx.Exec(query, admin.UserID, admin.Role, admin.GrantedBy, admin.GrantedAt); err != nil {
+		return err
+	}
+	return addAdminAudit(tx, AdminAuditEntry{
+		UserID: admin.UserID, Action: AuditGrant, Role: admin.Role, ActorID: admin.GrantedBy, Note: note, CreatedAt: admin.GrantedAt,
+	})
+}
+
+func addAdminAudit(tx *sql.Tx, entry AdminAuditEntry) error {
+	query := `INSERT INTO admin_audit (user_id, action, role, actor_id, note, created_at) VALUES (?, ?, ?, ?, ?, ?)`
+	_, err := tx.Exec(query, entry.UserID, entry.Action, entry.Role, entry.ActorID, entry.Note, entry.CreatedAt)
+	return err
+}
+
+// GetBotAdminsGrantedWith liefert die Bot-Admins, deren aktuelle Rolle mit einer der Notizen vergeben wurde
+func (db *DB) GetBotAdminsGrantedWith(notes ...string) ([]int64, error) {
+	if len(notes) == 0 {
+		return nil, nil
+	}
+	query := `SELECT b.user_id FROM bot_admins b
+			  WHERE (SELECT a.note FROM admin_audit a
+			         WHERE a.user_id = b.user_id AND a.action = ?
+			         ORDER BY a.id DESC LIMIT 1) IN (` + strings.TrimSuffix(strings.Repeat("?,", len(notes)), ",") + `)`
+
+	args := []interface{}{AuditGrant}
+	for _, note := range notes {
+		args = append(args, note)
+	}
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int64
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+// GetGroupAdmin liefert den gespeicherten Admin-Status eines Users in einer Gruppe, ohne Eintrag nil
+func (db *DB) GetGroupAdmin(chatID, userID int64) (*GroupAdmin, error) {
+	admin := GroupAdmin{ChatID: chatID, UserID: userID}
+	var roles string
+	query := `SELECT roles, synced_at FROM group_admins WHERE chat_id = ? AND user_id = ?`
+	err := db.conn.QueryRow(query, chatID, userID).Scan(&roles, &admin.SyncedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	admin.Roles = splitRoles(roles)
+	return &admin, nil
+}
+
+// GetGroupAdmins liefert alle gespeicherten Admins einer Gruppe
+func (db *DB) GetGroupAdmins(chatID int64) ([]GroupAdmin, error) {
+	rows, err := db.conn.Query(`SELECT user_id, roles, synced_at FROM group_admins WHERE chat_id = ? ORDER BY user_id`, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var admins []GroupAdmin
+	for rows.Next() {
+		admin := GroupAdmin{ChatID: chatID}
+		var roles string
+		if err := rows.Scan(&admin.UserID, &roles, &admin.SyncedAt); err != nil {
+			return nil, err
+		}
+		admin.Roles = splitRoles(roles)
+		admins = append(admins, admin)
+	}
+	return admins, rows.Err()
+}
+
+// GetGroupAdminChats liefert alle Gruppen, für die Admins gespeichert sind oder in denen der Bot ist
+func (db *DB) GetGroupAdminChats() ([]int64, error) {
+	rows, err := db.conn.Query(`SELECT chat_id FROM group_admins
+			  UNION SELECT chat_id FROM chats WHERE left_at IS NULL AND type IN ('group', 'supergroup')
+			  ORDER BY chat_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chatIDs []int64
+	for rows.Next() {
+		var chatID int64
+		if err := rows.Scan(&chatID); err != nil {
+			return nil, err
+		}
+		chatIDs = append(chatIDs, chatID)
+	}
+	return chatIDs, rows.Err()
+}
+
+// SetGroupAdmin speichert den Admin-Status eines Users in einer Gruppe
+func (db *DB) SetGroupAdmin(admin GroupAdmin) error {
+	query := `INSERT OR REPLACE INTO group_admins (chat_id, user_id, roles, synced_at) VALUES (?, ?, ?, ?)`
+	_, err := db.conn.Exec(query, admin.ChatID, admin.UserID, strings.Join(admin.Roles, ","), admin.SyncedAt)
+	return err
+}
+
+// RemoveGroupAdmin entfernt den Admin-Status eines Users in einer Gruppe und meldet, ob es einen gab
+func (db *DB) RemoveGroupAdmin(chatID, userID int64) (bool, error) {
+	result, err := db.conn.Exec(`DELETE FROM group_admins WHERE chat_id = ? AND user_id = ?`, chatID, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func splitRoles(roles string) []string {
+	if roles == "" {
+		return nil
+	}
+	return strings.Split(roles, ",")
+}
+
+// TouchKnownChat merkt sich Titel und Typ eines Chats, aus dem gerade ein Update kam.
+// Wer schreibt, ist im Chat: ein früheres Verlassen wird dabei zurückgesetzt.
+func (db *DB) TouchKnownChat(chatID int64, title, chatType string, at time.Time) error {
+	query := `INSERT INTO chats (chat_id, title, type, updated_at) VALUES (?, ?, ?, ?)
+			  ON CONFLICT(chat_id) DO UPDATE SET title = excluded.title, type = excluded.type,
+			  left_at = NULL, updated_at = excluded.updated_at`
+	_, err := db.conn.Exec(query, chatID, title, chatType, at.UTC())
+	return err
+}
+
+// SaveKnownChat speichert einen Chat vollständig, z.B. nach einem my_chat_member-Update
+func (db *DB) SaveKnownChat(chat KnownChat) error {
+	query := `INSERT OR REPLACE INTO chats (chat_id, title, type, bot_status, bot_permissions, joined_at, left_at, updated_at)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := db.conn.Exec(query, chat.ChatID, chat.Title, chat.Type, chat.BotStatus, chat.BotPermissions,
+		nullableTime(chat.JoinedAt), nullableTime(chat.LeftAt), chat.UpdatedAt.UTC())
+	return err
+}
+
+// GetKnownChat liefert einen gespeicherten Chat, ohne Eintrag nil
+func (db *DB) GetKnownChat(chatID int64) (*KnownChat, error) {
+	rows, err := db.conn.Query(`SELECT `+knownChatColumns+` FROM chats WHERE chat_id = ?`, chatID)
+	if err != nil {
+		return nil, err
+	}
+	chats, err := scanKnownChats(rows)
+	if err != nil || len(chats) == 0 {
+		return nil, err
+	}
+	return &chats[0], nil
+}
+
+// GetKnownChats liefert alle Chats, in denen der Bot noch ist, sortiert nach Titel
+func (db *DB) GetKnownChats() ([]KnownChat, error) {
+	rows, err := db.conn.Query(`SELECT ` + knownChatColumns + ` FROM chats WHERE left_at IS NULL ORDER BY title COLLATE NOCASE, chat_id`)
+	if err != nil {
+		return nil, err
+	}
+	return scanKnownChats(rows)
+}
+
+const knownChatColumns = `chat_id, title, type, bot_status, bot_permissions, joined_at, left_at, updated_at`
+
+func scanKnownChats(rows *sql.Rows) ([]KnownChat, error) {
+	defer rows.Close()
+
+	var chats []KnownChat
+	for rows.Next() {
+		var chat KnownChat
+		var title, chatType, status, permissions sql.NullString
+		var joinedAt, leftAt sql.NullTime
+		if err := rows.Scan(&chat.ChatID, &title, &chatType, &status, &permissions, &joinedAt, &leftAt, &chat.UpdatedAt); err != nil {
+			return nil, err
+		}
+		chat.Title = title.String
+		chat.Type = chatType.String
+		chat.BotStatus = status.String
+		chat.BotPermissions = permissions.String
+		chat.JoinedAt = joinedAt.Time
+		chat.LeftAt = leftAt.Time
+		chats = append(chats, chat)
+	}
+	return chats, rows.Err()
+}
+
+// GetSelectedChat liefert die per /groups gewählte Gruppe eines Users, ohne Auswahl 0
+func (db *DB) GetSelectedChat(userID int64) (int64, error) {
+	var chatID int64
+	err := db.conn.QueryRow(`SELECT chat_id FROM selected_chats WHERE user_id = ?`, userID).Scan(&chatID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return chatID, err
+}
+
+// SetSelectedChat merkt sich die gewählte Gruppe eines Users, 0 entfernt die Auswahl
+func (db *DB) SetSelectedChat(userID, chatID int64) error {
+	if chatID == 0 {
+		_, err := db.conn.Exec(`DELETE FROM selected_chats WHERE user_id = ?`, userID)
+		return err
+	}
+	query := `INSERT OR REPLACE INTO selected_chats (user_id, chat_id, selected_at) VALUES (?, ?, ?)`
+	_, err := db.conn.Exec(query, userID, chatID, time.Now().UTC())
+	return err
+}
+
+// GetRolePermissions liefert die angepassten Rechte aller Rollen eines Chats (0 = global)
+func (db *DB) GetRolePermissions(chatID int64) (map[string][]string, error) {
+	rows, err := db.conn.Query(`SELECT role, permissions FROM role_permissions WHERE chat_id = ?`, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := make(map[string][]string)
+	for rows.Next() {
+		var role, permissions string
+		if err := rows.Scan(&role, &permissions); err != nil {
+			return nil, err
+		}
+		// Eine leere Liste bleibt erhalten: die Rolle hat dann in diesem Chat keine Rechte
+		roles[role] = []string{}
+		if permissions != "" {
+			roles[role] = strings.Split(permissions, ",")
+		}
+	}
+	return roles, rows.Err()
+}
+
+// SetRolePermissions legt die Rechte einer Rolle in einem Chat fest (0 = global)
+func (db *DB) SetRolePermissions(chatID int64, role string, permissions []string) error {
+	query := `INSERT OR REPLACE INTO role_permissions (chat_id, role, permissions) VALUES (?, ?, ?)`
+	_, err := db.conn.Exec(query, chatID, role, strings.Join(permissions, ","))
+	return err
+}
+
+// RemoveRolePermissions setzt die Rechte einer Rolle in einem Chat auf den Standard zurück
+func (db *DB) RemoveRolePermissions(chatID int64, role string) (bool, error) {
+	result, err := db.conn.Exec(`DELETE FROM role_permissions WHERE chat_id = ? AND role = ?`, chatID, role)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// forgetUserTables enthält die Tabellen mit einer user_id-Spalte, die ForgetUser leert.
+// Neue Tabellen mit personenbezogenen Daten müssen hier ergänzt werden.
+// Bewusst ausgenommen (berechtigtes Interesse, sonst ließe sich jede Sanktion per /forgetme abschütteln):
+//   - bot_admins und admin_audit, damit Rechtevergaben nachvollziehbar bleiben
+//   - warnings, banned_users, moderation_actions: Verwarnstufe und Moderationsverlauf
+//   - muted_users, permission_snapshots, scheduled_jobs: laufende Mutes, Banns und ihre Aufhebung
+//   - pending_users: ein offenes Captcha ließe sich sonst umgehen
+var forgetUserTables = []string{
+	"welcome_messages",
+	"rules_acceptances",
+	"group_admins",
+	"selected_chats",
+}
+
+// ForgetUser löscht die Zeilen eines Users in forgetUserTables und gibt die Anzahl zurück
+func (db *DB) ForgetUser(userID int64) (int64, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var total int64
+	for _, table := range forgetUserTables {
+		result, err := tx.Exec(`DELETE FROM `+table+` WHERE user_id = ?`, userID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete from %s: %w", table, err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		total += affected
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
 func (db *DB) Close() error {
 	return db.conn.Close()
 }
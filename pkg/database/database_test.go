@@ -0,0 +1,28 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewDBPath(t *testing.T) {
+	for _, name := range []string{"bot.db", "bot?mode=ro.db", "bot#1 %20.db"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			db, err := NewDB(path)
+			if err != nil {
+				t.Fatalf("NewDB(%q): %v", path, err)
+			}
+			defer db.Close()
+
+			if _, err := os.Stat(path); err != nil {
+				t.Errorf("database not created at %q: %v", path, err)
+			}
+			var timeout int
+			if err := db.conn.QueryRow(`PRAGMA busy_timeout`).Scan(&timeout); err != nil || timeout != 5000 {
+				t.Errorf("busy_timeout = %d (err=%v), want 5000", timeout, err)
+			}
+		})
+	}
+}
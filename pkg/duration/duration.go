@@ -0,0 +1,141 @@
+package duration
+
+import (
+	"strconv"
+	"strings"
+	"telegramBot/pkg/i18n"
+	"time"
+)
+
+// Permanent steht für "perm"/"forever", also eine Aktion ohne Ablaufzeit
+const Permanent time.Duration = -1
+
+const (
+	Day  = 24 * time.Hour
+	Week = 7 * Day
+)
+
+// Einheiten in absteigender Reihenfolge, so werden sie auch von Format ausgegeben
+var units = []struct {
+	suffix byte
+	value  time.Duration
+}{
+	{'w', Week},
+	{'d', Day},
+	{'h', time.Hour},
+	{'m', time.Minute},
+	{'s', time.Second},
+}
+
+// Parse liest Dauern wie "30m", "12h", "3d", "1w2d" oder "1h30m".
+// "perm", "permanent" und "forever" ergeben Permanent.
+// Fehler (i18n.Error) nennen den Teil der Eingabe, der nicht verstanden wurde.
+func Parse(input string) (time.Duration, error) {
+	input = strings.ToLower(strings.TrimSpace(input))
+	switch input {
+	case "":
+		return 0, i18n.NewError("duration.missing")
+	case "perm", "permanent", "forever":
+		return Permanent, nil
+	}
+
+	var total time.Duration
+	seen := make(map[byte]bool)
+	rest := input
+
+	for rest != "" {
+		digits := 0
+		for digits < len(rest) && rest[digits] >= '0' && rest[digits] <= '9' {
+			digits++
+		}
+
+		token := rest
+		if digits < len(rest) {
+			token = rest[:digits+1]
+		}
+
+		if digits == 0 {
+			return 0, i18n.NewError("duration.expected_number", i18n.Vars{"input": input, "token": token})
+		}
+		if digits == len(rest) {
+			return 0, i18n.NewError("duration.missing_unit", i18n.Vars{"input": input, "token": token})
+		}
+
+		unit, ok := unitValue(rest[digits])
+		if !ok {
+			return 0, i18n.NewError("duration.unknown_unit", i18n.Vars{"input": input, "token": token})
+		}
+		if seen[rest[digits]] {
+			return 0, i18n.NewError("duration.duplicate_unit", i18n.Vars{"input": input, "token": token})
+		}
+		seen[rest[digits]] = true
+
+		value, err := strconv.ParseInt(rest[:digits], 10, 64)
+		if err != nil || value > int64(maxDuration/unit) {
+			return 0, i18n.NewError("duration.too_large", i18n.Vars{"input": input, "token": token})
+		}
+
+		total += time.Duration(value) * unit
+		if total > maxDuration || total < 0 {
+			return 0, i18n.NewError("duration.too_large", i18n.Vars{"input": input, "token": token})
+		}
+
+		rest = rest[digits+1:]
+	}
+
+	if total == 0 {
+		return 0, i18n.NewError("duration.not_positive", i18n.Vars{"input": input})
+	}
+
+	return total, nil
+}
+
+// maxDuration begrenzt Eingaben auf etwa 100 Jahre, damit time.Time-Rechnungen nicht überlaufen
+const maxDuration = 100 * 365 * Day
+
+func unitValue(suffix byte) (time.Duration, bool) {
+	for _, unit := range units {
+		if unit.suffix == suffix {
+			return unit.value, true
+		}
+	}
+	return 0, false
+}
+
+// Check prüft eine Dauer gegen Grenzen. Ist max Permanent, gibt es keine Obergrenze
+// und auch "perm" ist erlaubt.
+func Check(d, min, max time.Duration) error {
+	if d == Permanent {
+		if max != Permanent {
+			return i18n.NewError("duration.perm_not_allowed", i18n.Vars{"max": Format(max)})
+		}
+		return nil
+	}
+	if d < min {
+		return i18n.NewError("duration.too_short", i18n.Vars{"min": Format(min)})
+	}
+	if max != Permanent && d > max {
+		return i18n.NewError("duration.too_long", i18n.Vars{"max": Format(max)})
+	}
+	return nil
+}
+
+// Format gibt eine Dauer in derselben Schreibweise aus, die Parse versteht (z.B. "1w2d")
+func Format(d time.Duration) string {
+	if d == Permanent {
+		return "permanent"
+	}
+	if d < time.Second {
+		return "0s"
+	}
+
+	var sb strings.Builder
+	for _, unit := range units {
+		if n := d / unit.value; n > 0 {
+			sb.WriteString(strconv.FormatInt(int64(n), 10))
+			sb.WriteByte(unit.suffix)
+			d -= n * unit.value
+		}
+	}
+	return sb.String()
+}
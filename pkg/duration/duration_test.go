@@ -0,0 +1,82 @@
+package duration
+
+import (
+	"errors"
+	"telegramBot/pkg/i18n"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input     string
+		want      time.Duration
+		wantKey   string // erwarteter i18n-Schlüssel bei Fehlern
+		wantToken string // Teil der Eingabe, den der Fehler nennt
+	}{
+		{input: "30m", want: 30 * time.Minute},
+		{input: "1h30m", want: 90 * time.Minute},
+		{input: "1w2d", want: 9 * Day},
+		{input: " 3D ", want: 3 * Day},
+		{input: "45s", want: 45 * time.Second},
+		{input: "perm", want: Permanent},
+		{input: "forever", want: Permanent},
+		{input: "", wantKey: "duration.missing"},
+		{input: "h", wantKey: "duration.expected_number", wantToken: "h"},
+		{input: "10", wantKey: "duration.missing_unit", wantToken: "10"},
+		{input: "10m5", wantKey: "duration.missing_unit", wantToken: "5"},
+		{input: "5x", wantKey: "duration.unknown_unit", wantToken: "5x"},
+		{input: "1h2h", wantKey: "duration.duplicate_unit", wantToken: "2h"},
+		{input: "999999w", wantKey: "duration.too_large", wantToken: "999999w"},
+		{input: "0m", wantKey: "duration.not_positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if tt.wantKey == "" {
+				if err != nil || got != tt.want {
+					t.Errorf("Parse(%q) = %s, %v, want %s", tt.input, got, err, tt.want)
+				}
+				return
+			}
+
+			var i18nErr *i18n.Error
+			if !errors.As(err, &i18nErr) {
+				t.Fatalf("Parse(%q) error = %v, want %s", tt.input, err, tt.wantKey)
+			}
+			if i18nErr.Key != tt.wantKey {
+				t.Errorf("Parse(%q) error key = %s, want %s", tt.input, i18nErr.Key, tt.wantKey)
+			}
+			if tt.wantToken != "" && i18nErr.Vars["token"] != tt.wantToken {
+				t.Errorf("Parse(%q) token = %v, want %q", tt.input, i18nErr.Vars["token"], tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{Permanent, "permanent"},
+		{0, "0s"},
+		{500 * time.Millisecond, "0s"},
+		{90 * time.Minute, "1h30m"},
+		{9 * Day, "1w2d"},
+		{time.Hour + 5*time.Second, "1h5s"},
+	}
+
+	for _, tt := range tests {
+		got := Format(tt.d)
+		if got != tt.want {
+			t.Errorf("Format(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+		if tt.d >= time.Second || tt.d == Permanent {
+			if back, err := Parse(got); err != nil || back != tt.d {
+				t.Errorf("Parse(Format(%s)) = %s, %v", tt.d, back, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"strings"
+	"telegramBot/pkg/bot"
+	"telegramBot/pkg/i18n"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+type ForgetMeHandler struct{}
+
+func NewForgetMeHandler() *ForgetMeHandler {
+	return &ForgetMeHandler{}
+}
+
+// Handle löscht auf Wunsch die gespeicherten Daten des Absenders, Sanktionen bleiben erhalten.
+// Nur per DM und erst nach Bestätigung mit /forgetme confirm.
+func (h *ForgetMeHandler) Handle(b *bot.Bot, update tgbotapi.Update) error {
+	message := update.Message
+	tr := b.Localizer(message.Chat, message.From)
+
+	if message.Chat.Type != "private" {
+		_, _ = b.SendTemporaryGroupMessage(message.Chat.ID, tr.T("forgetme.dm_only"), 5)
+		return nil
+	}
+
+	if strings.TrimSpace(message.CommandArguments()) != "confirm" {
+		_, err := b.SendMessage(message.Chat.ID, tr.T("forgetme.explain"))
+		return err
+	}
+
+	entries, rows, err := b.ForgetUser(message.From.ID)
+	if err != nil {
+		_, _ = b.SendMessage(message.Chat.ID, tr.T("forgetme.failed"))
+		return err
+	}
+
+	_, err = b.SendMessage(message.Chat.ID, tr.T("forgetme.success", i18n.Vars{"entries": entries, "rows": rows}))
+	return err
+}
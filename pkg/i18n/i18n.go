@@ -0,0 +1,158 @@
+// Package i18n enthält die Textkataloge des Bots. Texte werden über Schlüssel wie
+// "ban.success" abgerufen und enthalten Platzhalter in der Form {name}.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DefaultLocale wird verwendet, wenn keine andere Sprache passt oder ein Text fehlt
+const DefaultLocale = "de"
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// catalogs enthält pro Sprache alle mitgelieferten Texte
+var catalogs = loadCatalogs()
+
+// Vars sind die Werte für die Platzhalter eines Textes
+type Vars map[string]interface{}
+
+func loadCatalogs() map[string]map[string]string {
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(fmt.Sprintf("i18n: failed to read locales: %v", err))
+	}
+
+	result := make(map[string]map[string]string)
+	for _, entry := range entries {
+		data, err := localeFiles.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("i18n: failed to read %s: %v", entry.Name(), err))
+		}
+
+		var catalog map[string]string
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			panic(fmt.Sprintf("i18n: invalid catalog %s: %v", entry.Name(), err))
+		}
+		result[strings.TrimSuffix(entry.Name(), ".json")] = catalog
+	}
+
+	if _, ok := result[DefaultLocale]; !ok {
+		panic("i18n: default locale " + DefaultLocale + " is missing")
+	}
+	return result
+}
+
+// Locales liefert alle mitgelieferten Sprachen, sortiert
+func Locales() []string {
+	locales := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Supported meldet, ob für locale ein Katalog existiert
+func Supported(locale string) bool {
+	_, ok := catalogs[locale]
+	return ok
+}
+
+// Match ordnet einen Telegram-LanguageCode wie "en-US" einer unterstützten Sprache zu.
+// Gibt "" zurück, wenn es keine passende Sprache gibt.
+func Match(languageCode string) string {
+	code := strings.ToLower(strings.TrimSpace(languageCode))
+	if Supported(code) {
+		return code
+	}
+	if i := strings.IndexAny(code, "-_"); i > 0 && Supported(code[:i]) {
+		return code[:i]
+	}
+	return ""
+}
+
+// Keys liefert alle Schlüssel der Standardsprache, sortiert
+func Keys() []string {
+	return LocaleKeys(DefaultLocale)
+}
+
+// LocaleKeys liefert alle Schlüssel einer Sprache, sortiert
+func LocaleKeys(locale string) []string {
+	keys := make([]string, 0, len(catalogs[locale]))
+	for key := range catalogs[locale] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Lookup sucht einen mitgelieferten Text, fehlt er in locale, wird die Standardsprache verwendet
+func Lookup(locale, key string) (string, bool) {
+	if text, ok := catalogs[locale][key]; ok {
+		return text, true
+	}
+	text, ok := catalogs[DefaultLocale][key]
+	return text, ok
+}
+
+// T liefert einen mitgelieferten Text mit eingesetzten Platzhaltern.
+// Unbekannte Schlüssel werden unverändert zurückgegeben, damit der Fehler sichtbar bleibt.
+func T(locale, key string, vars ...Vars) string {
+	text, ok := Lookup(locale, key)
+	if !ok {
+		return key
+	}
+	return Render(text, vars...)
+}
+
+// Render setzt die Werte aus vars für {name} ein. Platzhalter ohne Wert bleiben stehen.
+func Render(text string, vars ...Vars) string {
+	if !strings.Contains(text, "{") {
+		return text
+	}
+
+	var pairs []string
+	for _, v := range vars {
+		for name, value := range v {
+			pairs = append(pairs, "{"+name+"}", fmt.Sprint(value))
+		}
+	}
+	if len(pairs) == 0 {
+		return text
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// Error ist ein Fehler, dessen Meldung für den User übersetzt werden kann.
+// Error() liefert den Text in der Standardsprache.
+type Error struct {
+	Key  string
+	Vars Vars
+}
+
+// NewError erstellt einen übersetzbaren Fehler
+func NewError(key string, vars ...Vars) *Error {
+	merged := Vars{}
+	for _, v := range vars {
+		for name, value := range v {
+			merged[name] = value
+		}
+	}
+	return &Error{Key: key, Vars: merged}
+}
+
+func (e *Error) Error() string {
+	return T(DefaultLocale, e.Key, e.Vars)
+}
+
+// Translate liefert die Meldung in locale
+func (e *Error) Translate(locale string) string {
+	return T(locale, e.Key, e.Vars)
+}
@@ -0,0 +1,75 @@
+package i18n
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		vars []Vars
+		want string
+	}{
+		{"replaces placeholders", "Hallo {user}, du hast {count} Verwarnungen", []Vars{{"user": "Max", "count": 2}}, "Hallo Max, du hast 2 Verwarnungen"},
+		{"keeps missing placeholders", "{user} bis {until}", []Vars{{"user": "Max"}}, "Max bis {until}"},
+		{"merges several maps", "{a}-{b}", []Vars{{"a": 1}, {"b": 2}}, "1-2"},
+		{"does not expand values", "{a}{b}", []Vars{{"a": "{b}", "b": "x"}}, "{b}x"},
+		{"text without placeholders", "Kein Platzhalter", []Vars{{"user": "Max"}}, "Kein Platzhalter"},
+		{"no vars", "Hallo {user}", nil, "Hallo {user}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.text, tt.vars...); got != tt.want {
+				t.Errorf("Render(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"de", "de"},
+		{" EN ", "en"},
+		{"en-US", "en"},
+		{"de_AT", "de"},
+		{"fr", ""},
+		{"", ""},
+		{"-en", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Match(tt.code); got != tt.want {
+			t.Errorf("Match(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestCatalogs(t *testing.T) {
+	placeholder := regexp.MustCompile(`\{[a-z_]+\}`)
+
+	for _, locale := range Locales() {
+		keys := LocaleKeys(locale)
+		if len(keys) != len(Keys()) {
+			t.Errorf("locale %s has %d keys, want %d", locale, len(keys), len(Keys()))
+		}
+		for _, key := range Keys() {
+			text, _ := Lookup(locale, key)
+			base, _ := Lookup(DefaultLocale, key)
+			got := placeholder.FindAllString(text, -1)
+			want := placeholder.FindAllString(base, -1)
+			sort.Strings(got)
+			sort.Strings(want)
+			if strings.Join(got, ",") != strings.Join(want, ",") {
+				t.Errorf("%s/%s placeholders = %v, want %v", locale, key, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,411 @@
+// Package telegramtest stellt einen Fake-Telegram-Bot-API-Server für Tests bereit.
+// Er zeichnet alle API-Aufrufe auf und liefert per getUpdates vorab eingereihte Updates.
+package telegramtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// BotUserID ist die User-ID, die der Fake-Server für den Bot selbst meldet
+const BotUserID int64 = 1000
+
+// Call ist ein aufgezeichneter API-Aufruf
+type Call struct {
+	Method string
+	Params url.Values
+}
+
+// Int64 liefert einen numerischen Parameter des Aufrufs
+func (c Call) Int64(key string) int64 {
+	v, _ := strconv.ParseInt(c.Params.Get(key), 10, 64)
+	return v
+}
+
+// Responder erzeugt das Ergebnis für eine API-Methode.
+// Ein Fehler wird als {"ok": false} mit Fehlercode 400 beantwortet, ein *APIError mit seinem Code.
+type Responder func(params url.Values) (interface{}, error)
+
+// APIError ist eine Fehlerantwort mit eigenem Code, z.B. 429 mit retry_after
+type APIError struct {
+	Code       int
+	Message    string
+	RetryAfter int
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// TooManyRequests liefert die Antwort der Bot API bei überschrittenem Limit
+func TooManyRequests(retryAfter int) *APIError {
+	return &APIError{
+		Code:       http.StatusTooManyRequests,
+		Message:    fmt.Sprintf("Too Many Requests: retry after %d", retryAfter),
+		RetryAfter: retryAfter,
+	}
+}
+
+// Server ist ein In-Process-Fake der Telegram Bot API
+type Server struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	calls         []Call
+	updates       []tgbotapi.Update
+	nextUpdateID  int
+	nextMessageID int
+	members       map[memberKey]string
+	restrictions  map[memberKey]tgbotapi.ChatPermissions
+	permissions   map[int64]tgbotapi.ChatPermissions
+	adminRights   map[memberKey]AdminRights
+	responders    map[string]Responder
+}
+
+// AdminRights sind die Admin-Rechte, die getChatMember für einen Administrator meldet
+type AdminRights struct {
+	CanRestrictMembers bool
+	CanDeleteMessages  bool
+	CanChangeInfo      bool
+	CanPromoteMembers  bool
+}
+
+// DefaultAdminRights gelten für Administratoren ohne SetAdminRights
+var DefaultAdminRights = AdminRights{
+	CanRestrictMembers: true,
+	CanDeleteMessages:  true,
+	CanChangeInfo:      true,
+	CanPromoteMembers:  true,
+}
+
+// DefaultChatPermissions sind die Standardrechte, die getChat ohne SetChatPermissions meldet
+var DefaultChatPermissions = tgbotapi.ChatPermissions{
+	CanSendMessages:       true,
+	CanSendMediaMessages:  true,
+	CanSendPolls:          true,
+	CanSendOtherMessages:  true,
+	CanAddWebPagePreviews: true,
+	CanInviteUsers:        true,
+}
+
+type memberKey struct {
+	chatID int64
+	userID int64
+}
+
+// NewServer startet einen Fake-Server. Er muss mit Close beendet werden.
+func NewServer() *Server {
+	s := &Server{
+		nextUpdateID:  1,
+		nextMessageID: 1,
+		members:       make(map[memberKey]string),
+		restrictions:  make(map[memberKey]tgbotapi.ChatPermissions),
+		permissions:   make(map[int64]tgbotapi.ChatPermissions),
+		adminRights:   make(map[memberKey]AdminRights),
+		responders:    make(map[string]Responder),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Endpoint liefert den API-Endpoint im Format von tgbotapi.APIEndpoint
+func (s *Server) Endpoint() string {
+	return s.URL + "/bot%s/%s"
+}
+
+// Handle überschreibt die Antwort für eine API-Methode
+func (s *Server) Handle(method string, responder Responder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responders[method] = responder
+}
+
+// SetMemberStatus legt den Status fest, den getChatMember für einen User liefert
+// ("creator", "administrator", "member", "restricted", "left", "kicked")
+func (s *Server) SetMemberStatus(chatID, userID int64, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.members[memberKey{chatID, userID}] = status
+}
+
+// SetMemberRestrictions macht einen User zum eingeschränkten Mitglied mit den angegebenen Rechten
+func (s *Server) SetMemberRestrictions(chatID, userID int64, permissions tgbotapi.ChatPermissions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.members[memberKey{chatID, userID}] = "restricted"
+	s.restrictions[memberKey{chatID, userID}] = permissions
+}
+
+// SetAdminRights macht einen User zum Administrator mit den angegebenen Rechten
+func (s *Server) SetAdminRights(chatID, userID int64, rights AdminRights) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.members[memberKey{chatID, userID}] = "administrator"
+	s.adminRights[memberKey{chatID, userID}] = rights
+}
+
+// SetChatPermissions legt die Standardrechte fest, die getChat für eine Gruppe liefert
+func (s *Server) SetChatPermissions(chatID int64, permissions tgbotapi.ChatPermissions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.permissions[chatID] = permissions
+}
+
+// PushUpdate reiht ein Update ein, das beim nächsten getUpdates ausgeliefert wird
+func (s *Server) PushUpdate(update tgbotapi.Update) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	update.UpdateID = s.nextUpdateID
+	s.nextUpdateID++
+	s.updates = append(s.updates, update)
+}
+
+// Calls liefert alle aufgezeichneten Aufrufe einer Methode (leer = alle)
+func (s *Server) Calls(method string) []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var calls []Call
+	for _, call := range s.calls {
+		if method == "" || call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Reset verwirft alle aufgezeichneten Aufrufe
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = nil
+}
+
+// WaitForCall wartet bis eine Methode mindestens count mal aufgerufen wurde
+func (s *Server) WaitForCall(method string, count int, timeout time.Duration) ([]Call, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		calls := s.Calls(method)
+		if len(calls) >= count {
+			return calls, nil
+		}
+		if time.Now().After(deadline) {
+			return calls, fmt.Errorf("timeout waiting for %d %s calls, got %d", count, method, len(calls))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	// Pfad: /bot<token>/<method>
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if len(parts) != 2 || !strings.HasPrefix(parts[0], "bot") {
+		http.NotFound(w, r)
+		return
+	}
+	method := parts[1]
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		r.ParseMultipartForm(10 << 20)
+	} else {
+		r.ParseForm()
+	}
+	params := r.Form
+
+	var result interface{}
+	var err error
+
+	if method == "getUpdates" {
+		result = s.getUpdates(params)
+	} else {
+		s.mu.Lock()
+		s.calls = append(s.calls, Call{Method: method, Params: params})
+		responder, custom := s.responders[method]
+		s.mu.Unlock()
+
+		if custom {
+			result, err = responder(params)
+		} else {
+			result, err = s.defaultResponse(method, params)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		response := map[string]interface{}{
+			"ok":          false,
+			"error_code":  400,
+			"description": err.Error(),
+		}
+		if apiErr, ok := err.(*APIError); ok {
+			response["error_code"] = apiErr.Code
+			if apiErr.RetryAfter > 0 {
+				response["parameters"] = map[string]int{"retry_after": apiErr.RetryAfter}
+			}
+			w.WriteHeader(apiErr.Code)
+		}
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	raw, err := json.Marshal(result)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ok":     true,
+		"result": json.RawMessage(raw),
+	})
+}
+
+func (s *Server) getUpdates(params url.Values) []tgbotapi.Update {
+	offset, _ := strconv.Atoi(params.Get("offset"))
+
+	// Kurzes Long-Polling, damit der Client nicht im Leerlauf rotiert
+	deadline := time.Now().Add(100 * time.Millisecond)
+	for {
+		s.mu.Lock()
+		var pending []tgbotapi.Update
+		for _, update := range s.updates {
+			if update.UpdateID >= offset {
+				pending = append(pending, update)
+			}
+		}
+		s.mu.Unlock()
+
+		if len(pending) > 0 || time.Now().After(deadline) {
+			if pending == nil {
+				pending = []tgbotapi.Update{}
+			}
+			return pending
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (s *Server) defaultResponse(method string, params url.Values) (interface{}, error) {
+	switch method {
+	case "getMe":
+		return tgbotapi.User{ID: BotUserID, IsBot: true, FirstName: "Test Bot", UserName: "test_bot"}, nil
+
+	case "sendMessage", "sendPhoto", "editMessageText":
+		chatID, _ := strconv.ParseInt(params.Get("chat_id"), 10, 64)
+		s.mu.Lock()
+		messageID := s.nextMessageID
+		s.nextMessageID++
+		s.mu.Unlock()
+		if method == "editMessageText" {
+			messageID, _ = strconv.Atoi(params.Get("message_id"))
+		}
+		return tgbotapi.Message{
+			MessageID: messageID,
+			From:      &tgbotapi.User{ID: BotUserID, IsBot: true, UserName: "test_bot"},
+			Chat:      &tgbotapi.Chat{ID: chatID},
+			Date:      int(time.Now().Unix()),
+			Text:      params.Get("text"),
+		}, nil
+
+	case "getChatMember":
+		chatID, _ := strconv.ParseInt(params.Get("chat_id"), 10, 64)
+		userID, _ := strconv.ParseInt(params.Get("user_id"), 10, 64)
+		member := tgbotapi.ChatMember{
+			User:   &tgbotapi.User{ID: userID},
+			Status: s.memberStatus(chatID, userID),
+		}
+		if member.Status == "restricted" {
+			s.mu.Lock()
+			p := s.restrictions[memberKey{chatID, userID}]
+			s.mu.Unlock()
+			member.CanSendMessages = p.CanSendMessages
+			member.CanSendMediaMessages = p.CanSendMediaMessages
+			member.CanSendPolls = p.CanSendPolls
+			member.CanSendOtherMessages = p.CanSendOtherMessages
+			member.CanAddWebPagePreviews = p.CanAddWebPagePreviews
+			member.CanChangeInfo = p.CanChangeInfo
+			member.CanInviteUsers = p.CanInviteUsers
+			member.CanPinMessages = p.CanPinMessages
+		}
+		if member.Status == "administrator" && userID != BotUserID {
+			s.mu.Lock()
+			s.applyAdminRights(chatID, &member)
+			s.mu.Unlock()
+		}
+		return member, nil
+
+	case "getChatAdministrators":
+		chatID, _ := strconv.ParseInt(params.Get("chat_id"), 10, 64)
+		return s.administrators(chatID), nil
+
+	case "getChat":
+		chatID, _ := strconv.ParseInt(params.Get("chat_id"), 10, 64)
+		s.mu.Lock()
+		permissions, ok := s.permissions[chatID]
+		s.mu.Unlock()
+		if !ok {
+			permissions = DefaultChatPermissions
+		}
+		return tgbotapi.Chat{ID: chatID, Type: "supergroup", Title: "Test Group", Permissions: &permissions}, nil
+
+	case "getChatMemberCount", "getChatMembersCount":
+		return 1, nil
+	}
+
+	// deleteMessage, restrictChatMember, banChatMember, unbanChatMember,
+	// answerCallbackQuery, setWebhook, deleteWebhook, ...
+	return true, nil
+}
+
+func (s *Server) memberStatus(chatID, userID int64) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if userID == BotUserID {
+		return "administrator"
+	}
+	if status, ok := s.members[memberKey{chatID, userID}]; ok {
+		return status
+	}
+	return "member"
+}
+
+func (s *Server) administrators(chatID int64) []tgbotapi.ChatMember {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	admins := []tgbotapi.ChatMember{}
+	for key, status := range s.members {
+		if key.chatID == chatID && (status == "administrator" || status == "creator") {
+			member := tgbotapi.ChatMember{
+				User:   &tgbotapi.User{ID: key.userID},
+				Status: status,
+			}
+			if status == "administrator" {
+				s.applyAdminRights(chatID, &member)
+			}
+			admins = append(admins, member)
+		}
+	}
+	return admins
+}
+
+// applyAdminRights setzt die Rechte eines Administrators, s.mu muss gehalten werden
+func (s *Server) applyAdminRights(chatID int64, member *tgbotapi.ChatMember) {
+	rights, ok := s.adminRights[memberKey{chatID, member.User.ID}]
+	if !ok {
+		rights = DefaultAdminRights
+	}
+	member.CanRestrictMembers = rights.CanRestrictMembers
+	member.CanDeleteMessages = rights.CanDeleteMessages
+	member.CanChangeInfo = rights.CanChangeInfo
+	member.CanPromoteMembers = rights.CanPromoteMembers
+}